@@ -33,6 +33,19 @@ const (
 	SYS_MODE = 0b11111
 )
 
+// Program Status Register mask bits
+// (B1.3.3 Program Status Registers (PSRs), ARM Architecture Reference Manual ARMv7-A and ARMv7-R edition).
+const (
+	CPSR_I = 7
+	CPSR_F = 6
+)
+
+// System Control Register fields
+// (B4.1.130 SCTLR, ARM Architecture Reference Manual ARMv7-A and ARMv7-R edition).
+const (
+	SCTLR_A = 1
+)
+
 // CPU instance
 type CPU struct {
 	// instruction sets
@@ -59,11 +72,17 @@ type CPU struct {
 	gicd uint32
 	// GIC CPU interface base address
 	gicc uint32
+
+	// PMU cycle counter overflows
+	cycleOverflows uint32
 }
 
 // defined in arm.s
 func read_cpsr() uint32
+func read_sctlr() uint32
+func write_sctlr(val uint32)
 func halt()
+func wfi()
 
 // Init performs initialization of an ARM core instance, the argument must be a
 // pointer to a 64 kB memory area which will be reserved for storing the
@@ -81,6 +100,18 @@ func (cpu *CPU) Init(base uint32) {
 	cpu.initVectorTable()
 }
 
+// WaitForInterrupt suspends execution, placing the core in low-power state,
+// until an interrupt or debug event occurs.
+//
+// The core is woken up by pending interrupts even when masked in the current
+// program status, in which case execution resumes without servicing them.
+// Unlike WaitInterrupt(), which suspends only the calling goroutine, this
+// function halts the whole core and is therefore meant to be used when no
+// goroutine is runnable.
+func (cpu *CPU) WaitForInterrupt() {
+	wfi()
+}
+
 // Mode returns the processor mode.
 func (cpu *CPU) Mode() int {
 	return int(read_cpsr() & 0x1f)
@@ -111,3 +142,26 @@ func ModeName(mode int) string {
 
 	return "Unknown"
 }
+
+// SetAlignmentCheck enables or disables strict alignment checking (SCTLR.A),
+// when enabled unaligned data accesses raise an alignment fault data abort
+// (see DataAbortHandler() and ExceptionContext.AlignmentFault()).
+//
+// Note that, regardless of this setting, unaligned accesses to Device and
+// Strongly-ordered memory always raise an alignment fault.
+func (cpu *CPU) SetAlignmentCheck(enable bool) {
+	sctlr := read_sctlr()
+
+	if enable {
+		sctlr |= 1 << SCTLR_A
+	} else {
+		sctlr &^= 1 << SCTLR_A
+	}
+
+	write_sctlr(sctlr)
+}
+
+// AlignmentCheck returns whether strict alignment checking is enabled.
+func (cpu *CPU) AlignmentCheck() bool {
+	return (read_sctlr()>>SCTLR_A)&1 == 1
+}
@@ -16,8 +16,33 @@ TEXT ·read_cpsr(SB),$0-4
 
 	RET
 
+// func wfi()
+TEXT ·wfi(SB),$0
+	WORD	$0xf57ff04f // dsb sy
+	WORD	$0xe320f003 // wfi
+	RET
+
 // func halt()
 TEXT ·halt(SB),$0
 	// wait forever in low-power state
 	WORD	$0xf10c0080 // cpsid i
 	WORD	$0xe320f003 // wfi
+
+// func read_sctlr() uint32
+TEXT ·read_sctlr(SB),$0-4
+	// ARM Architecture Reference Manual - ARMv7-A and ARMv7-R edition
+	// B4.1.130 SCTLR, System Control Register, VMSA
+	MRC	15, 0, R0, C1, C0, 0
+	MOVW	R0, ret+0(FP)
+
+	RET
+
+// func write_sctlr(val uint32)
+TEXT ·write_sctlr(SB),$0-4
+	// ARM Architecture Reference Manual - ARMv7-A and ARMv7-R edition
+	// B4.1.130 SCTLR, System Control Register, VMSA
+	MOVW	val+0(FP), R0
+	MCR	15, 0, R0, C1, C0, 0
+	WORD	$0xf57ff06f // isb sy
+
+	RET
@@ -12,6 +12,8 @@ package arm
 // ARM cache register constants
 const (
 	ACTLR_SMP = 6
+
+	CTR_DMINLINE = 16
 )
 
 // defined in cache.s
@@ -21,6 +23,10 @@ func cache_enable()
 func cache_disable()
 func cache_flush_data()
 func cache_flush_instruction()
+func read_ctr() uint32
+func cache_clean_data_range(start uint32, end uint32, line uint32)
+func cache_invalidate_data_range(start uint32, end uint32, line uint32)
+func cache_flush_data_range(start uint32, end uint32, line uint32)
 
 // EnableSMP sets the SMP bit in Cortex-A7 Auxiliary Control Register, to
 // enable coherent requests to the processor. This must be ensured before
@@ -33,6 +39,12 @@ func (cpu *CPU) EnableSMP() {
 }
 
 // EnableCache activates the ARM instruction and data caches.
+//
+// On cores with an integrated L2 cache (e.g. Cortex-A7), rather than an
+// external L2C-310 (PL310) controller, the L2 is enabled and disabled
+// together with the L1 data cache and is covered by all cache maintenance
+// operations of this package, which operate on all levels up to the point of
+// coherency.
 func (cpu *CPU) EnableCache() {
 	cache_enable()
 }
@@ -42,11 +54,77 @@ func (cpu *CPU) DisableCache() {
 	cache_disable()
 }
 
-// FlushDataCache flushes the ARM data cache.
+// FlushDataCache cleans and invalidates, by set/way, all ARM data cache
+// levels up to the level of coherency (including any integrated L2).
 func (cpu *CPU) FlushDataCache() {
 	cache_flush_data()
 }
 
+// DataCacheLineSize returns the smallest data cache line size, in bytes,
+// across all cache levels.
+func (cpu *CPU) DataCacheLineSize() int {
+	return 4 << ((read_ctr() >> CTR_DMINLINE) & 0xf)
+}
+
+// dataCacheRange returns the cache line aligned boundaries of a memory range.
+func (cpu *CPU) dataCacheRange(addr uint32, size int) (start uint32, end uint32, line uint32) {
+	line = uint32(cpu.DataCacheLineSize())
+	start = addr &^ (line - 1)
+	end = (addr + uint32(size) + line - 1) &^ (line - 1)
+
+	return
+}
+
+// CleanDataCacheRange cleans the ARM data cache lines holding the argument
+// memory range to the point of coherency, ensuring that its content is
+// written back to memory (e.g. before a DMA transfer from memory).
+func (cpu *CPU) CleanDataCacheRange(addr uint32, size int) {
+	if size <= 0 {
+		return
+	}
+
+	cache_clean_data_range(cpu.dataCacheRange(addr, size))
+}
+
+// InvalidateDataCacheRange invalidates the ARM data cache lines holding the
+// argument memory range to the point of coherency, ensuring that its content
+// is fetched from memory on the next access (e.g. after a DMA transfer to
+// memory).
+//
+// Partial cache lines at the range boundaries are cleaned before being
+// invalidated, to preserve adjacent data.
+func (cpu *CPU) InvalidateDataCacheRange(addr uint32, size int) {
+	if size <= 0 {
+		return
+	}
+
+	start, end, line := cpu.dataCacheRange(addr, size)
+
+	if addr != start {
+		cache_flush_data_range(start, start+line, line)
+		start += line
+	}
+
+	if tail := addr + uint32(size); tail != end && end > start {
+		cache_flush_data_range(end-line, end, line)
+		end -= line
+	}
+
+	if end > start {
+		cache_invalidate_data_range(start, end, line)
+	}
+}
+
+// FlushDataCacheRange cleans and invalidates the ARM data cache lines holding
+// the argument memory range to the point of coherency.
+func (cpu *CPU) FlushDataCacheRange(addr uint32, size int) {
+	if size <= 0 {
+		return
+	}
+
+	cache_flush_data_range(cpu.dataCacheRange(addr, size))
+}
+
 // FlushInstructionCache flushes the ARM instruction cache.
 func (cpu *CPU) FlushInstructionCache() {
 	cache_flush_instruction()
@@ -111,3 +111,53 @@ TEXT ·cache_flush_instruction(SB),$0
 	MOVW	$0, R0
 	MCR	15, 0, R0, C7, C5, 0
 	RET
+
+// func read_ctr() uint32
+TEXT ·read_ctr(SB),$0-4
+	// ARM Architecture Reference Manual - ARMv7-A and ARMv7-R edition
+	// B4.1.42 CTR, Cache Type Register, VMSA
+	MRC	15, 0, R0, C0, C0, 1
+	MOVW	R0, ret+0(FP)
+	RET
+
+// func cache_clean_data_range(start uint32, end uint32, line uint32)
+TEXT ·cache_clean_data_range(SB),$0-12
+	MOVW	start+0(FP), R0
+	MOVW	end+4(FP), R1
+	MOVW	line+8(FP), R2
+	WORD	$0xf57ff05f			// DMB SY
+clean_line:
+	MCR	15, 0, R0, C7, C10, 1		// DCCMVAC
+	ADD	R2, R0
+	CMP	R1, R0
+	BLO	clean_line
+	WORD	$0xf57ff04f			// DSB SY
+	RET
+
+// func cache_invalidate_data_range(start uint32, end uint32, line uint32)
+TEXT ·cache_invalidate_data_range(SB),$0-12
+	MOVW	start+0(FP), R0
+	MOVW	end+4(FP), R1
+	MOVW	line+8(FP), R2
+	WORD	$0xf57ff05f			// DMB SY
+invalidate_line:
+	MCR	15, 0, R0, C7, C6, 1		// DCIMVAC
+	ADD	R2, R0
+	CMP	R1, R0
+	BLO	invalidate_line
+	WORD	$0xf57ff04f			// DSB SY
+	RET
+
+// func cache_flush_data_range(start uint32, end uint32, line uint32)
+TEXT ·cache_flush_data_range(SB),$0-12
+	MOVW	start+0(FP), R0
+	MOVW	end+4(FP), R1
+	MOVW	line+8(FP), R2
+	WORD	$0xf57ff05f			// DMB SY
+flush_line:
+	MCR	15, 0, R0, C7, C14, 1		// DCCIMVAC
+	ADD	R2, R0
+	CMP	R1, R0
+	BLO	flush_line
+	WORD	$0xf57ff04f			// DSB SY
+	RET
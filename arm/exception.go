@@ -10,6 +10,7 @@
 package arm
 
 import (
+	"errors"
 	"unsafe"
 
 	"github.com/usbarmory/tamago/internal/reg"
@@ -30,6 +31,13 @@ const (
 // set by cpu.Init()
 var vecTableStart uint32
 
+// active vector table address, see SetVectorBase()
+var vecBase uint32
+
+// saved caller registers (r0-rN, lr) of the last exception, set by the
+// exception handlers defined in exception.s
+var excFrame uint32
+
 const (
 	vecTableJump   = 0xe59ff018 // ldr pc, [pc, #24]
 	vecTableSize   = 0x4000     // 16 kB
@@ -37,8 +45,36 @@ const (
 	excStackSize   = 0x4000     // 16 kB
 )
 
+// ExceptionStacks represents the stack sizes, in bytes, of each exception
+// mode, all stacks are allocated within the 16 kB exception stack area
+// reserved by CPU.Init().
+type ExceptionStacks struct {
+	FIQ        int
+	IRQ        int
+	Supervisor int
+	Monitor    int
+	Abort      int
+	Undefined  int
+}
+
+// DefaultExceptionStacks represents the exception mode stack sizes set at CPU
+// initialization.
+//
+// Among the handlers of SystemVectorTable() only the IRQ one uses its
+// exception mode stack, the others switch to the Go runtime stack. The FIQ
+// mode is given an equal share to accommodate custom vector tables, where FIQ
+// servicing is typically performed within the exception mode.
+var DefaultExceptionStacks = ExceptionStacks{
+	FIQ:        0x1000, // 4 kB
+	IRQ:        0x1000, // 4 kB
+	Supervisor: 0x0800, // 2 kB
+	Monitor:    0x0800, // 2 kB
+	Abort:      0x0800, // 2 kB
+	Undefined:  0x0800, // 2 kB
+}
+
 // defined in exception.s
-func set_exc_stack(addr uint32)
+func set_mode_stack(mode uint32, addr uint32)
 func set_vbar(addr uint32)
 func set_mvbar(addr uint32)
 func resetHandler()
@@ -49,6 +85,10 @@ func dataAbortHandler()
 func irqHandler()
 func fiqHandler()
 func nullHandler()
+func read_dfsr() uint32
+func read_dfar() uint32
+func read_ifsr() uint32
+func read_ifar() uint32
 
 type ExceptionHandler func()
 
@@ -66,13 +106,186 @@ type VectorTable struct {
 	FIQ           ExceptionHandler
 }
 
-// DefaultExceptionHandler handles an exception by printing its vector and
-// processor mode before panicking.
+// ExceptionContext represents the processor state at the time of an
+// exception, the fault status and address registers are only relevant for
+// abort exceptions.
+type ExceptionContext struct {
+	// Exception vector offset
+	Vector int
+	// Processor mode
+	Mode int
+
+	// General purpose registers R0-R12, R8-R12 are not saved (and
+	// therefore zero) on FIQ exceptions as they are banked.
+	R [13]uint32
+	// Program Counter of the faulting (or, for FIQ and SUPERVISOR, next)
+	// instruction
+	PC uint32
+
+	// Data Fault Status Register
+	DFSR uint32
+	// Data Fault Address Register
+	DFAR uint32
+	// Instruction Fault Status Register
+	IFSR uint32
+	// Instruction Fault Address Register
+	IFAR uint32
+}
+
+// AlignmentFault returns whether the exception context represents a data
+// abort caused by an alignment fault (DFSR.FS 0b00001, Short-descriptor format
+// FSR encodings, ARM Architecture Reference Manual ARMv7-A and ARMv7-R
+// edition).
+func (ctx *ExceptionContext) AlignmentFault() bool {
+	if ctx.Vector != DATA_ABORT {
+		return false
+	}
+
+	fs := (ctx.DFSR>>10&1)<<4 | ctx.DFSR&0xf
+
+	return fs == 0b00001
+}
+
+// FaultHandler represents a handler for abort and undefined instruction
+// exceptions.
+type FaultHandler func(ctx *ExceptionContext)
+
+// abort and undefined instruction handlers, indexed by vector offset
+var faultHandlers [8]FaultHandler
+
+// exception context passed to fault handlers, kept outside the stack to
+// avoid heap allocation in the exception path
+var faultContext ExceptionContext
+
+// NewExceptionContext returns the processor state for the argument exception
+// vector offset, it must be invoked within the exception handler.
+func NewExceptionContext(off int) (ctx ExceptionContext) {
+	ctx.Vector = off
+	ctx.Mode = int(read_cpsr() & 0x1f)
+
+	if excFrame != 0 {
+		// FIQ mode banks R8-R12, which are therefore not saved
+		n := len(ctx.R)
+
+		if off == FIQ {
+			n = 8
+		}
+
+		for i := 0; i < n; i++ {
+			ctx.R[i] = reg.Read(excFrame + uint32(4*i))
+		}
+
+		ctx.PC = reg.Read(excFrame + uint32(4*n))
+	}
+
+	switch off {
+	case DATA_ABORT:
+		ctx.DFSR = read_dfsr()
+		ctx.DFAR = read_dfar()
+	case PREFETCH_ABORT:
+		ctx.IFSR = read_ifsr()
+		ctx.IFAR = read_ifar()
+	}
+
+	return ctx
+}
+
+// Print prints the exception context.
+func (ctx *ExceptionContext) Print() {
+	print("exception: vector ", VectorName(ctx.Vector), " mode ", ModeName(ctx.Mode), "\n")
+
+	print("\tPC ")
+	printHex(ctx.PC)
+	print("\n")
+
+	for i, r := range ctx.R {
+		if i%4 == 0 {
+			print("\t")
+		} else {
+			print(" ")
+		}
+
+		print("r", i, " ")
+		printHex(r)
+
+		if i%4 == 3 || i == len(ctx.R)-1 {
+			print("\n")
+		}
+	}
+
+	switch ctx.Vector {
+	case DATA_ABORT:
+		print("\tDFSR ")
+		printHex(ctx.DFSR)
+		print(" DFAR ")
+		printHex(ctx.DFAR)
+		print("\n")
+	case PREFETCH_ABORT:
+		print("\tIFSR ")
+		printHex(ctx.IFSR)
+		print(" IFAR ")
+		printHex(ctx.IFAR)
+		print("\n")
+	}
+}
+
+func printHex(val uint32) {
+	const digits = "0123456789abcdef"
+
+	print("0x")
+
+	for i := 28; i >= 0; i -= 4 {
+		d := (val >> i) & 0xf
+		print(digits[d : d+1])
+	}
+}
+
+// DefaultExceptionHandler handles an exception by printing its context
+// before panicking.
 func DefaultExceptionHandler(off int) {
-	print("exception: vector ", off, " mode ", int(read_cpsr()&0x1f), "\n")
+	ctx := NewExceptionContext(off)
+	ctx.Print()
 	panic("unhandled exception")
 }
 
+// UndefinedHandler sets the handler invoked, in place of
+// SystemExceptionHandler, on undefined instruction exceptions raised by the
+// vector table returned by SystemVectorTable(). A nil argument removes the
+// handler.
+//
+// Returning from the handler resumes execution at the faulting instruction,
+// the handler should therefore either resolve the fault cause or never
+// return.
+func (cpu *CPU) UndefinedHandler(fn FaultHandler) {
+	setFaultHandler(UNDEFINED, fn)
+}
+
+// PrefetchAbortHandler sets the handler invoked, in place of
+// SystemExceptionHandler, on prefetch abort exceptions raised by the vector
+// table returned by SystemVectorTable(). A nil argument removes the handler.
+//
+// Returning from the handler resumes execution at the faulting instruction,
+// the handler should therefore either resolve the fault cause or never
+// return.
+func (cpu *CPU) PrefetchAbortHandler(fn FaultHandler) {
+	setFaultHandler(PREFETCH_ABORT, fn)
+}
+
+// DataAbortHandler sets the handler invoked, in place of
+// SystemExceptionHandler, on data abort exceptions raised by the vector table
+// returned by SystemVectorTable(). A nil argument removes the handler.
+//
+// Returning from the handler resumes execution at the faulting instruction,
+// the handler should therefore either resolve the fault cause or never
+// return.
+func (cpu *CPU) DataAbortHandler(fn FaultHandler) {
+	setFaultHandler(DATA_ABORT, fn)
+}
+
+func setFaultHandler(off int, fn FaultHandler) {
+	faultHandlers[off/4] = fn
+}
+
 // SystemExceptionHandler allows to override the default exception handler
 // executed at any exception by the table returned by SystemVectorTable(),
 // which is used by default when initializing the CPU instance (e.g.
@@ -80,6 +293,12 @@ func DefaultExceptionHandler(off int) {
 var SystemExceptionHandler = DefaultExceptionHandler
 
 func systemException(off int) {
+	if fn := faultHandlers[off/4]; fn != nil {
+		faultContext = NewExceptionContext(off)
+		fn(&faultContext)
+		return
+	}
+
 	SystemExceptionHandler(off)
 }
 
@@ -123,7 +342,7 @@ func VectorName(off int) string {
 // SetVectorTable updates the CPU exception handling vector table with the
 // addresses of the functions defined in the passed structure.
 func SetVectorTable(t VectorTable) {
-	vecTable := vecTableStart + 8*4
+	vecTable := vecBase + 8*4
 
 	// set handler pointers
 	// Table 11-1 ARM® Cortex™ -A Series Programmer’s Guide
@@ -139,6 +358,8 @@ func SetVectorTable(t VectorTable) {
 
 //go:nosplit
 func (cpu *CPU) initVectorTable() {
+	vecBase = vecTableStart
+
 	// initialize jump table
 	// Table 11-1 ARM® Cortex™ -A Series Programmer’s Guide
 	for i := uint32(0); i < 8; i++ {
@@ -158,6 +379,111 @@ func (cpu *CPU) initVectorTable() {
 
 	// Set the stack pointer for exception modes to provide a stack when
 	// summoned by exception vectors.
-	excStackStart := vecTableStart + excStackOffset
-	set_exc_stack(excStackStart + excStackSize)
+	setExceptionStacks(DefaultExceptionStacks)
+}
+
+//go:nosplit
+func setExceptionStacks(stacks ExceptionStacks) {
+	modes := []struct {
+		mode int
+		size int
+	}{
+		{FIQ_MODE, stacks.FIQ},
+		{IRQ_MODE, stacks.IRQ},
+		{SVC_MODE, stacks.Supervisor},
+		{MON_MODE, stacks.Monitor},
+		{ABT_MODE, stacks.Abort},
+		{UND_MODE, stacks.Undefined},
+	}
+
+	// stacks are full descending, each mode is assigned the area below
+	// the previous one
+	sp := vecTableStart + excStackOffset + excStackSize
+
+	for _, m := range modes {
+		set_mode_stack(uint32(m.mode), sp)
+		sp -= uint32(m.size)
+	}
+}
+
+// SetExceptionStacks configures the stack sizes of each exception mode, the
+// sizes must be multiples of 8 bytes, to preserve stack alignment, and their
+// sum must not exceed the 16 kB exception stack area.
+//
+// The exception stacks must not be in use when invoking this function, which
+// is therefore meant to be called at initialization.
+func (cpu *CPU) SetExceptionStacks(stacks ExceptionStacks) (err error) {
+	var total int
+
+	for _, size := range []int{
+		stacks.FIQ,
+		stacks.IRQ,
+		stacks.Supervisor,
+		stacks.Monitor,
+		stacks.Abort,
+		stacks.Undefined,
+	} {
+		if size <= 0 || size%8 != 0 {
+			return errors.New("invalid exception stack size")
+		}
+
+		total += size
+	}
+
+	if total > excStackSize {
+		return errors.New("exception stacks exceed reserved area")
+	}
+
+	if vecBase == 0 {
+		return errors.New("vector table is not initialized")
+	}
+
+	setExceptionStacks(stacks)
+
+	return
+}
+
+// SetVectorBase relocates the exception vector table to the argument
+// address, which must be 32-byte aligned, by copying the current jump table
+// and handler pointers and updating the Vector Base Address Register (VBAR),
+// as well as the Monitor Vector Base Address Register (MVBAR) in Secure
+// World.
+//
+// The destination must provide 64 bytes of executable memory, subsequent
+// SetVectorTable() invocations update the relocated table.
+func (cpu *CPU) SetVectorBase(addr uint32) (err error) {
+	if addr&0x1f != 0 {
+		return errors.New("vector base must be 32-byte aligned")
+	}
+
+	if vecBase == 0 {
+		return errors.New("vector table is not initialized")
+	}
+
+	// The security state is probed before relocation, as its detection
+	// in Normal World traps an UNDEFINED exception on the active table.
+	secure := cpu.Secure()
+
+	// jump table and handler pointers
+	for i := uint32(0); i < 16; i++ {
+		reg.Write(addr+4*i, reg.Read(vecBase+4*i))
+	}
+
+	cpu.CleanDataCacheRange(addr, 16*4)
+	cpu.FlushInstructionCache()
+
+	vecBase = addr
+
+	set_vbar(vecBase)
+
+	if secure {
+		set_mvbar(vecBase)
+	}
+
+	return
+}
+
+// VectorBase returns the exception vector table address.
+func (cpu *CPU) VectorBase() uint32 {
+	return vecBase
 }
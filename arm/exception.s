@@ -10,36 +10,23 @@
 #include "go_asm.h"
 #include "textflag.h"
 
-// func set_exc_stack(addr uint32)
-TEXT ·set_exc_stack(SB),NOSPLIT,$0-4
-	MOVW addr+0(FP), R0
+// func set_mode_stack(mode uint32, addr uint32)
+TEXT ·set_mode_stack(SB),NOSPLIT,$0-8
+	MOVW	mode+0(FP), R1
+	MOVW	addr+4(FP), R0
 
-	// Set FIQ mode SP
-	WORD	$0xe321f0d1	// msr CPSR_c, 0xd1
-	MOVW R0, R13
+	// save current mode and interrupt masks
+	WORD	$0xe10f2000	// mrs r2, CPSR
 
-	// Set IRQ mode SP
-	WORD	$0xe321f0d2	// msr CPSR_c, 0xd2
-	MOVW R0, R13
+	// switch to the requested mode, with IRQs and FIQs masked
+	ORR	$0xc0, R1
+	WORD	$0xe121f001	// msr CPSR_c, r1
 
-	// Set Supervisor mode SP
-	WORD	$0xe321f0d3	// msr CPSR_c, 0xd3
-	MOVW R0, R13
+	// set its banked SP
+	MOVW	R0, R13
 
-	// Set Monitor mode SP
-	WORD	$0xe321f0d6	// msr CPSR_c, 0xd6
-	MOVW R0, R13
-
-	// Set Abort mode SP
-	WORD	$0xe321f0d7	// msr CPSR_c, 0xd7
-	MOVW R0, R13
-
-	// Set Undefined mode SP
-	WORD	$0xe321f0db	// msr CPSR_c, 0xdb
-	MOVW R0, R13
-
-	// Return to System mode
-	WORD	$0xe321f0df	// msr CPSR_c, 0xdf
+	// restore previous mode and interrupt masks
+	WORD	$0xe121f002	// msr CPSR_c, r2
 
 	RET
 
@@ -64,6 +51,7 @@ TEXT ·set_mvbar(SB),NOSPLIT,$0-4
 									\
 	/* save caller registers */					\
 	MOVM.DB.W	[R0-RN, R14], (R13)	/* push {r0-rN, r14} */	\
+	MOVW	R13, ·excFrame(SB)					\
 									\
 	/* restore g in case this mode banks them */			\
 	MOVW	$SAVE_SIZE, R0						\
@@ -135,3 +123,27 @@ TEXT ·fiqHandler(SB),NOSPLIT|NOFRAME,$0
 
 TEXT ·nullHandler(SB),NOSPLIT|NOFRAME,$0
 	MOVW.S	R14, R15
+
+// func read_dfsr() uint32
+TEXT ·read_dfsr(SB),NOSPLIT,$0-4
+	MRC	15, 0, R0, C5, C0, 0
+	MOVW	R0, ret+0(FP)
+	RET
+
+// func read_dfar() uint32
+TEXT ·read_dfar(SB),NOSPLIT,$0-4
+	MRC	15, 0, R0, C6, C0, 0
+	MOVW	R0, ret+0(FP)
+	RET
+
+// func read_ifsr() uint32
+TEXT ·read_ifsr(SB),NOSPLIT,$0-4
+	MRC	15, 0, R0, C5, C0, 1
+	MOVW	R0, ret+0(FP)
+	RET
+
+// func read_ifar() uint32
+TEXT ·read_ifar(SB),NOSPLIT,$0-4
+	MRC	15, 0, R0, C6, C0, 2
+	MOVW	R0, ret+0(FP)
+	RET
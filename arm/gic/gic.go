@@ -38,10 +38,11 @@ const (
 	GICD_TYPER         = 0x004
 	GICD_TYPER_ITLINES = 0
 
-	GICD_IGROUPR   = 0x080
-	GICD_ISENABLER = 0x100
-	GICD_ICENABLER = 0x180
-	GICD_ICPENDR   = 0x280
+	GICD_IGROUPR    = 0x080
+	GICD_ISENABLER  = 0x100
+	GICD_ICENABLER  = 0x180
+	GICD_ICPENDR    = 0x280
+	GICD_IPRIORITYR = 0x400
 
 	// CPU interface register map
 	// (p76, Table 4-2, ARM Generic Interrupt Controller Architecture Specification).
@@ -161,6 +162,36 @@ func (hw *GIC) DisableInterrupt(id int) {
 	irq(hw.gicd, id, false, false)
 }
 
+// SetPriority sets the priority of the corresponding interrupt, lower values
+// indicate higher priority.
+//
+// Only the most significant bits supported by the implementation are taken
+// into account (e.g. 5 bits on Cortex-A7) and interrupts are signaled only if
+// their priority is higher than the CPU interface priority mask, which is set
+// to 0x80 at initialization.
+func (hw *GIC) SetPriority(id int, prio uint8) {
+	if hw.gicd == 0 {
+		return
+	}
+
+	n := uint32(id / 4)
+	i := (id % 4) * 8
+
+	reg.SetN(hw.gicd+GICD_IPRIORITYR+4*n, i, 0xff, uint32(prio))
+}
+
+// GetPriority returns the priority of the corresponding interrupt.
+func (hw *GIC) GetPriority(id int) (prio uint8) {
+	if hw.gicd == 0 {
+		return
+	}
+
+	n := uint32(id / 4)
+	i := (id % 4) * 8
+
+	return uint8(reg.Get(hw.gicd+GICD_IPRIORITYR+4*n, i, 0xff))
+}
+
 // GetInterrupt obtains and acknowledges a signaled interrupt, the end of its
 // handling must be signaled by closing the returned channel.
 func (hw *GIC) GetInterrupt(secure bool) (id int, end chan struct{}) {
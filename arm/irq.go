@@ -39,6 +39,23 @@ func (cpu *CPU) DisableInterrupts(saved bool) {
 	irq_disable(saved)
 }
 
+// WithoutInterrupts executes the argument function with IRQ interrupts masked
+// in the current program status, restoring their previous state on return.
+//
+// It can be used to serialize register read-modify-write sequences with
+// custom exception handlers, the function must not block as IRQs are not
+// received during its execution.
+func (cpu *CPU) WithoutInterrupts(fn func()) {
+	masked := read_cpsr()&(1<<CPSR_I) != 0
+
+	if !masked {
+		irq_disable(false)
+		defer irq_enable(false)
+	}
+
+	fn()
+}
+
 // EnableFastInterrupts unmasks FIQ interrupts in the current or saved program
 // status.
 func (cpu *CPU) EnableFastInterrupts(saved bool) {
@@ -53,6 +70,22 @@ func (cpu *CPU) DisableFastInterrupts(saved bool) {
 
 // RegisterInterruptHandler sets the calling goroutine as IRQ handler, the
 // goroutine must then use WaitInterrupt() to receive an IRQ and service it.
+//
+// The IRQ exception handler only wakes up the IRQ handling goroutine, on the
+// IRQ mode stack (see SetExceptionStacks()), and never nests as IRQs remain
+// masked until WaitInterrupt() is invoked again.
+//
+// Limited nesting of interrupt servicing is possible through the interrupt
+// controller priorities, as an acknowledged GIC interrupt (see
+// gic.GetInterrupt()) prevents signaling of interrupts of equal or lower
+// priority until its end is signaled. The IRQ handling goroutine can
+// therefore acknowledge an interrupt, hand over its lengthy servicing to a
+// separate goroutine, which signals its end once complete, and re-enter
+// WaitInterrupt() to receive higher priority interrupts in the meantime.
+//
+// As required by the GIC, interrupts must complete in the reverse order of
+// their acknowledgment, preempting interrupts must therefore be serviced
+// without blocking on lower priority ones.
 func RegisterInterruptHandler() {
 	irqHandlerG, irqHandlerP = runtime.GetG()
 }
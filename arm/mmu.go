@@ -10,6 +10,7 @@
 package arm
 
 import (
+	"errors"
 	"runtime"
 
 	"github.com/usbarmory/tamago/internal/reg"
@@ -34,6 +35,37 @@ const (
 	TTE_NS            uint32 = (1 << 19)
 )
 
+// First-level section descriptor fields
+// (Figure B3-4, ARM Architecture Reference Manual ARMv7-A and ARMv7-R edition).
+const (
+	TTE_SECTION_ADDR = 20
+	TTE_SECTION_AP2  = 15
+	TTE_SECTION_TEX  = 12
+	TTE_SECTION_AP   = 10
+)
+
+// MemoryAttribute represents the memory type and cacheability of a memory
+// region, as encoded in the TEX, C and B bits of a first-level section
+// descriptor with TEX remap disabled (Table B3-10, ARM Architecture Reference
+// Manual ARMv7-A and ARMv7-R edition).
+type MemoryAttribute uint32
+
+// Memory region types
+const (
+	// Strongly-ordered (TEX: 0b000, C: 0, B: 0)
+	StronglyOrdered MemoryAttribute = 0
+	// Shareable Device (TEX: 0b000, C: 0, B: 1)
+	Device = MemoryAttribute(TTE_BUFFERABLE)
+	// Normal, Write-Through, no Write-Allocate (TEX: 0b000, C: 1, B: 0)
+	NormalWriteThrough = MemoryAttribute(TTE_CACHEABLE)
+	// Normal, Write-Back, no Write-Allocate (TEX: 0b000, C: 1, B: 1)
+	NormalWriteBack = MemoryAttribute(TTE_CACHEABLE | TTE_BUFFERABLE)
+	// Normal, Non-cacheable (TEX: 0b001, C: 0, B: 0)
+	NormalNonCacheable = MemoryAttribute(0b001 << TTE_SECTION_TEX)
+	// Normal, Write-Back, Write-Allocate (TEX: 0b001, C: 1, B: 1)
+	NormalWriteBackAllocate = MemoryAttribute(0b001<<TTE_SECTION_TEX | TTE_CACHEABLE | TTE_BUFFERABLE)
+)
+
 // MMU access permissions
 // (Table B3-8, ARM Architecture Reference Manual ARMv7-A and ARMv7-R edition).
 const (
@@ -97,6 +129,51 @@ func (cpu *CPU) ConfigureMMU(start uint32, end uint32, alias uint32, flags uint3
 	cpu.FlushTLBs()
 }
 
+// SetAttributes changes the memory type and access permissions (see TTE_AP_*
+// constants) of the first-level section descriptors mapping the argument
+// virtual memory range, the start and end addresses must be 1MB aligned.
+//
+// The physical address and security state of each section are preserved,
+// all other attributes are replaced. Sections mapped through second-level
+// translation tables (such as the first one, see InitMMU()) cannot be changed
+// and result in an error.
+func (cpu *CPU) SetAttributes(start uint32, end uint32, attr MemoryAttribute, ap uint32) (err error) {
+	if start%(1<<20) != 0 || end%(1<<20) != 0 || end < start {
+		return errors.New("invalid section range")
+	}
+
+	if ap > TTE_AP_111 || ap == TTE_AP_100 {
+		return errors.New("invalid access permissions")
+	}
+
+	l1pageTableStart := vecTableStart + l1pageTableOffset
+	first := start >> TTE_SECTION_ADDR
+	last := end >> TTE_SECTION_ADDR
+
+	for i := first; i < last; i++ {
+		if reg.Read(l1pageTableStart+4*i)&0b11 != TTE_SECTION {
+			return errors.New("range includes non-section descriptors")
+		}
+	}
+
+	for i := first; i < last; i++ {
+		page := l1pageTableStart + 4*i
+		entry := reg.Read(page) & (0xfff<<TTE_SECTION_ADDR | TTE_NS)
+
+		entry |= uint32(attr)
+		entry |= (ap & 0b11) << TTE_SECTION_AP
+		entry |= (ap >> 2) << TTE_SECTION_AP2
+		entry |= TTE_SECTION
+
+		reg.Write(page, entry)
+	}
+
+	cpu.FlushDataCache()
+	cpu.FlushTLBs()
+
+	return
+}
+
 // InitMMU initializes the first-level translation tables for all available
 // memory with a flat mapping and privileged attribute flags.
 //
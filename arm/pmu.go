@@ -0,0 +1,72 @@
+// ARM processor support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package arm
+
+// ARM Performance Monitors register constants
+const (
+	PMCR_D = 3
+	PMCR_C = 2
+	PMCR_P = 1
+	PMCR_E = 0
+
+	PMCNTEN_C = 31
+	PMOVS_C   = 31
+)
+
+// defined in pmu.s
+func read_pmcr() uint32
+func write_pmcr(val uint32)
+func write_pmcntenset(val uint32)
+func read_pmovsr() uint32
+func write_pmovsr(val uint32)
+func read_pmccntr() uint32
+
+// EnablePMU enables the ARM Performance Monitors and resets the cycle counter,
+// which is incremented on every processor clock cycle.
+func (cpu *CPU) EnablePMU() {
+	pmcr := read_pmcr()
+
+	// count every cycle
+	pmcr &^= 1 << PMCR_D
+	pmcr |= 1 << PMCR_E
+
+	write_pmcr(pmcr)
+	write_pmcntenset(1 << PMCNTEN_C)
+
+	cpu.ResetCycleCount()
+}
+
+// ResetCycleCount resets the ARM Performance Monitors cycle counter.
+func (cpu *CPU) ResetCycleCount() {
+	write_pmcr(read_pmcr() | 1<<PMCR_C)
+	write_pmovsr(1 << PMOVS_C)
+
+	cpu.cycleOverflows = 0
+}
+
+// CycleCount returns the ARM Performance Monitors cycle counter value, the
+// EnablePMU() function must be invoked before use.
+//
+// The 32-bit hardware counter is extended to 64 bits by tracking its overflow
+// flag, therefore the function must be invoked at least once for every
+// counter overflow period (e.g. ~10 seconds at 396 MHz) to return accurate
+// values.
+func (cpu *CPU) CycleCount() uint64 {
+	cycles := read_pmccntr()
+
+	if read_pmovsr()&(1<<PMOVS_C) != 0 {
+		write_pmovsr(1 << PMOVS_C)
+		cpu.cycleOverflows += 1
+		// the overflow might have occurred after the first read
+		cycles = read_pmccntr()
+	}
+
+	return uint64(cpu.cycleOverflows)<<32 | uint64(cycles)
+}
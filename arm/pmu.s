@@ -0,0 +1,65 @@
+// ARM processor support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+// func read_pmcr() uint32
+TEXT ·read_pmcr(SB),$0-4
+	// ARM Architecture Reference Manual - ARMv7-A and ARMv7-R edition
+	// PMCR, Performance Monitors Control Register, VMSA
+	MRC	15, 0, R0, C9, C12, 0
+	MOVW	R0, ret+0(FP)
+
+	RET
+
+// func write_pmcr(val uint32)
+TEXT ·write_pmcr(SB),$0-4
+	// ARM Architecture Reference Manual - ARMv7-A and ARMv7-R edition
+	// PMCR, Performance Monitors Control Register, VMSA
+	MOVW	val+0(FP), R0
+	MCR	15, 0, R0, C9, C12, 0
+	WORD	$0xf57ff06f // isb sy
+
+	RET
+
+// func write_pmcntenset(val uint32)
+TEXT ·write_pmcntenset(SB),$0-4
+	// ARM Architecture Reference Manual - ARMv7-A and ARMv7-R edition
+	// PMCNTENSET, Performance Monitors Count Enable Set register, VMSA
+	MOVW	val+0(FP), R0
+	MCR	15, 0, R0, C9, C12, 1
+	WORD	$0xf57ff06f // isb sy
+
+	RET
+
+// func read_pmovsr() uint32
+TEXT ·read_pmovsr(SB),$0-4
+	// ARM Architecture Reference Manual - ARMv7-A and ARMv7-R edition
+	// PMOVSR, Performance Monitors Overflow Flag Status Register, VMSA
+	MRC	15, 0, R0, C9, C12, 3
+	MOVW	R0, ret+0(FP)
+
+	RET
+
+// func write_pmovsr(val uint32)
+TEXT ·write_pmovsr(SB),$0-4
+	// ARM Architecture Reference Manual - ARMv7-A and ARMv7-R edition
+	// PMOVSR, Performance Monitors Overflow Flag Status Register, VMSA
+	MOVW	val+0(FP), R0
+	MCR	15, 0, R0, C9, C12, 3
+
+	RET
+
+// func read_pmccntr() uint32
+TEXT ·read_pmccntr(SB),$0-4
+	// ARM Architecture Reference Manual - ARMv7-A and ARMv7-R edition
+	// PMCCNTR, Performance Monitors Cycle Count Register, VMSA
+	WORD	$0xf57ff06f // isb sy
+	MRC	15, 0, R0, C9, C13, 0
+	MOVW	R0, ret+0(FP)
+
+	RET
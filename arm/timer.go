@@ -91,6 +91,45 @@ func (cpu *CPU) SetTimer(t int64) {
 	cpu.TimerOffset = t - int64(cpu.TimerFn()*cpu.TimerMultiplier)
 }
 
+// TimerCount returns the raw value of the timer counter used by the runtime
+// (see TimerFn, e.g. CNTPCT after InitGenericTimers()), see TimerFreq() for
+// its frequency.
+func (cpu *CPU) TimerCount() uint64 {
+	if cpu.TimerFn == nil {
+		return 0
+	}
+
+	return uint64(cpu.TimerFn())
+}
+
+// TimerFreq returns the frequency, in Hz, of the timer counter used by the
+// runtime (see TimerCount()), as derived from TimerMultiplier.
+func (cpu *CPU) TimerFreq() uint32 {
+	if cpu.TimerMultiplier == 0 {
+		return 0
+	}
+
+	return uint32(refFreq / cpu.TimerMultiplier)
+}
+
+// Delay busy waits for at least the argument number of nanoseconds, without
+// yielding to the scheduler, by polling the CPU timer counter.
+//
+// The minimum reliable resolution is one counter tick, TimerMultiplier
+// nanoseconds (e.g. 125 ns with the 8 MHz generic timer used on i.MX6UL), as
+// the requested delay is rounded up to the next tick.
+func (cpu *CPU) Delay(ns int64) {
+	if cpu.TimerFn == nil || cpu.TimerMultiplier == 0 || ns <= 0 {
+		return
+	}
+
+	ticks := (ns + cpu.TimerMultiplier - 1) / cpu.TimerMultiplier
+	start := cpu.TimerFn()
+
+	for cpu.TimerFn()-start <= ticks {
+	}
+}
+
 // SetDownCounter sets a physical countdown timer.
 func (cpu *CPU) SetDownCounter(t int32, enable bool) {
 	write_cntptval(t, enable)
@@ -24,7 +24,14 @@ func (cpu *CPU) NonSecure() bool {
 		return false
 	}
 
-	vecTable := vecTableStart + 8*4
+	vecTable := vecBase
+
+	if vecTable == 0 {
+		vecTable = vecTableStart
+	}
+
+	// patch the active vector table (see SetVectorBase())
+	vecTable += 8 * 4
 	undefinedHandler := reg.Read(vecTable + UNDEFINED)
 
 	// NonSecure World cannot read the NS bit, the only way to infer it
@@ -36,22 +36,56 @@ const (
 	TZASC_CONF   = 0x000
 	CONF_REGIONS = 0
 
+	TZASC_ACTION = 0x004
+	ACTION       = 0
+
 	TZASC_LOCKDOWN_RANGE  = 0x008
 	TZASC_LOCKDOWN_SELECT = 0x00c
-	TZASC_SEC_INV_EN      = 0x034
+
+	TZASC_INT_STATUS   = 0x010
+	INT_STATUS_OVERRUN = 1
+	INT_STATUS_STATUS  = 0
+
+	TZASC_INT_CLEAR = 0x014
+
+	TZASC_FAIL_ADDRESS_LOW  = 0x020
+	TZASC_FAIL_ADDRESS_HIGH = 0x024
+
+	TZASC_FAIL_CONTROL     = 0x028
+	FAIL_CONTROL_WRITE     = 24
+	FAIL_CONTROL_NONSECURE = 21
+	FAIL_CONTROL_PRIV      = 20
+
+	TZASC_FAIL_ID = 0x02c
+
+	TZASC_SEC_INV_EN = 0x034
 
 	TZASC_REGION_SETUP_LOW_0  = 0x100
 	TZASC_REGION_SETUP_HIGH_0 = 0x104
 
-	TZASC_REGION_ATTRS_0 = 0x108
-	REGION_ATTRS_SP      = 28
-	REGION_ATTRS_SIZE    = 1
-	REGION_ATTRS_EN      = 0
+	TZASC_REGION_ATTRS_0   = 0x108
+	REGION_ATTRS_SP        = 28
+	REGION_ATTRS_SUBREG_DS = 8
+	REGION_ATTRS_SIZE      = 1
+	REGION_ATTRS_EN        = 0
 
 	SIZE_MIN = 0b001110
 	SIZE_MAX = 0b111111
 )
 
+// TZASC actions on region permission failures
+// (3.2.2 Action Register, TZC-380 TRM).
+const (
+	// tzasc_int LOW, OKAY response
+	ACTION_OKAY = 0b00
+	// tzasc_int LOW, DECERR response
+	ACTION_DECERR = 0b01
+	// tzasc_int HIGH, OKAY response
+	ACTION_INT_OKAY = 0b10
+	// tzasc_int HIGH, DECERR response
+	ACTION_INT_DECERR = 0b11
+)
+
 // TZASC security permissions,
 // (p28, Table 2-4, TZC-380 TRM).
 const (
@@ -65,10 +99,59 @@ const (
 	SP_NW_WR = 0
 )
 
+// Permissions represents the security permissions of a TZASC region, values
+// can be combined (e.g. SecureRW | NonSecureRO).
+type Permissions uint32
+
+// TZASC region security permissions
+const (
+	NoAccess    Permissions = 0
+	SecureRO    Permissions = 1 << SP_SW_RD
+	SecureWO    Permissions = 1 << SP_SW_WR
+	SecureRW                = SecureRO | SecureWO
+	NonSecureRO Permissions = 1 << SP_NW_RD
+	NonSecureWO Permissions = 1 << SP_NW_WR
+	NonSecureRW             = NonSecureRO | NonSecureWO
+)
+
+// Subregions represents a TZASC region subregion disable mask, each region
+// is divided in 8 equally sized subregions and bit n disables subregion n
+// (where 0 is the lowest address one).
+//
+// A disabled subregion is not controlled by its region and permissions are
+// therefore determined by the next highest priority region which covers it.
+type Subregions uint8
+
+// Attributes represents the configuration of a TZASC region.
+type Attributes struct {
+	// Security permissions
+	SP Permissions
+	// Subregion disable mask
+	SubregionDisable Subregions
+}
+
+// Violation represents a TZASC region permission failure.
+type Violation struct {
+	// Accessed address
+	Address uint64
+	// AXI master ID
+	ID uint32
+	// Write (true) or read (false) access
+	Write bool
+	// NonSecure (true) or Secure (false) access
+	NonSecure bool
+	// Privileged (true) or unprivileged (false) access
+	Privileged bool
+	// Failures occurred before the reported one was cleared
+	Overrun bool
+}
+
 // TZASC represents the TrustZone Address Space Controller instance.
 type TZASC struct {
 	// Base register
 	Base uint32
+	// Interrupt ID
+	IRQ int
 
 	// The bypass register controls the TZASC monitoring of DDR
 	// transactions.
@@ -91,12 +174,21 @@ type TZASC struct {
 
 	// control registers
 	conf                uint32
+	action              uint32
 	lockdown_range      uint32
 	lockdown_select     uint32
+	int_status          uint32
+	int_clear           uint32
+	fail_address_low    uint32
+	fail_address_high   uint32
+	fail_control        uint32
+	fail_id             uint32
 	sec_inv_en          uint32
 	region_setup_low_0  uint32
 	region_setup_high_0 uint32
 	region_attrs_0      uint32
+
+	handler func(Violation)
 }
 
 // Init initializes the TrustZone Address Space Controller (TZASC).
@@ -106,8 +198,15 @@ func (hw *TZASC) Init() {
 	}
 
 	hw.conf = hw.Base + TZASC_CONF
+	hw.action = hw.Base + TZASC_ACTION
 	hw.lockdown_range = hw.Base + TZASC_LOCKDOWN_RANGE
 	hw.lockdown_select = hw.Base + TZASC_LOCKDOWN_SELECT
+	hw.int_status = hw.Base + TZASC_INT_STATUS
+	hw.int_clear = hw.Base + TZASC_INT_CLEAR
+	hw.fail_address_low = hw.Base + TZASC_FAIL_ADDRESS_LOW
+	hw.fail_address_high = hw.Base + TZASC_FAIL_ADDRESS_HIGH
+	hw.fail_control = hw.Base + TZASC_FAIL_CONTROL
+	hw.fail_id = hw.Base + TZASC_FAIL_ID
 	hw.sec_inv_en = hw.Base + TZASC_SEC_INV_EN
 	hw.region_setup_low_0 = hw.Base + TZASC_REGION_SETUP_LOW_0
 	hw.region_setup_high_0 = hw.Base + TZASC_REGION_SETUP_HIGH_0
@@ -128,10 +227,20 @@ func (hw *TZASC) EnableSecurityInversion() {
 }
 
 // EnableRegion configures a TZASC region with the argument start address, size
-// and security permissions, for region 0 only security permissions are
-// relevant.
-func (hw *TZASC) EnableRegion(n int, start uint32, size uint32, sp int) (err error) {
-	var attrs uint32
+// and attributes, for region 0 only security permissions are relevant.
+//
+// The region size must be a power of two and at least 32KB, the start address
+// must be aligned to the region size. Higher numbered regions take priority
+// over lower numbered ones.
+//
+// Unless security inversion is enabled (see EnableSecurityInversion()),
+// permissions granting non-secure access are automatically extended to secure
+// access by the controller.
+//
+// Once the secure boot lock is set (see Lock()) region configuration can no
+// longer be changed until the next reset, in which case an error is returned.
+func (hw *TZASC) EnableRegion(n int, start uint32, size int, attrs Attributes) (err error) {
+	var val uint32
 	var s uint32
 
 	if n < 0 || n+1 > hw.Regions() {
@@ -142,25 +251,21 @@ func (hw *TZASC) EnableRegion(n int, start uint32, size uint32, sp int) (err err
 		return errors.New("TZASC inactive (bypass detected)")
 	}
 
-	if n == 0 {
-		reg.SetN(hw.region_attrs_0, REGION_ATTRS_SP, 0b1111, uint32(sp))
-		return
-	}
-
-	if start%(1<<15) != 0 {
-		return errors.New("incompatible start address")
+	if attrs.SP > SecureRW|NonSecureRW {
+		return errors.New("invalid security permissions")
 	}
 
-	if start != 0 && (start%size) != 0 {
-		return errors.New("start address must be a multiple of its region size")
+	if hw.locked() {
+		return errors.New("TZASC configuration is locked")
 	}
 
-	if sp > 0b1111 {
-		return errors.New("invalid security permissions")
+	if n == 0 {
+		reg.SetN(hw.region_attrs_0, REGION_ATTRS_SP, 0b1111, uint32(attrs.SP))
+		return
 	}
 
 	// size = 2^(s+1)
-	for i := uint32(SIZE_MIN); i <= SIZE_MAX; i++ {
+	for i := uint32(SIZE_MIN); i <= SIZE_MAX && i < 30; i++ {
 		if size == (1 << (i + 1)) {
 			s = i
 			break
@@ -171,15 +276,20 @@ func (hw *TZASC) EnableRegion(n int, start uint32, size uint32, sp int) (err err
 		return errors.New("incompatible region size")
 	}
 
-	bits.SetN(&attrs, REGION_ATTRS_SP, 0b1111, uint32(sp))
-	bits.SetN(&attrs, REGION_ATTRS_SIZE, 0b111111, s)
-	bits.Set(&attrs, REGION_ATTRS_EN)
+	if start%uint32(size) != 0 {
+		return errors.New("start address must be a multiple of its region size")
+	}
+
+	bits.SetN(&val, REGION_ATTRS_SP, 0b1111, uint32(attrs.SP))
+	bits.SetN(&val, REGION_ATTRS_SUBREG_DS, 0xff, uint32(attrs.SubregionDisable))
+	bits.SetN(&val, REGION_ATTRS_SIZE, 0b111111, s)
+	bits.Set(&val, REGION_ATTRS_EN)
 
 	off := uint32(0x10 * n)
 
 	reg.Write(hw.region_setup_low_0+off, start&0xffff8000)
 	reg.Write(hw.region_setup_high_0+off, 0)
-	reg.Write(hw.region_attrs_0+off, attrs)
+	reg.Write(hw.region_attrs_0+off, val)
 
 	return
 }
@@ -194,11 +304,19 @@ func (hw *TZASC) DisableRegion(n int) (err error) {
 		return errors.New("TZASC inactive (bypass detected)")
 	}
 
+	if hw.locked() {
+		return errors.New("TZASC configuration is locked")
+	}
+
 	reg.Clear(hw.region_attrs_0+uint32(0x10*n), REGION_ATTRS_EN)
 
 	return
 }
 
+func (hw *TZASC) locked() bool {
+	return reg.Get(hw.SecureBootLockReg, hw.SecureBootLockPos, 1) == 1
+}
+
 // Lock enables TZASC secure boot lock register writing restrictions
 // (p30, 2.2.8 Preventing writes to registers and using secure_boot_lock, TZC-380 TRM).
 func (hw *TZASC) Lock() {
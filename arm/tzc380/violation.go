@@ -0,0 +1,122 @@
+// ARM TrustZone Address Space Controller TZC-380 driver
+// https://github.com/usbarmory/tamago
+//
+// IP: ARM CoreLink™ TrustZone Address Space Controller TZC-380
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package tzc380
+
+import (
+	"fmt"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// String returns a description of the permission failure.
+func (v Violation) String() string {
+	dir := "read"
+	world := "secure"
+	mode := "unprivileged"
+
+	if v.Write {
+		dir = "write"
+	}
+
+	if v.NonSecure {
+		world = "non-secure"
+	}
+
+	if v.Privileged {
+		mode = "privileged"
+	}
+
+	s := fmt.Sprintf("%s %s %s access to %#x from ID %#x", world, mode, dir, v.Address, v.ID)
+
+	if v.Overrun {
+		s += " (overrun)"
+	}
+
+	return s
+}
+
+// FailAddress returns the address of the first access which failed region
+// permission checks since the last interrupt clear.
+func (hw *TZASC) FailAddress() uint64 {
+	return uint64(reg.Read(hw.fail_address_high))<<32 | uint64(reg.Read(hw.fail_address_low))
+}
+
+// FailID returns the AXI master ID of the first access which failed region
+// permission checks since the last interrupt clear.
+func (hw *TZASC) FailID() uint32 {
+	return reg.Read(hw.fail_id)
+}
+
+// Violation returns whether a region permission failure is pending and its
+// details (3.2.5 Interrupt Status Register, TZC-380 TRM).
+func (hw *TZASC) Violation() (v Violation, pending bool) {
+	status := reg.Read(hw.int_status)
+
+	if status&(1<<INT_STATUS_STATUS) == 0 {
+		return
+	}
+
+	ctrl := reg.Read(hw.fail_control)
+
+	v = Violation{
+		Address:    hw.FailAddress(),
+		ID:         hw.FailID(),
+		Write:      (ctrl>>FAIL_CONTROL_WRITE)&1 == 1,
+		NonSecure:  (ctrl>>FAIL_CONTROL_NONSECURE)&1 == 1,
+		Privileged: (ctrl>>FAIL_CONTROL_PRIV)&1 == 1,
+		Overrun:    (status>>INT_STATUS_OVERRUN)&1 == 1,
+	}
+
+	return v, true
+}
+
+// ClearInterrupt clears a pending region permission failure, allowing the
+// next one to be recorded.
+func (hw *TZASC) ClearInterrupt() {
+	reg.Write(hw.int_clear, 1)
+}
+
+// EnableInterrupt configures the TZASC to signal region permission failures
+// on its interrupt line, the argument function is invoked by
+// ServiceInterrupt() for each failure. Failed accesses receive a DECERR
+// response.
+//
+// The application is responsible for enabling the TZASC interrupt (see IRQ)
+// on the interrupt controller and invoking ServiceInterrupt() upon its
+// reception.
+func (hw *TZASC) EnableInterrupt(fn func(Violation)) {
+	hw.handler = fn
+	reg.SetN(hw.action, ACTION, 0b11, ACTION_INT_DECERR)
+}
+
+// DisableInterrupt restores the default TZASC response to region permission
+// failures, which only issues a DECERR response.
+func (hw *TZASC) DisableInterrupt() {
+	reg.SetN(hw.action, ACTION, 0b11, ACTION_DECERR)
+	hw.handler = nil
+}
+
+// ServiceInterrupt reads and clears a pending region permission failure,
+// invoking the function set with EnableInterrupt().
+func (hw *TZASC) ServiceInterrupt() {
+	v, pending := hw.Violation()
+
+	if !pending {
+		return
+	}
+
+	hw.ClearInterrupt()
+
+	if fn := hw.handler; fn != nil {
+		fn(v)
+	}
+}
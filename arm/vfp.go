@@ -10,13 +10,34 @@
 package arm
 
 const (
+	CPACR_CP10 = 20
+	CPACR_CP11 = 22
+
 	FPEXC_EN = 30
 )
 
 // defined in vfp.s
 func vfp_enable()
+func read_cpacr() uint32
+func read_fpexc() uint32
 
-// EnableVFP activates the ARM Vector-Floating-Point co-processor.
+// EnableVFP activates the ARM Vector-Floating-Point co-processor, enabling
+// hardware floating point and Advanced SIMD (NEON) instructions.
+//
+// This is performed during SoC initialization on all supported targets (see
+// VFPEnabled()).
 func (cpu *CPU) EnableVFP() {
 	vfp_enable()
 }
+
+// VFPEnabled returns whether the ARM Vector-Floating-Point co-processor is
+// accessible and enabled.
+func (cpu *CPU) VFPEnabled() bool {
+	cpacr := read_cpacr()
+
+	if (cpacr>>CPACR_CP10)&0b11 != 0b11 || (cpacr>>CPACR_CP11)&0b11 != 0b11 {
+		return false
+	}
+
+	return read_fpexc()&(1<<FPEXC_EN) != 0
+}
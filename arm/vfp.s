@@ -22,3 +22,17 @@ TEXT ·vfp_enable(SB),$0
 	WORD	$0xeee83a10		// vmsr fpexc, r3
 
 	RET
+
+// func read_cpacr() uint32
+TEXT ·read_cpacr(SB),$0-4
+	MRC	15, 0, R0, C1, C0, 2
+	MOVW	R0, ret+0(FP)
+
+	RET
+
+// func read_fpexc() uint32
+TEXT ·read_fpexc(SB),$0-4
+	WORD	$0xeef80a10		// vmrs r0, fpexc
+	MOVW	R0, ret+0(FP)
+
+	RET
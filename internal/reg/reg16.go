@@ -16,6 +16,10 @@ import (
 
 // As sync/atomic does not provide 16-bit support, note that these functions do
 // not necessarily enforce memory ordering.
+//
+// Registers with a 16-bit width must only be accessed with these functions, as
+// wider accesses can fault or affect adjacent registers (e.g. i.MX I2C and
+// WDOG controllers, see soc/nxp/i2c and soc/nxp/wdog).
 
 func Get16(addr uint32, pos int, mask int) uint16 {
 	reg := (*uint16)(unsafe.Pointer(uintptr(addr)))
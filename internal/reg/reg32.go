@@ -9,6 +9,24 @@
 // Package reg provides primitives for retrieving and modifying hardware
 // registers.
 //
+// Register accesses must match the register width, 8-bit and 16-bit registers
+// must be accessed through the functions with the relevant suffix (e.g.
+// Read16, Write8).
+//
+// Read, Write and Get perform a single register access. Set, Clear, SetTo,
+// SetN, ClearN, Or and WriteBack instead perform a read-modify-write sequence
+// which is not atomic with respect to the hardware, or to code executing in
+// exception context, and therefore requires external synchronization.
+//
+// With `GOOS=tamago` goroutines are cooperatively scheduled on a single core
+// and IRQ exceptions only wake up the IRQ handling goroutine (see
+// arm.RegisterInterruptHandler), therefore a read-modify-write sequence is
+// never interleaved with register accesses from other goroutines, including
+// interrupt servicing ones. Registers accessed within custom exception
+// handlers must instead be modified with interrupts masked (see
+// arm.CPU.WithoutInterrupts), while write-1-to-clear status registers must
+// only be acknowledged with Write to avoid clearing unrelated events.
+//
 // This package is only meant to be used with `GOOS=tamago` as supported by the
 // TamaGo framework for bare metal Go on ARM/RISC-V SoCs, see
 // https://github.com/usbarmory/tamago.
@@ -21,6 +39,7 @@ import (
 	"unsafe"
 )
 
+// Get returns a register bit field, shifted to bit 0.
 func Get(addr uint32, pos int, mask int) uint32 {
 	reg := (*uint32)(unsafe.Pointer(uintptr(addr)))
 	r := atomic.LoadUint32(reg)
@@ -28,6 +47,8 @@ func Get(addr uint32, pos int, mask int) uint32 {
 	return uint32((int(r) >> pos) & mask)
 }
 
+// Set sets a register bit with a read-modify-write sequence (see package
+// documentation for synchronization requirements).
 func Set(addr uint32, pos int) {
 	reg := (*uint32)(unsafe.Pointer(uintptr(addr)))
 
@@ -37,6 +58,8 @@ func Set(addr uint32, pos int) {
 	atomic.StoreUint32(reg, r)
 }
 
+// Clear clears a register bit with a read-modify-write sequence (see package
+// documentation for synchronization requirements).
 func Clear(addr uint32, pos int) {
 	reg := (*uint32)(unsafe.Pointer(uintptr(addr)))
 
@@ -46,6 +69,8 @@ func Clear(addr uint32, pos int) {
 	atomic.StoreUint32(reg, r)
 }
 
+// SetTo sets or clears a register bit with a read-modify-write sequence (see
+// package documentation for synchronization requirements).
 func SetTo(addr uint32, pos int, val bool) {
 	if val {
 		Set(addr, pos)
@@ -54,6 +79,8 @@ func SetTo(addr uint32, pos int, val bool) {
 	}
 }
 
+// SetN sets a register bit field, of the given mask, with a read-modify-write
+// sequence (see package documentation for synchronization requirements).
 func SetN(addr uint32, pos int, mask int, val uint32) {
 	reg := (*uint32)(unsafe.Pointer(uintptr(addr)))
 
@@ -63,6 +90,9 @@ func SetN(addr uint32, pos int, mask int, val uint32) {
 	atomic.StoreUint32(reg, r)
 }
 
+// ClearN clears a register bit field, of the given mask, with a
+// read-modify-write sequence (see package documentation for synchronization
+// requirements).
 func ClearN(addr uint32, pos int, mask int) {
 	reg := (*uint32)(unsafe.Pointer(uintptr(addr)))
 
@@ -75,16 +105,20 @@ func ClearN(addr uint32, pos int, mask int) {
 // defined in reg32_*.s
 func Move(dst uint32, src uint32)
 
+// Read returns a register value with a single access.
 func Read(addr uint32) uint32 {
 	reg := (*uint32)(unsafe.Pointer(uintptr(addr)))
 	return atomic.LoadUint32(reg)
 }
 
+// Write sets a register value with a single access.
 func Write(addr uint32, val uint32) {
 	reg := (*uint32)(unsafe.Pointer(uintptr(addr)))
 	atomic.StoreUint32(reg, val)
 }
 
+// WriteBack writes back a register value, to acknowledge write-1-to-clear
+// bits.
 func WriteBack(addr uint32) {
 	reg := (*uint32)(unsafe.Pointer(uintptr(addr)))
 
@@ -94,6 +128,8 @@ func WriteBack(addr uint32) {
 	atomic.StoreUint32(reg, r)
 }
 
+// Or sets register bits with a read-modify-write sequence (see package
+// documentation for synchronization requirements).
 func Or(addr uint32, val uint32) {
 	reg := (*uint32)(unsafe.Pointer(uintptr(addr)))
 
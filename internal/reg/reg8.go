@@ -0,0 +1,91 @@
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package reg
+
+import (
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+// As sync/atomic does not provide 8-bit support, note that these functions do
+// not necessarily enforce memory ordering.
+
+// Get8 returns an 8-bit register bit field, shifted to bit 0.
+func Get8(addr uint32, pos int, mask int) uint8 {
+	reg := (*uint8)(unsafe.Pointer(uintptr(addr)))
+	return (*reg >> pos) & uint8(mask)
+}
+
+// Set8 sets an 8-bit register bit with a read-modify-write sequence.
+func Set8(addr uint32, pos int) {
+	reg := (*uint8)(unsafe.Pointer(uintptr(addr)))
+	*reg |= (1 << pos)
+}
+
+// Clear8 clears an 8-bit register bit with a read-modify-write sequence.
+func Clear8(addr uint32, pos int) {
+	reg := (*uint8)(unsafe.Pointer(uintptr(addr)))
+	*reg &= ^(1 << pos)
+}
+
+// SetTo8 sets or clears an 8-bit register bit with a read-modify-write
+// sequence.
+func SetTo8(addr uint32, pos int, val bool) {
+	if val {
+		Set8(addr, pos)
+	} else {
+		Clear8(addr, pos)
+	}
+}
+
+// SetN8 sets an 8-bit register bit field, of the given mask, with a
+// read-modify-write sequence.
+func SetN8(addr uint32, pos int, mask int, val uint8) {
+	reg := (*uint8)(unsafe.Pointer(uintptr(addr)))
+	*reg = (*reg & (^(uint8(mask) << pos))) | (val << pos)
+}
+
+// ClearN8 clears an 8-bit register bit field, of the given mask, with a
+// read-modify-write sequence.
+func ClearN8(addr uint32, pos int, mask int) {
+	reg := (*uint8)(unsafe.Pointer(uintptr(addr)))
+	*reg &= ^(uint8(mask) << pos)
+}
+
+// Read8 returns an 8-bit register value.
+func Read8(addr uint32) uint8 {
+	reg := (*uint8)(unsafe.Pointer(uintptr(addr)))
+	return *reg
+}
+
+// Write8 sets an 8-bit register value.
+func Write8(addr uint32, val uint8) {
+	reg := (*uint8)(unsafe.Pointer(uintptr(addr)))
+	*reg = val
+}
+
+// WaitFor8 waits, until a timeout expires, for a specific register bit to match
+// a value. The return boolean indicates whether the wait condition was checked
+// (true) or if it timed out (false). This function cannot be used before
+// runtime initialization with `GOOS=tamago`.
+func WaitFor8(timeout time.Duration, addr uint32, pos int, mask int, val uint8) bool {
+	start := time.Now()
+
+	for Get8(addr, pos, mask) != val {
+		// tamago is single-threaded, give other goroutines a chance
+		runtime.Gosched()
+
+		if time.Since(start) >= timeout {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,228 @@
+// NXP Analog-to-Digital Converter (ADC) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+// Package adc implements a driver for NXP 12-bit Analog-to-Digital Converters
+// (ADC) adopting the following reference specifications:
+//   - IMX6ULLRM - i.MX 6ULL Applications Processor Reference Manual - Rev 1 2017/11
+//
+// On the i.MX6UL/i.MX6ULL input channels 0 to 9 are routed to pads
+// GPIO1_IO00 to GPIO1_IO09 (e.g. channel 3 is sampled from GPIO1_IO03), the
+// pad must not be driven by its GPIO function while sampled.
+//
+// This package is only meant to be used with `GOOS=tamago GOARCH=arm` as
+// supported by the TamaGo framework for bare metal Go on ARM SoCs, see
+// https://github.com/usbarmory/tamago.
+package adc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// ADC registers
+// (ADC Memory Map/Register Definition, IMX6ULLRM).
+const (
+	ADCx_HC0 = 0x00
+	HC_AIEN  = 7
+	HC_ADCH  = 0
+
+	ADCx_HS  = 0x08
+	HS_COCO0 = 0
+
+	ADCx_R0 = 0x0c
+
+	ADCx_CFG   = 0x14
+	CFG_OVWREN = 16
+	CFG_AVGS   = 14
+	CFG_ADTRG  = 13
+	CFG_ADHSC  = 10
+	CFG_ADLSMP = 4
+	CFG_MODE   = 2
+	CFG_ADICLK = 0
+
+	ADCx_GC = 0x18
+	GC_CAL  = 7
+	GC_ADCO = 6
+	GC_AVGE = 5
+
+	ADCx_GS  = 0x1c
+	GS_CALF  = 1
+	GS_ADACT = 0
+)
+
+// Configuration constants
+const (
+	// number of external input channels
+	CHANNELS = 10
+	// conversion stopped channel value
+	ADCH_OFF = 0b11111
+	// 12-bit conversion mode
+	MODE_12BIT = 0b10
+	// input clock (IPG_CLK / 2)
+	ADICLK_IPG_DIV2 = 0b01
+
+	// Timeout is the default timeout for conversions and calibration.
+	Timeout = 10 * time.Millisecond
+)
+
+// ADC represents an Analog-to-Digital Converter instance.
+type ADC struct {
+	sync.Mutex
+
+	// Controller index
+	Index int
+	// Base register
+	Base uint32
+	// Clock gate register
+	CCGR uint32
+	// Clock gate
+	CG int
+	// Interrupt ID
+	IRQ int
+	// Hardware averaging samples (0 to disable, otherwise 4, 8, 16 or 32),
+	// changes take effect on the next Read().
+	Averaging int
+
+	// control registers
+	hc0 uint32
+	hs  uint32
+	r0  uint32
+	cfg uint32
+	gc  uint32
+	gs  uint32
+
+	calibrated bool
+}
+
+// Init initializes the ADC instance for 12-bit software triggered
+// conversions, Calibrate() must be invoked before any conversion.
+func (hw *ADC) Init() {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.Base == 0 || hw.CCGR == 0 {
+		panic("invalid ADC instance")
+	}
+
+	hw.hc0 = hw.Base + ADCx_HC0
+	hw.hs = hw.Base + ADCx_HS
+	hw.r0 = hw.Base + ADCx_R0
+	hw.cfg = hw.Base + ADCx_CFG
+	hw.gc = hw.Base + ADCx_GC
+	hw.gs = hw.Base + ADCx_GS
+
+	// enable clock
+	reg.SetN(hw.CCGR, hw.CG, 0b11, 0b11)
+
+	// stop conversions
+	reg.SetN(hw.hc0, HC_ADCH, 0x1f, ADCH_OFF)
+	reg.Clear(hw.hc0, HC_AIEN)
+
+	cfg := reg.Read(hw.cfg)
+	cfg &^= 1<<CFG_ADTRG | 0b11<<CFG_MODE | 0b11<<CFG_ADICLK
+	cfg |= MODE_12BIT<<CFG_MODE | ADICLK_IPG_DIV2<<CFG_ADICLK
+	// long sample time
+	cfg |= 1 << CFG_ADLSMP
+	reg.Write(hw.cfg, cfg)
+
+	reg.Clear(hw.gc, GC_ADCO)
+	hw.calibrated = false
+}
+
+func (hw *ADC) setAveraging(n int) (err error) {
+	var avgs uint32
+
+	switch n {
+	case 0:
+		reg.Clear(hw.gc, GC_AVGE)
+		return
+	case 4:
+		avgs = 0b00
+	case 8:
+		avgs = 0b01
+	case 16:
+		avgs = 0b10
+	case 32:
+		avgs = 0b11
+	default:
+		return fmt.Errorf("invalid averaging samples %d", n)
+	}
+
+	reg.SetN(hw.cfg, CFG_AVGS, 0b11, avgs)
+	reg.Set(hw.gc, GC_AVGE)
+
+	return
+}
+
+// Calibrate performs the ADC self-calibration, which is required after
+// initialization for accurate conversions.
+func (hw *ADC) Calibrate() (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.gc == 0 {
+		return errors.New("ADC is not initialized")
+	}
+
+	// calibrate with maximum averaging
+	if err = hw.setAveraging(32); err != nil {
+		return
+	}
+
+	reg.Write(hw.gs, 1<<GS_CALF)
+	reg.Set(hw.gc, GC_CAL)
+
+	if !reg.WaitFor(Timeout, hw.gc, GC_CAL, 1, 0) {
+		return errors.New("calibration timeout")
+	}
+
+	if reg.Get(hw.gs, GS_CALF, 1) == 1 {
+		reg.Write(hw.gs, 1<<GS_CALF)
+		return errors.New("calibration failed")
+	}
+
+	// discard calibration result
+	reg.Read(hw.r0)
+	hw.calibrated = true
+
+	return
+}
+
+// Read performs a single conversion on the argument input channel and
+// returns its 12-bit result.
+func (hw *ADC) Read(channel int) (val uint16, err error) {
+	if channel < 0 || channel >= CHANNELS {
+		return 0, errors.New("invalid channel")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if !hw.calibrated {
+		return 0, errors.New("ADC is not calibrated")
+	}
+
+	if err = hw.setAveraging(hw.Averaging); err != nil {
+		return
+	}
+
+	// start conversion
+	reg.SetN(hw.hc0, HC_ADCH, 0x1f, uint32(channel))
+
+	if !reg.WaitFor(Timeout, hw.hs, HS_COCO0, 1, 1) {
+		reg.SetN(hw.hc0, HC_ADCH, 0x1f, ADCH_OFF)
+		return 0, errors.New("conversion timeout")
+	}
+
+	return uint16(reg.Read(hw.r0) & 0xfff), nil
+}
@@ -63,3 +63,88 @@ func (hw *CSU) SetSecurityLevel(periph int, slave int, csl uint8, lock bool) (er
 
 	return
 }
+
+// Lock locks the config security level (CSL) registers for a peripheral slave
+// for changes until the next power cycle.
+func (hw *CSU) Lock(periph int, slave int) (err error) {
+	if err = checkArgs(periph, slave); err != nil {
+		return
+	}
+
+	reg.Set(hw.csl0+uint32(4*periph), CSL_S1_LOCK+CSL_S2*slave)
+
+	return
+}
+
+// AccessLevel represents a decoded config security level (CSL).
+type AccessLevel struct {
+	// Raw CSL value
+	CSL uint8
+	// Security level (SEC_LEVEL_0-7), -1 for non-standard CSL values
+	Level int
+
+	NonSecureSupervisorRead  bool
+	NonSecureSupervisorWrite bool
+	NonSecureUserRead        bool
+	NonSecureUserWrite       bool
+	SecureSupervisorRead     bool
+	SecureSupervisorWrite    bool
+	SecureUserRead           bool
+	SecureUserWrite          bool
+}
+
+// NewAccessLevel decodes a config security level (CSL) value.
+func NewAccessLevel(csl uint8) (a AccessLevel) {
+	isSet := func(pos int) bool {
+		return (csl>>pos)&1 == 1
+	}
+
+	a = AccessLevel{
+		CSL:                      csl,
+		Level:                    -1,
+		NonSecureSupervisorRead:  isSet(CSL_NW_SUP_RD),
+		NonSecureSupervisorWrite: isSet(CSL_NW_SUP_WR),
+		NonSecureUserRead:        isSet(CSL_NW_USR_RD),
+		NonSecureUserWrite:       isSet(CSL_NW_USR_WR),
+		SecureSupervisorRead:     isSet(CSL_SW_SUP_RD),
+		SecureSupervisorWrite:    isSet(CSL_SW_SUP_WR),
+		SecureUserRead:           isSet(CSL_SW_USR_RD),
+		SecureUserWrite:          isSet(CSL_SW_USR_WR),
+	}
+
+	for level, val := range []uint8{
+		SEC_LEVEL_0,
+		SEC_LEVEL_1,
+		SEC_LEVEL_2,
+		SEC_LEVEL_3,
+		SEC_LEVEL_4,
+		SEC_LEVEL_5,
+		SEC_LEVEL_6,
+		SEC_LEVEL_7,
+	} {
+		if csl == val {
+			a.Level = level
+			break
+		}
+	}
+
+	return
+}
+
+// NonSecure returns whether the NonSecure world has any access.
+func (a AccessLevel) NonSecure() bool {
+	return a.NonSecureSupervisorRead || a.NonSecureSupervisorWrite ||
+		a.NonSecureUserRead || a.NonSecureUserWrite
+}
+
+// Access returns the decoded config security level (CSL) for a peripheral
+// slave, see GetSecurityLevel() for the lock return value.
+func (hw *CSU) Access(periph int, slave int) (level AccessLevel, lock bool, err error) {
+	csl, lock, err := hw.GetSecurityLevel(periph, slave)
+
+	if err != nil {
+		return
+	}
+
+	return NewAccessLevel(csl), lock, nil
+}
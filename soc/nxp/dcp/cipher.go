@@ -29,25 +29,41 @@ func (pkt *WorkPacket) SetCipherDefaults() {
 	pkt.Control1 |= CIPHER_MODE_CBC << DCP_CTRL1_CIPHER_MODE
 }
 
-func (hw *DCP) cipher(buf []byte, index int, iv []byte, enc bool) (err error) {
+func (hw *DCP) cipher(buf []byte, index int, key []byte, iv []byte, enc bool) (err error) {
 	if len(buf)%aes.BlockSize != 0 {
 		return errors.New("invalid input size")
 	}
 
-	if index < 0 || index > 3 {
-		return errors.New("key index must be between 0 and 3")
+	if key != nil {
+		if len(key) != aes.BlockSize {
+			return errors.New("invalid key size")
+		}
+	} else if index != KEY_SELECT_UNIQUE_KEY && (index < 0 || index > 3) {
+		return errors.New("key index must be between 0 and 3 or KEY_SELECT_UNIQUE_KEY")
 	}
 
 	if len(iv) != aes.BlockSize {
 		return errors.New("invalid IV size")
 	}
 
+	payload := iv
+
+	if key != nil {
+		// the payload key precedes the IV
+		payload = append(append([]byte{}, key...), iv...)
+	}
+
 	sourceBufferAddress := dma.Alloc(buf, aes.BlockSize)
 	defer dma.Free(sourceBufferAddress)
 
-	payloadPointer := dma.Alloc(iv, 4)
+	payloadPointer := dma.Alloc(payload, 4)
 	defer dma.Free(payloadPointer)
 
+	if key != nil {
+		// scrub the payload key before releasing its buffers
+		defer zeroize(dma.Default(), payloadPointer, payload)
+	}
+
 	pkt := &WorkPacket{}
 	pkt.SetCipherDefaults()
 
@@ -55,8 +71,19 @@ func (hw *DCP) cipher(buf []byte, index int, iv []byte, enc bool) (err error) {
 		pkt.Control0 |= 1 << DCP_CTRL0_CIPHER_ENCRYPT
 	}
 
-	// use key RAM slot
-	pkt.Control1 |= (uint32(index) & 0xff) << DCP_CTRL1_KEY_SELECT
+	switch {
+	case key != nil:
+		// use payload key
+		pkt.Control0 |= 1 << DCP_CTRL0_PAYLOAD_KEY
+	case index == KEY_SELECT_UNIQUE_KEY:
+		// use device-specific hardware key
+		pkt.Control0 |= 1 << DCP_CTRL0_OTP_KEY
+		pkt.Control1 |= KEY_SELECT_UNIQUE_KEY << DCP_CTRL1_KEY_SELECT
+	default:
+		// use key RAM slot
+		pkt.Control1 |= (uint32(index) & 0xff) << DCP_CTRL1_KEY_SELECT
+	}
+
 	pkt.SourceBufferAddress = uint32(sourceBufferAddress)
 	pkt.DestinationBufferAddress = pkt.SourceBufferAddress
 	pkt.BufferSize = uint32(len(buf))
@@ -77,13 +104,69 @@ func (hw *DCP) cipher(buf []byte, index int, iv []byte, enc bool) (err error) {
 // Encrypt performs in-place buffer encryption using AES-128-CBC, the key can
 // be selected with the index argument from one previously set with SetKey().
 func (hw *DCP) Encrypt(buf []byte, index int, iv []byte) (err error) {
-	return hw.cipher(buf, index, iv, true)
+	// the unique key is only available through EncryptUNIQUE()
+	if index < 0 || index > 3 {
+		return errors.New("key index must be between 0 and 3")
+	}
+
+	return hw.cipher(buf, index, nil, iv, true)
 }
 
 // Decrypt performs in-place buffer decryption using AES-128-CBC, the key can
 // be selected with the index argument from one previously set with SetKey().
 func (hw *DCP) Decrypt(buf []byte, index int, iv []byte) (err error) {
-	return hw.cipher(buf, index, iv, false)
+	// the unique key is only available through DecryptUNIQUE()
+	if index < 0 || index > 3 {
+		return errors.New("key index must be between 0 and 3")
+	}
+
+	return hw.cipher(buf, index, nil, iv, false)
+}
+
+// EncryptUNIQUE performs in-place buffer encryption using AES-128-CBC with
+// the internal OTPMK (when SNVS is enabled), binding the ciphertext to the
+// SoC without exposing the key to software.
+//
+// *WARNING*: when SNVS is not enabled a default non-unique test vector is used
+// and therefore encryption is *unsafe*, see snvs.Available().
+func (hw *DCP) EncryptUNIQUE(buf []byte, iv []byte) (err error) {
+	return hw.cipher(buf, KEY_SELECT_UNIQUE_KEY, nil, iv, true)
+}
+
+// DecryptUNIQUE performs in-place buffer decryption using AES-128-CBC with
+// the internal OTPMK (when SNVS is enabled), see EncryptUNIQUE().
+func (hw *DCP) DecryptUNIQUE(buf []byte, iv []byte) (err error) {
+	return hw.cipher(buf, KEY_SELECT_UNIQUE_KEY, nil, iv, false)
+}
+
+// EncryptCBC performs in-place buffer encryption using AES-128-CBC with the
+// argument key, which is passed to the DCP through the work packet payload
+// rather than the key RAM.
+//
+// The payload key copy, held in DMA memory during the operation, is zeroized
+// before the function returns.
+func (hw *DCP) EncryptCBC(key []byte, iv []byte, buf []byte) (err error) {
+	// a nil key would select key RAM slot 0
+	if len(key) != aes.BlockSize {
+		return errors.New("invalid key size")
+	}
+
+	return hw.cipher(buf, 0, key, iv, true)
+}
+
+// DecryptCBC performs in-place buffer decryption using AES-128-CBC with the
+// argument key, which is passed to the DCP through the work packet payload
+// rather than the key RAM.
+//
+// The payload key copy, held in DMA memory during the operation, is zeroized
+// before the function returns.
+func (hw *DCP) DecryptCBC(key []byte, iv []byte, buf []byte) (err error) {
+	// a nil key would select key RAM slot 0
+	if len(key) != aes.BlockSize {
+		return errors.New("invalid key size")
+	}
+
+	return hw.cipher(buf, 0, key, iv, false)
 }
 
 // CipherChain performs chained in-place buffer encryption/decryption using
@@ -153,3 +236,107 @@ func (hw *DCP) CipherChain(buf []byte, ivs []byte, count int, size int, index in
 
 	return
 }
+
+// BlockMode is the common interface to DCP hardware backed block cipher
+// modes, to process data in successive chunks.
+//
+// While similar to Go native cipher.BlockMode, this interface is not fully
+// compatible with it as hardware errors must be checked.
+type BlockMode interface {
+	// BlockSize returns the mode's block size.
+	BlockSize() int
+
+	// CryptBlocks encrypts or decrypts a number of blocks. The length of
+	// src must be a multiple of the block size. Dst and src must overlap
+	// entirely or not at all.
+	//
+	// The chaining state is maintained across invocations, therefore
+	// consecutive calls are equivalent to a single one on concatenated
+	// input.
+	CryptBlocks(dst, src []byte) error
+}
+
+type cbc struct {
+	dcp   *DCP
+	index int
+	iv    []byte
+	enc   bool
+}
+
+func (hw *DCP) newCBC(index int, iv []byte, enc bool) (BlockMode, error) {
+	if index != KEY_SELECT_UNIQUE_KEY && (index < 0 || index > 3) {
+		return nil, errors.New("key index must be between 0 and 3 or KEY_SELECT_UNIQUE_KEY")
+	}
+
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid IV size")
+	}
+
+	return &cbc{
+		dcp:   hw,
+		index: index,
+		iv:    append([]byte{}, iv...),
+		enc:   enc,
+	}, nil
+}
+
+// NewCBCEncrypter returns a BlockMode which encrypts using AES-128-CBC, the
+// key can be selected with the index argument from one previously set with
+// SetKey() or KEY_SELECT_UNIQUE_KEY (see EncryptUNIQUE()).
+//
+// Large inputs can be encrypted with bounded memory by invoking CryptBlocks()
+// on successive chunks, each chunk is processed with a single DCP work packet
+// and returned on completion.
+func (hw *DCP) NewCBCEncrypter(index int, iv []byte) (BlockMode, error) {
+	return hw.newCBC(index, iv, true)
+}
+
+// NewCBCDecrypter returns a BlockMode which decrypts using AES-128-CBC, see
+// NewCBCEncrypter().
+func (hw *DCP) NewCBCDecrypter(index int, iv []byte) (BlockMode, error) {
+	return hw.newCBC(index, iv, false)
+}
+
+// BlockSize returns the AES block size.
+func (c *cbc) BlockSize() int {
+	return aes.BlockSize
+}
+
+// CryptBlocks encrypts or decrypts a number of blocks, updating the CBC
+// chaining state.
+func (c *cbc) CryptBlocks(dst, src []byte) (err error) {
+	n := len(src)
+
+	if n%aes.BlockSize != 0 {
+		return errors.New("invalid input size")
+	}
+
+	if len(dst) < n {
+		return errors.New("output smaller than input")
+	}
+
+	if n == 0 {
+		return
+	}
+
+	var next []byte
+
+	if !c.enc {
+		// the last ciphertext block is the next IV
+		next = append([]byte{}, src[n-aes.BlockSize:]...)
+	}
+
+	copy(dst, src)
+
+	if err = c.dcp.cipher(dst[:n], c.index, nil, c.iv, c.enc); err != nil {
+		return
+	}
+
+	if c.enc {
+		next = dst[n-aes.BlockSize : n]
+	}
+
+	copy(c.iv, next)
+
+	return
+}
@@ -0,0 +1,87 @@
+// NXP Data Co-Processor (DCP) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package dcp
+
+import (
+	"context"
+	"encoding/binary"
+	"math/bits"
+)
+
+// CRC32ChunkSize represents the maximum amount of data processed by a single
+// DCP work packet for CRC32 computation, larger buffers are processed with
+// multiple work packets.
+const CRC32ChunkSize = 64 * 1024
+
+// CRC32 returns the CRC-32 checksum of the data using the IEEE polynomial, as
+// returned by hash/crc32.ChecksumIEEE().
+//
+// The DCP CRC32 engine uses the fixed, non-reflected, IEEE polynomial
+// (0x04c11db7) with 0xffffffff seed, neither can be configured. The input
+// and output reflection and final inversion of the IEEE variant are
+// therefore applied by the driver.
+//
+// A single DCP channel is used for all hash operations, the function blocks
+// while a digest instance is in use (see New256()).
+func (hw *DCP) CRC32(data []byte) (crc uint32, err error) {
+	if len(data) == 0 {
+		return
+	}
+
+	if err = sem.Acquire(context.Background(), 1); err != nil {
+		return
+	}
+	defer sem.Release(1)
+
+	return crc32Reflected(data, func(buf []byte, init bool, term bool) ([]byte, error) {
+		return hw.hash(buf, HASH_SELECT_CRC32, 4, init, term)
+	})
+}
+
+// crc32Reflected computes a reflected CRC-32 checksum (e.g. IEEE) through a
+// non-reflected engine, with 0xffffffff seed and no final inversion, invoked
+// on successive input chunks and returning its register value, in big endian
+// order, on the last one.
+func crc32Reflected(data []byte, engine func(buf []byte, init bool, term bool) ([]byte, error)) (crc uint32, err error) {
+	var sum []byte
+
+	if len(data) == 0 {
+		return
+	}
+
+	buf := make([]byte, 0, CRC32ChunkSize)
+
+	for off := 0; off < len(data); off += len(buf) {
+		n := len(data) - off
+
+		if n > CRC32ChunkSize {
+			n = CRC32ChunkSize
+		}
+
+		buf = buf[:n]
+
+		// reflect input bytes
+		for i, b := range data[off : off+n] {
+			buf[i] = bits.Reverse8(b)
+		}
+
+		init := off == 0
+		term := off+n == len(data)
+
+		if sum, err = engine(buf, init, term); err != nil {
+			return
+		}
+	}
+
+	// reflect and invert output
+	crc = ^bits.Reverse32(binary.BigEndian.Uint32(sum))
+
+	return
+}
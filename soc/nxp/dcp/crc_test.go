@@ -0,0 +1,79 @@
+// NXP Data Co-Processor (DCP) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package dcp
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/bits"
+	"testing"
+)
+
+// crcEngine models the DCP CRC32 engine, a non-reflected CRC-32 with
+// 0xffffffff seed and no final inversion, for the argument polynomial.
+func crcEngine(poly uint32) func(buf []byte, init bool, term bool) ([]byte, error) {
+	var crc uint32
+
+	return func(buf []byte, init bool, term bool) (sum []byte, err error) {
+		if init {
+			crc = 0xffffffff
+		}
+
+		for _, b := range buf {
+			crc ^= uint32(b) << 24
+
+			for i := 0; i < 8; i++ {
+				if crc&0x80000000 != 0 {
+					crc = crc<<1 ^ poly
+				} else {
+					crc <<= 1
+				}
+			}
+		}
+
+		if term {
+			sum = binary.BigEndian.AppendUint32(nil, crc)
+		}
+
+		return
+	}
+}
+
+func TestCRC32Reflected(t *testing.T) {
+	polys := map[string]uint32{
+		"IEEE":       crc32.IEEE,
+		"Castagnoli": crc32.Castagnoli,
+		"Koopman":    crc32.Koopman,
+	}
+
+	lengths := []int{0, 1, 3, 64, 1000, CRC32ChunkSize, CRC32ChunkSize + 1, 2*CRC32ChunkSize + 5}
+
+	for name, poly := range polys {
+		table := crc32.MakeTable(poly)
+
+		for _, n := range lengths {
+			data := make([]byte, n)
+
+			for i := range data {
+				data[i] = byte(i*7 + i>>8)
+			}
+
+			crc, err := crc32Reflected(data, crcEngine(bits.Reverse32(poly)))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if exp := crc32.Checksum(data, table); crc != exp {
+				t.Errorf("%s, %d bytes: expected %#08x, got %#08x", name, n, exp, crc)
+			}
+		}
+	}
+}
@@ -30,9 +30,12 @@ import (
 
 // DCP registers
 const (
-	DCP_CTRL     = 0x00
-	CTRL_SFTRST  = 31
-	CTRL_CLKGATE = 30
+	DCP_CTRL                      = 0x00
+	CTRL_SFTRST                   = 31
+	CTRL_CLKGATE                  = 30
+	CTRL_GATHER_RESIDUAL_WRITES   = 23
+	CTRL_ENABLE_CONTEXT_CACHING   = 22
+	CTRL_ENABLE_CONTEXT_SWITCHING = 21
 
 	DCP_STAT     = 0x10
 	DCP_STAT_CLR = 0x18
@@ -40,6 +43,8 @@ const (
 
 	DCP_CHANNELCTRL = 0x0020
 
+	DCP_CONTEXT = 0x0050
+
 	DCP_KEY     = 0x0060
 	KEY_INDEX   = 4
 	KEY_SUBWORD = 0
@@ -53,6 +58,9 @@ const (
 	CHxSTAT_ERROR_MASK = 0b1111110
 
 	DCP_CH0STAT_CLR = 0x0128
+
+	// channel registers stride
+	DCP_CH_OFFSET = 0x40
 )
 
 // DCP channels
@@ -61,6 +69,25 @@ const (
 	DCP_CHANNEL_1
 	DCP_CHANNEL_2
 	DCP_CHANNEL_3
+
+	DCP_CHANNELS = 4
+)
+
+// DCP channel bit masks (CHANNELCTRL, STAT and STAT_CLR)
+const (
+	DCP_CHANNEL_0_MASK = 1 << iota
+	DCP_CHANNEL_1_MASK
+	DCP_CHANNEL_2_MASK
+	DCP_CHANNEL_3_MASK
+)
+
+// DCP channel assignment
+const (
+	// Hash operations keep their state across work packets and are
+	// therefore always dispatched on the same channel.
+	hashChannel = 0
+	// Context switching buffer size (52 bytes per channel)
+	contextSize = DCP_CHANNELS * 52
 )
 
 // DCP control packet settings
@@ -69,6 +96,7 @@ const (
 
 	DCP_CTRL0_HASH_TERM       = 13
 	DCP_CTRL0_HASH_INIT       = 12
+	DCP_CTRL0_PAYLOAD_KEY     = 11
 	DCP_CTRL0_OTP_KEY         = 10
 	DCP_CTRL0_CIPHER_INIT     = 9
 	DCP_CTRL0_CIPHER_ENCRYPT  = 8
@@ -138,12 +166,18 @@ type DCP struct {
 	stat        uint32
 	stat_clr    uint32
 	chctrl      uint32
+	context     uint32
 	key         uint32
 	keydata     uint32
 	ch0cmdptr   uint32
 	ch0sema     uint32
 	ch0stat     uint32
 	ch0stat_clr uint32
+
+	// free channels for cipher operations
+	channels chan int
+	// context switching buffer
+	ctx uint
 }
 
 // Bytes converts the DCP work packet structure to byte array format.
@@ -154,6 +188,10 @@ func (pkt *WorkPacket) Bytes() []byte {
 }
 
 // Init initializes the DCP module.
+//
+// Channel 0 is reserved for hash operations, cipher and key derivation
+// operations are dispatched on the first available channel among the
+// remaining ones, allowing concurrent use from different goroutines.
 func (hw *DCP) Init() {
 	hw.Lock()
 	defer hw.Unlock()
@@ -166,6 +204,7 @@ func (hw *DCP) Init() {
 	hw.stat = hw.Base + DCP_STAT
 	hw.stat_clr = hw.Base + DCP_STAT_CLR
 	hw.chctrl = hw.Base + DCP_CHANNELCTRL
+	hw.context = hw.Base + DCP_CONTEXT
 	hw.key = hw.Base + DCP_KEY
 	hw.keydata = hw.Base + DCP_KEYDATA
 	hw.ch0cmdptr = hw.Base + DCP_CH0CMDPTR
@@ -183,37 +222,74 @@ func (hw *DCP) Init() {
 	// enable DCP
 	reg.Clear(hw.ctrl, CTRL_CLKGATE)
 
-	// enable channel 0
-	reg.Write(hw.chctrl, DCP_CHANNEL_0)
+	// Context switching preserves each channel hash and cipher state
+	// when the DCP arbitrates between active channels
+	// (see HW_DCP_CONTEXT, MCIMX28RM).
+	if hw.ctx == 0 {
+		hw.ctx = dma.Alloc(make([]byte, contextSize), 4)
+	}
+
+	reg.Write(hw.context, uint32(hw.ctx))
+	reg.Set(hw.ctrl, CTRL_ENABLE_CONTEXT_SWITCHING)
+
+	hw.channels = make(chan int, DCP_CHANNELS-1)
+
+	for ch := 0; ch < DCP_CHANNELS; ch++ {
+		if ch != hashChannel {
+			hw.channels <- ch
+		}
+	}
+
+	// enable all channels
+	reg.Write(hw.chctrl, DCP_CHANNEL_0_MASK|DCP_CHANNEL_1_MASK|DCP_CHANNEL_2_MASK|DCP_CHANNEL_3_MASK)
 }
 
+// cmd executes a work packet chain on the first available channel not
+// reserved to hash operations.
 func (hw *DCP) cmd(ptr uint, count int) (err error) {
 	hw.Lock()
-	defer hw.Unlock()
+	channels := hw.channels
+	hw.Unlock()
+
+	if channels == nil {
+		return errors.New("co-processor is not initialized")
+	}
+
+	ch := <-channels
+	defer func() { channels <- ch }()
+
+	return hw.exec(ch, ptr, count)
+}
+
+// exec executes a work packet chain on the argument channel, which must not be
+// in use by other goroutines (see cmd() and, for the hash channel, sem).
+func (hw *DCP) exec(ch int, ptr uint, count int) (err error) {
+	off := uint32(ch * DCP_CH_OFFSET)
+	mask := 1 << ch
 
-	if reg.Read(hw.chctrl) != DCP_CHANNEL_0 {
+	if reg.Get(hw.chctrl, 0, mask) == 0 {
 		return errors.New("co-processor is not initialized")
 	}
 
 	// clear channel status
-	reg.Write(hw.ch0stat_clr, 0xffffffff)
+	reg.Write(hw.ch0stat_clr+off, 0xffffffff)
 
 	// set command address
-	reg.Write(hw.ch0cmdptr, uint32(ptr))
+	reg.Write(hw.ch0cmdptr+off, uint32(ptr))
 	// activate channel
-	reg.SetN(hw.ch0sema, 0, 0xff, uint32(count))
+	reg.SetN(hw.ch0sema+off, 0, 0xff, uint32(count))
 	// wait for completion
-	reg.Wait(hw.stat, DCP_STAT_IRQ, DCP_CHANNEL_0, 1)
-	// clear interrupt register
-	reg.Set(hw.stat_clr, DCP_CHANNEL_0)
+	reg.Wait(hw.stat, DCP_STAT_IRQ+ch, 1, 1)
+	// clear channel interrupt, leaving other channels untouched
+	reg.Write(hw.stat_clr, uint32(mask))
 
-	chstatus := reg.Read(hw.ch0stat)
+	chstatus := reg.Read(hw.ch0stat + off)
 
 	// check for errors
 	if bits.Get(&chstatus, 0, CHxSTAT_ERROR_MASK) != 0 {
 		code := bits.Get(&chstatus, CHxSTAT_ERROR_CODE, 0xff)
-		sema := reg.Read(hw.ch0sema)
-		return fmt.Errorf("DCP channel 0 error, status:%#x error_code:%#x sema:%#x", chstatus, code, sema)
+		sema := reg.Read(hw.ch0sema + off)
+		return fmt.Errorf("DCP channel %d error, status:%#x error_code:%#x sema:%#x", ch, chstatus, code, sema)
 	}
 
 	return
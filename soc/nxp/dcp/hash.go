@@ -10,6 +10,7 @@
 package dcp
 
 import (
+	"github.com/usbarmory/tamago/bits"
 	"github.com/usbarmory/tamago/dma"
 )
 
@@ -23,6 +24,8 @@ func (pkt *WorkPacket) SetHashDefaults() {
 	pkt.Control1 |= HASH_SELECT_SHA256 << DCP_CTRL1_HASH_SELECT
 }
 
+// hash executes a hash work packet on the hash channel, the caller must hold
+// sem to serialize its use.
 func (hw *DCP) hash(buf []byte, mode int, size int, init bool, term bool) (sum []byte, err error) {
 	sourceBufferAddress := dma.Alloc(buf, 4)
 	defer dma.Free(sourceBufferAddress)
@@ -54,12 +57,12 @@ func (hw *DCP) hash(buf []byte, mode int, size int, init bool, term bool) (sum [
 		}()
 	}
 
-	pkt.Control1 |= uint32(mode) << DCP_CTRL1_HASH_SELECT
+	bits.SetN(&pkt.Control1, DCP_CTRL1_HASH_SELECT, 0xff, uint32(mode))
 
 	ptr := dma.Alloc(pkt.Bytes(), 4)
 	defer dma.Free(ptr)
 
-	err = hw.cmd(ptr, 1)
+	err = hw.exec(hashChannel, ptr, 1)
 
 	return
 }
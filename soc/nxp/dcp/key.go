@@ -16,6 +16,7 @@ import (
 	"errors"
 
 	"github.com/usbarmory/tamago/bits"
+	"github.com/usbarmory/tamago/dma"
 	"github.com/usbarmory/tamago/internal/reg"
 )
 
@@ -34,6 +35,9 @@ import (
 // An index argument equal or greater than 0 moves the derived key, through
 // DeriveKeyMemory, to the corresponding internal DCP key RAM slot (see
 // SetKey()). In this case no key is returned by the function.
+//
+// In both cases the derived key copy, held in DeriveKeyMemory during the
+// operation, is zeroized before the function returns.
 func (hw *DCP) DeriveKey(diversifier []byte, iv []byte, index int) (key []byte, err error) {
 	if len(iv) != aes.BlockSize {
 		return nil, errors.New("invalid IV size")
@@ -53,6 +57,9 @@ func (hw *DCP) DeriveKey(diversifier []byte, iv []byte, index int) (key []byte,
 	sourceBufferAddress := region.Alloc(key, aes.BlockSize)
 	defer region.Free(sourceBufferAddress)
 
+	// scrub the derived key before releasing its buffer
+	defer zeroize(region, sourceBufferAddress, make([]byte, len(key)))
+
 	payloadPointer := region.Alloc(iv, 0)
 	defer region.Free(payloadPointer)
 
@@ -122,10 +129,28 @@ func (hw *DCP) setKeyData(index int, key []byte, addr uint32) (err error) {
 
 // SetKey configures an AES-128 key in one of the 4 available slots of the DCP
 // key RAM.
+//
+// The key is written directly to the key RAM through the DCP registers, no
+// copy is held in DMA memory.
 func (hw *DCP) SetKey(index int, key []byte) (err error) {
 	return hw.setKeyData(index, key, 0)
 }
 
+// ZeroizeKey overwrites with zeros one of the 4 available slots of the DCP
+// key RAM.
+func (hw *DCP) ZeroizeKey(index int) (err error) {
+	return hw.setKeyData(index, make([]byte, aes.BlockSize), 0)
+}
+
+// zeroize overwrites with zeros a buffer as well as its DMA copy.
+func zeroize(region *dma.Region, addr uint, buf []byte) {
+	for i := range buf {
+		buf[i] = 0
+	}
+
+	region.Write(addr, 0, buf)
+}
+
 func pad(buf []byte, extraBlock bool) []byte {
 	padLen := 0
 	r := len(buf) % aes.BlockSize
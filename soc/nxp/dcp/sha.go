@@ -10,6 +10,8 @@
 package dcp
 
 import (
+	"context"
+	"crypto/sha1"
 	"crypto/sha256"
 	"errors"
 	"io"
@@ -17,8 +19,8 @@ import (
 	"golang.org/x/sync/semaphore"
 )
 
-// A single DCP channel is used for all operations, this entails that only one
-// digest state can be kept at any given time.
+// A single DCP channel is used for all hash operations, this entails that only
+// one digest state can be kept at any given time.
 var sem = semaphore.NewWeighted(1)
 
 // Hash is the common interface to DCP hardware backed hash functions.
@@ -51,6 +53,9 @@ type digest struct {
 	init bool
 	buf  []byte
 	sum  []byte
+
+	// empty input checksum
+	empty []byte
 }
 
 // Write adds more data to the running hash. It returns an error if Sum has
@@ -113,9 +118,9 @@ func (d *digest) Sum(in []byte) (sum []byte, err error) {
 	defer sem.Release(1)
 
 	if d.init && len(d.buf) == 0 {
-		d.sum = sha256.New().Sum(nil)
+		d.sum = d.empty
 	} else {
-		s, err := d.dcp.hash(d.buf, HASH_SELECT_SHA256, d.n, d.init, true)
+		s, err := d.dcp.hash(d.buf, d.mode, d.n, d.init, true)
 
 		if err != nil {
 			return nil, err
@@ -134,9 +139,9 @@ func (d *digest) BlockSize() int {
 
 // New256 returns a new Digest computing the SHA256 checksum.
 //
-// A single DCP channel is used for all operations, this entails that only one
-// digest instance can be kept at any given time, if this condition is not met
-// an error is returned.
+// A single DCP channel is used for all hash operations, this entails that only
+// one digest instance can be kept at any given time, if this condition is not
+// met an error is returned.
 //
 // The digest instance starts with New256() and terminates when when Sum() is
 // invoked, after which the digest state can no longer be changed.
@@ -154,14 +159,51 @@ func (hw *DCP) New256() (Hash, error) {
 		buf:  make([]byte, 0, sha256.BlockSize),
 	}
 
+	d.empty = sha256.New().Sum(nil)
+
+	return d, nil
+}
+
+// NewSHA1 returns a new Digest computing the SHA1 checksum.
+//
+// A single DCP channel is used for all hash operations, this entails that only
+// one digest instance can be kept at any given time, if this condition is not
+// met an error is returned.
+//
+// The digest instance starts with NewSHA1() and terminates when when Sum() is
+// invoked, after which the digest state can no longer be changed.
+func (hw *DCP) NewSHA1() (Hash, error) {
+	if !sem.TryAcquire(1) {
+		return nil, errors.New("another digest instance is already in use")
+	}
+
+	d := &digest{
+		dcp:  hw,
+		mode: HASH_SELECT_SHA1,
+		n:    sha1.Size,
+		bs:   sha1.BlockSize,
+		init: true,
+		buf:  make([]byte, 0, sha1.BlockSize),
+	}
+
+	d.empty = sha1.New().Sum(nil)
+
 	return d, nil
 }
 
 // Sum256 returns the SHA256 checksum of the data.
 //
+// A single DCP channel is used for all hash operations, the function blocks
+// while a digest instance is in use (see New256()).
+//
 // There must be sufficient DMA memory allocated to hold the data, otherwise
 // the function will panic.
 func (hw *DCP) Sum256(data []byte) (sum [32]byte, err error) {
+	if err = sem.Acquire(context.Background(), 1); err != nil {
+		return
+	}
+	defer sem.Release(1)
+
 	s, err := hw.hash(data, HASH_SELECT_SHA256, len(sum), true, true)
 
 	if err != nil {
@@ -172,3 +214,27 @@ func (hw *DCP) Sum256(data []byte) (sum [32]byte, err error) {
 
 	return
 }
+
+// SumSHA1 returns the SHA1 checksum of the data.
+//
+// A single DCP channel is used for all hash operations, the function blocks
+// while a digest instance is in use (see New256()).
+//
+// There must be sufficient DMA memory allocated to hold the data, otherwise
+// the function will panic.
+func (hw *DCP) SumSHA1(data []byte) (sum [20]byte, err error) {
+	if err = sem.Acquire(context.Background(), 1); err != nil {
+		return
+	}
+	defer sem.Release(1)
+
+	s, err := hw.hash(data, HASH_SELECT_SHA1, len(sum), true, true)
+
+	if err != nil {
+		return
+	}
+
+	copy(sum[:], s)
+
+	return
+}
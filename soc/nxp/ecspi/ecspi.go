@@ -0,0 +1,342 @@
+// NXP Enhanced Configurable SPI (ECSPI) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+// Package ecspi implements a driver for NXP Enhanced Configurable SPI (ECSPI)
+// controllers adopting the following reference specifications:
+//   - IMX6ULLRM - i.MX 6ULL Applications Processor Reference Manual - Rev 1 2017/11
+//
+// This package is only meant to be used with `GOOS=tamago GOARCH=arm` as
+// supported by the TamaGo framework for bare metal Go on ARM SoCs, see
+// https://github.com/usbarmory/tamago.
+package ecspi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/usbarmory/tamago/bits"
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// ECSPI registers
+// (ECSPI Memory Map/Register Definition, IMX6ULLRM).
+const (
+	ECSPIx_RXDATA = 0x00
+	ECSPIx_TXDATA = 0x04
+
+	ECSPIx_CONREG       = 0x08
+	CONREG_BURST_LENGTH = 20
+	CONREG_CHANNEL_SEL  = 18
+	CONREG_PRE_DIVIDER  = 12
+	CONREG_POST_DIVIDER = 8
+	CONREG_CHANNEL_MODE = 4
+	CONREG_SMC          = 3
+	CONREG_XCH          = 2
+	CONREG_EN           = 0
+
+	ECSPIx_CONFIGREG   = 0x0c
+	CONFIGREG_SCLK_CTL = 20
+	CONFIGREG_DATA_CTL = 16
+	CONFIGREG_SS_POL   = 12
+	CONFIGREG_SS_CTL   = 8
+	CONFIGREG_SCLK_POL = 4
+	CONFIGREG_SCLK_PHA = 0
+
+	ECSPIx_INTREG = 0x10
+	ECSPIx_DMAREG = 0x14
+
+	ECSPIx_STATREG = 0x18
+	STATREG_TC     = 7
+	STATREG_RO     = 6
+	STATREG_RR     = 3
+	STATREG_TE     = 0
+
+	ECSPIx_PERIODREG = 0x1c
+)
+
+// Configuration constants
+const (
+	// Timeout is the default timeout for ECSPI transfers.
+	Timeout = 100 * time.Millisecond
+
+	// FIFO depth in 32-bit words
+	FIFO_DEPTH = 64
+	// maximum number of bytes transferred in a single burst
+	MAX_BURST = FIFO_DEPTH * 4
+	// number of chip select signals
+	CHANNELS = 4
+)
+
+// SPI modes (clock polarity and phase)
+const (
+	// CPOL: 0, CPHA: 0
+	MODE_0 = 0b00
+	// CPOL: 0, CPHA: 1
+	MODE_1 = 0b01
+	// CPOL: 1, CPHA: 0
+	MODE_2 = 0b10
+	// CPOL: 1, CPHA: 1
+	MODE_3 = 0b11
+)
+
+// ECSPI represents an ECSPI controller instance.
+type ECSPI struct {
+	sync.Mutex
+
+	// Controller index
+	Index int
+	// Base register
+	Base uint32
+	// Clock gate register
+	CCGR uint32
+	// Clock gate
+	CG int
+	// Clock retrieval function (ECSPI_CLK_ROOT)
+	Clock func() uint32
+	// Interrupt ID
+	IRQ int
+	// Timeout for each transfer burst (default: Timeout)
+	Timeout time.Duration
+
+	// control registers
+	rxdata    uint32
+	txdata    uint32
+	conreg    uint32
+	configreg uint32
+	statreg   uint32
+
+	// per channel clock dividers
+	div [CHANNELS]uint32
+}
+
+// Init initializes the ECSPI controller instance in master mode, all chip
+// selects are configured in SPI mode 0 at 1 MHz until configured with Setup().
+func (hw *ECSPI) Init() {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.Base == 0 || hw.CCGR == 0 || hw.Clock == nil {
+		panic("invalid ECSPI controller instance")
+	}
+
+	if hw.Timeout == 0 {
+		hw.Timeout = Timeout
+	}
+
+	hw.rxdata = hw.Base + ECSPIx_RXDATA
+	hw.txdata = hw.Base + ECSPIx_TXDATA
+	hw.conreg = hw.Base + ECSPIx_CONREG
+	hw.configreg = hw.Base + ECSPIx_CONFIGREG
+	hw.statreg = hw.Base + ECSPIx_STATREG
+
+	// enable clock
+	reg.SetN(hw.CCGR, hw.CG, 0b11, 0b11)
+
+	// reset
+	reg.Write(hw.conreg, 0)
+	reg.Set(hw.conreg, CONREG_EN)
+
+	// set all channels in master mode
+	reg.SetN(hw.conreg, CONREG_CHANNEL_MODE, 0xf, 0xf)
+
+	reg.Write(hw.configreg, 0)
+	reg.Write(hw.Base+ECSPIx_INTREG, 0)
+	reg.Write(hw.Base+ECSPIx_DMAREG, 0)
+	reg.Write(hw.Base+ECSPIx_PERIODREG, 0)
+
+	for cs := 0; cs < CHANNELS; cs++ {
+		hw.div[cs] = divider(hw.Clock(), 1000000)
+	}
+}
+
+// divider returns the PRE_DIVIDER and POST_DIVIDER combination resulting in
+// the highest SCLK frequency which does not exceed the argument value.
+func divider(clk uint32, hz uint32) (div uint32) {
+	var post uint32
+
+	// SCLK = clk / ((PRE_DIVIDER + 1) * 2^POST_DIVIDER)
+	pre := (clk + hz - 1) / hz
+
+	for pre > 16 && post < 15 {
+		post += 1
+		pre = (clk>>post + hz - 1) / hz
+	}
+
+	if pre > 16 {
+		pre = 16
+	}
+
+	if pre == 0 {
+		pre = 1
+	}
+
+	bits.SetN(&div, CONREG_PRE_DIVIDER, 0xf, pre-1)
+	bits.SetN(&div, CONREG_POST_DIVIDER, 0xf, post)
+
+	return
+}
+
+// Setup configures the clock rate, as the highest frequency which does not
+// exceed the argument value, and SPI mode (see MODE_* constants) for the
+// argument chip select.
+func (hw *ECSPI) Setup(cs int, hz int, mode int) (err error) {
+	if cs < 0 || cs >= CHANNELS {
+		return errors.New("invalid chip select")
+	}
+
+	if hz <= 0 {
+		return fmt.Errorf("invalid ECSPI speed %d", hz)
+	}
+
+	if mode < MODE_0 || mode > MODE_3 {
+		return errors.New("invalid SPI mode")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	hw.div[cs] = divider(hw.Clock(), uint32(hz))
+
+	cpol := mode&0b10 != 0
+	cpha := mode&0b01 != 0
+
+	// clock polarity, phase and inactive state
+	reg.SetTo(hw.configreg, CONFIGREG_SCLK_POL+cs, cpol)
+	reg.SetTo(hw.configreg, CONFIGREG_SCLK_CTL+cs, cpol)
+	reg.SetTo(hw.configreg, CONFIGREG_SCLK_PHA+cs, cpha)
+
+	// active low chip select, asserted for the entire burst
+	reg.Clear(hw.configreg, CONFIGREG_SS_POL+cs)
+	reg.Clear(hw.configreg, CONFIGREG_SS_CTL+cs)
+
+	return
+}
+
+// Txn performs a full-duplex transfer on the argument chip select,
+// transmitting the write buffer while receiving into the read buffer.
+//
+// A nil or shorter write buffer is padded with zeroes, a nil or shorter read
+// buffer discards excess received data, the transfer length is the longest
+// of the two buffers.
+//
+// The chip select is asserted for each burst of up to 256 bytes (see
+// MAX_BURST), it is therefore de-asserted between bursts on longer
+// transfers.
+func (hw *ECSPI) Txn(cs int, w []byte, r []byte) (err error) {
+	if cs < 0 || cs >= CHANNELS {
+		return errors.New("invalid chip select")
+	}
+
+	size := len(w)
+
+	if len(r) > size {
+		size = len(r)
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.conreg == 0 {
+		return errors.New("controller is not initialized")
+	}
+
+	for off := 0; off < size; off += MAX_BURST {
+		n := size - off
+
+		if n > MAX_BURST {
+			n = MAX_BURST
+		}
+
+		if err = hw.burst(cs, w, r, off, n); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (hw *ECSPI) burst(cs int, w []byte, r []byte, off int, n int) (err error) {
+	conreg := reg.Read(hw.conreg)
+
+	bits.SetN(&conreg, CONREG_BURST_LENGTH, 0xfff, uint32(n*8-1))
+	bits.SetN(&conreg, CONREG_CHANNEL_SEL, 0b11, uint32(cs))
+	bits.SetN(&conreg, CONREG_POST_DIVIDER, 0xff, hw.div[cs]>>CONREG_POST_DIVIDER)
+	bits.Clear(&conreg, CONREG_SMC)
+
+	reg.Write(hw.conreg, conreg)
+
+	// clear transfer completed status
+	reg.Write(hw.statreg, 1<<STATREG_TC|1<<STATREG_RO)
+
+	// When the burst length is not a multiple of 32 bits, the first word
+	// is shifted out with the remainder bits (MSB first).
+	first := n % 4
+
+	if first == 0 {
+		first = 4
+	}
+
+	for i := 0; i < n; {
+		l := 4
+
+		if i == 0 {
+			l = first
+		}
+
+		var word uint32
+
+		for j := 0; j < l; j++ {
+			word <<= 8
+
+			if k := off + i + j; k < len(w) {
+				word |= uint32(w[k])
+			}
+		}
+
+		reg.Write(hw.txdata, word)
+		i += l
+	}
+
+	// start exchange
+	reg.Set(hw.conreg, CONREG_XCH)
+
+	if !reg.WaitFor(hw.Timeout, hw.statreg, STATREG_TC, 1, 1) {
+		return errors.New("transfer timeout")
+	}
+
+	for i := 0; i < n; {
+		l := 4
+
+		if i == 0 {
+			l = first
+		}
+
+		if reg.Get(hw.statreg, STATREG_RR, 1) == 0 {
+			return errors.New("receive FIFO empty")
+		}
+
+		word := reg.Read(hw.rxdata)
+
+		for j := l - 1; j >= 0; j-- {
+			if k := off + i + j; k < len(r) {
+				r[k] = byte(word)
+			}
+
+			word >>= 8
+		}
+
+		i += l
+	}
+
+	reg.Write(hw.statreg, 1<<STATREG_TC)
+
+	return
+}
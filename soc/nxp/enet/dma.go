@@ -163,7 +163,7 @@ func (ring *bufferDescriptorRing) pop() (data []byte) {
 func (ring *bufferDescriptorRing) push(data []byte) {
 	bd := ring.bds[ring.index]
 
-	if uint16(bd.desc[3]<<8)&(1<<BD_TX_ST_R) != 0 {
+	if uint16(bd.desc[3])<<8&(1<<BD_TX_ST_R) != 0 {
 		print("enet: frame not sent\n")
 	}
 
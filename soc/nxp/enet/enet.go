@@ -293,5 +293,5 @@ func (hw *ENET) EnableInterrupt(event int) {
 
 // ClearInterrupt clears the interrupt corresponding to a specific event.
 func (hw *ENET) ClearInterrupt(event int) {
-	reg.Set(hw.eir, event)
+	reg.Write(hw.eir, 1<<event)
 }
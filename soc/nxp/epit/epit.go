@@ -0,0 +1,238 @@
+// NXP Enhanced Periodic Interrupt Timer (EPIT) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+// Package epit implements a driver for the NXP Enhanced Periodic Interrupt
+// Timer (EPIT) adopting the following reference specifications:
+//   - IMX6ULLRM - i.MX 6ULL Applications Processor Reference Manual - Rev 1 2017/11
+//
+// This package is only meant to be used with `GOOS=tamago GOARCH=arm` as
+// supported by the TamaGo framework for bare metal Go on ARM SoCs, see
+// https://github.com/usbarmory/tamago.
+package epit
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// EPIT registers
+// (EPIT Memory Map/Register Definition, IMX6ULLRM).
+const (
+	EPITx_CR     = 0x00
+	CR_CLKSRC    = 24
+	CR_STOPEN    = 21
+	CR_WAITEN    = 19
+	CR_DBGEN     = 18
+	CR_IOVW      = 17
+	CR_SWR       = 16
+	CR_PRESCALAR = 4
+	CR_RLD       = 3
+	CR_OCIEN     = 2
+	CR_ENMOD     = 1
+	CR_EN        = 0
+
+	EPITx_SR = 0x04
+	SR_OCIF  = 0
+
+	EPITx_LR   = 0x08
+	EPITx_CMPR = 0x0c
+	EPITx_CNR  = 0x10
+)
+
+// EPIT clock sources
+const (
+	CLKSRC_OFF        = 0b00
+	CLKSRC_PERIPHERAL = 0b01
+	CLKSRC_HIGH_FREQ  = 0b10
+)
+
+// EPIT operating modes
+const (
+	// the counter is reloaded with the interval value after reaching
+	// zero, a single event is signaled
+	MODE_ONE_SHOT = iota
+	// the counter is reloaded with the interval value after reaching
+	// zero, an event is signaled on every reload (set-and-forget mode)
+	MODE_PERIODIC
+	// the counter rolls over to 0xffffffff after reaching zero, the
+	// interval applies only to the first event (free-running mode)
+	MODE_FREE_RUNNING
+)
+
+// EPIT represents an Enhanced Periodic Interrupt Timer instance.
+type EPIT struct {
+	sync.Mutex
+
+	// Module index
+	Index int
+	// Base register
+	Base uint32
+	// Clock gate register
+	CCGR uint32
+	// Clock gate
+	CG int
+	// Clock retrieval function (PERCLK_CLK_ROOT)
+	Clock func() uint32
+	// Interrupt ID
+	IRQ int
+
+	// control registers
+	cr   uint32
+	sr   uint32
+	lr   uint32
+	cmpr uint32
+	cnr  uint32
+
+	mode    int
+	handler func()
+}
+
+// Init initializes an Enhanced Periodic Interrupt Timer instance.
+func (hw *EPIT) Init() {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.Base == 0 || hw.CCGR == 0 || hw.Clock == nil {
+		panic("invalid EPIT instance")
+	}
+
+	hw.cr = hw.Base + EPITx_CR
+	hw.sr = hw.Base + EPITx_SR
+	hw.lr = hw.Base + EPITx_LR
+	hw.cmpr = hw.Base + EPITx_CMPR
+	hw.cnr = hw.Base + EPITx_CNR
+
+	// enable clock
+	reg.SetN(hw.CCGR, hw.CG, 0b11, 0b11)
+
+	// disable and reset
+	reg.Write(hw.cr, 0)
+	reg.Set(hw.cr, CR_SWR)
+	reg.Wait(hw.cr, CR_SWR, 1, 0)
+
+	reg.SetN(hw.cr, CR_CLKSRC, 0b11, CLKSRC_HIGH_FREQ)
+
+	// keep counting in low power modes, load counter on enable
+	reg.Set(hw.cr, CR_WAITEN)
+	reg.Set(hw.cr, CR_ENMOD)
+	// load counter on interval change
+	reg.Set(hw.cr, CR_IOVW)
+
+	// signal events when the counter reaches zero
+	reg.Write(hw.cmpr, 0)
+	reg.Write(hw.sr, 1<<SR_OCIF)
+}
+
+// SetInterval sets the timer interval, the counter is immediately reloaded
+// with the new value.
+func (hw *EPIT) SetInterval(d time.Duration) (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	ticks := uint64(hw.Clock()) * uint64(d) / uint64(time.Second)
+	div := ticks>>32 + 1
+
+	if ticks == 0 || div > 4096 {
+		return errors.New("unsupported interval")
+	}
+
+	reg.SetN(hw.cr, CR_PRESCALAR, 0xfff, uint32(div-1))
+	reg.Write(hw.lr, uint32(ticks/div-1))
+
+	return
+}
+
+// Start enables the timer in the argument operating mode (see MODE_*
+// constants).
+func (hw *EPIT) Start(mode int) (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	switch mode {
+	case MODE_ONE_SHOT, MODE_PERIODIC:
+		reg.Set(hw.cr, CR_RLD)
+	case MODE_FREE_RUNNING:
+		reg.Clear(hw.cr, CR_RLD)
+	default:
+		return errors.New("invalid mode")
+	}
+
+	hw.mode = mode
+
+	reg.Write(hw.sr, 1<<SR_OCIF)
+	reg.Set(hw.cr, CR_EN)
+
+	if mode == MODE_FREE_RUNNING {
+		// With RLD cleared the counter is enabled from 0xffffffff,
+		// rewrite the interval to load it through IOVW.
+		reg.Write(hw.lr, reg.Read(hw.lr))
+	}
+
+	return
+}
+
+// Stop disables the timer.
+func (hw *EPIT) Stop() {
+	reg.Clear(hw.cr, CR_EN)
+}
+
+// Count returns the timer counter value.
+func (hw *EPIT) Count() uint32 {
+	return reg.Read(hw.cnr)
+}
+
+// EnableInterrupt enables interrupt generation on timer events, the argument
+// function is invoked by ServiceInterrupt() for each event.
+//
+// The application is responsible for enabling the timer interrupt (see IRQ)
+// on the interrupt controller and invoking ServiceInterrupt() upon its
+// reception.
+func (hw *EPIT) EnableInterrupt(fn func()) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	hw.handler = fn
+	reg.Set(hw.cr, CR_OCIEN)
+}
+
+// DisableInterrupt disables interrupt generation on timer events.
+func (hw *EPIT) DisableInterrupt() {
+	hw.Lock()
+	defer hw.Unlock()
+
+	reg.Clear(hw.cr, CR_OCIEN)
+	hw.handler = nil
+}
+
+// ServiceInterrupt clears a pending timer event, stopping the timer when in
+// one-shot mode, and invokes the function set with EnableInterrupt().
+func (hw *EPIT) ServiceInterrupt() {
+	hw.Lock()
+
+	if reg.Get(hw.sr, SR_OCIF, 1) == 0 {
+		hw.Unlock()
+		return
+	}
+
+	reg.Write(hw.sr, 1<<SR_OCIF)
+
+	if hw.mode == MODE_ONE_SHOT {
+		reg.Clear(hw.cr, CR_EN)
+	}
+
+	fn := hw.handler
+	hw.Unlock()
+
+	if fn != nil {
+		fn()
+	}
+}
@@ -17,18 +17,52 @@ package gpio
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/usbarmory/tamago/internal/reg"
 )
 
 // GPIO registers
 const (
-	GPIO_DR   = 0x00
-	GPIO_GDIR = 0x04
+	GPIO_DR       = 0x00
+	GPIO_GDIR     = 0x04
+	GPIO_PSR      = 0x08
+	GPIO_ICR1     = 0x0c
+	GPIO_ICR2     = 0x10
+	GPIO_IMR      = 0x14
+	GPIO_ISR      = 0x18
+	GPIO_EDGE_SEL = 0x1c
+)
+
+// GPIO interrupt configuration (GPIOx_ICR1, GPIOx_ICR2)
+const (
+	ICR_LOW     = 0b00
+	ICR_HIGH    = 0b01
+	ICR_RISING  = 0b10
+	ICR_FALLING = 0b11
+)
+
+// GPIO directions
+const (
+	In = iota
+	Out
+)
+
+// Edge represents the signal transition which triggers a GPIO interrupt.
+type Edge int
+
+// GPIO interrupt edges
+const (
+	RisingEdge Edge = iota
+	FallingEdge
+	BothEdges
 )
 
 // GPIO controller instance
 type GPIO struct {
+	sync.Mutex
+
 	// Controller index
 	Index int
 	// Base register
@@ -37,15 +71,31 @@ type GPIO struct {
 	CCGR uint32
 	// Clock gate
 	CG int
+	// Interrupt ID for signals 0-15
+	IRQLow int
+	// Interrupt ID for signals 16-31
+	IRQHigh int
 
 	clk bool
 }
 
 // Pin instance
 type Pin struct {
+	// controller instance
+	hw *GPIO
+
 	num  int
+	irq  int
+	od   bool
 	data uint32
 	dir  uint32
+	icr  uint32
+	imr  uint32
+	isr  uint32
+	edge uint32
+
+	// debounced pushbutton watcher (see WatchButton())
+	button chan struct{}
 }
 
 // Init initializes a GPIO.
@@ -59,41 +109,273 @@ func (hw *GPIO) Init(num int) (gpio *Pin, err error) {
 	}
 
 	gpio = &Pin{
+		hw:   hw,
 		num:  num,
+		irq:  hw.IRQLow,
 		data: hw.Base + GPIO_DR,
 		dir:  hw.Base + GPIO_GDIR,
+		icr:  hw.Base + GPIO_ICR1,
+		imr:  hw.Base + GPIO_IMR,
+		isr:  hw.Base + GPIO_ISR,
+		edge: hw.Base + GPIO_EDGE_SEL,
+	}
+
+	if num > 15 {
+		gpio.irq = hw.IRQHigh
+		gpio.icr = hw.Base + GPIO_ICR2
 	}
 
+	hw.enableClock()
+
+	return
+}
+
+func (hw *GPIO) enableClock() {
 	if !hw.clk {
 		// enable clock
 		reg.SetN(hw.CCGR, hw.CG, 0b11, 0b11)
 		hw.clk = true
 	}
+}
 
-	return
+// WriteAll sets the controller data register bits selected by the argument
+// mask to the corresponding value bits, the update of all selected signals
+// happens with a single register write.
+func (hw *GPIO) WriteAll(mask uint32, value uint32) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	dr := hw.Base + GPIO_DR
+
+	r := reg.Read(dr)
+	r = (r & ^mask) | (value & mask)
+
+	reg.Write(dr, r)
+}
+
+// ReadAll returns the signal levels of all controller pads, sampled with a
+// single read of the pad status register.
+func (hw *GPIO) ReadAll() uint32 {
+	return reg.Read(hw.Base + GPIO_PSR)
 }
 
 // Out configures a GPIO as output.
 func (gpio *Pin) Out() {
+	gpio.hw.Lock()
+	defer gpio.hw.Unlock()
+
 	reg.Set(gpio.dir, gpio.num)
 }
 
 // In configures a GPIO as input.
 func (gpio *Pin) In() {
+	gpio.hw.Lock()
+	defer gpio.hw.Unlock()
+
 	reg.Clear(gpio.dir, gpio.num)
 }
 
 // High configures a GPIO signal as high.
 func (gpio *Pin) High() {
+	gpio.hw.Lock()
+	defer gpio.hw.Unlock()
+
+	if gpio.od {
+		// release the line, the high level is supplied externally
+		reg.Clear(gpio.dir, gpio.num)
+		return
+	}
+
 	reg.Set(gpio.data, gpio.num)
 }
 
 // Low configures a GPIO signal as low.
 func (gpio *Pin) Low() {
+	gpio.hw.Lock()
+	defer gpio.hw.Unlock()
+
 	reg.Clear(gpio.data, gpio.num)
+
+	if gpio.od {
+		// drive the line
+		reg.Set(gpio.dir, gpio.num)
+	}
+}
+
+// OpenDrain enables or disables open-drain emulation for a GPIO.
+//
+// When enabled the GPIO is never driven high, High() configures the GPIO as
+// input and Low() as output driving the signal low. The high level must
+// therefore be supplied by an external or pad pull-up (see iomuxc.PullUp()).
+//
+// Enabling open-drain emulation leaves the GPIO in the released (input)
+// state, disabling it leaves the GPIO direction unchanged.
+func (gpio *Pin) OpenDrain(enable bool) {
+	gpio.hw.Lock()
+	defer gpio.hw.Unlock()
+
+	gpio.od = enable
+
+	if enable {
+		reg.Clear(gpio.data, gpio.num)
+		reg.Clear(gpio.dir, gpio.num)
+	}
+}
+
+// Direction returns the GPIO direction (In or Out).
+func (gpio *Pin) Direction() int {
+	return int(reg.Get(gpio.dir, gpio.num, 1))
+}
+
+// Toggle inverts a GPIO output signal level.
+func (gpio *Pin) Toggle() {
+	gpio.hw.Lock()
+	defer gpio.hw.Unlock()
+
+	if gpio.od {
+		reg.Write(gpio.dir, reg.Read(gpio.dir)^(1<<gpio.num))
+		return
+	}
+
+	reg.Write(gpio.data, reg.Read(gpio.data)^(1<<gpio.num))
 }
 
 // Value returns the GPIO signal level.
 func (gpio *Pin) Value() (high bool) {
 	return reg.Get(gpio.data, gpio.num, 1) == 1
 }
+
+// IRQ returns the interrupt ID which signals events for this GPIO, to be
+// enabled on the ARM Generic Interrupt Controller (see gic.EnableInterrupt()).
+func (gpio *Pin) IRQ() int {
+	return gpio.irq
+}
+
+// EnableInterrupt configures a GPIO to generate an interrupt on the argument
+// signal transition and unmasks it.
+func (gpio *Pin) EnableInterrupt(edge Edge) (err error) {
+	gpio.hw.Lock()
+	defer gpio.hw.Unlock()
+
+	pos := (gpio.num % 16) * 2
+
+	// mask interrupt while changing its configuration
+	reg.Clear(gpio.imr, gpio.num)
+
+	switch edge {
+	case RisingEdge:
+		reg.Clear(gpio.edge, gpio.num)
+		reg.SetN(gpio.icr, pos, 0b11, ICR_RISING)
+	case FallingEdge:
+		reg.Clear(gpio.edge, gpio.num)
+		reg.SetN(gpio.icr, pos, 0b11, ICR_FALLING)
+	case BothEdges:
+		// ICR settings are ignored when EDGE_SEL is set
+		reg.Set(gpio.edge, gpio.num)
+	default:
+		return fmt.Errorf("invalid GPIO interrupt edge %d", edge)
+	}
+
+	// discard events latched before the configuration change
+	gpio.ClearInterrupt()
+	reg.Set(gpio.imr, gpio.num)
+
+	return
+}
+
+// DisableInterrupt masks a GPIO interrupt.
+func (gpio *Pin) DisableInterrupt() {
+	gpio.hw.Lock()
+	defer gpio.hw.Unlock()
+
+	reg.Clear(gpio.imr, gpio.num)
+}
+
+// ClearInterrupt clears a GPIO interrupt status.
+func (gpio *Pin) ClearInterrupt() {
+	// the interrupt status register is write-1-to-clear, therefore it
+	// must not be written back to avoid clearing other pins events
+	reg.Write(gpio.isr, 1<<gpio.num)
+}
+
+// Interrupt returns whether a GPIO interrupt condition has been detected.
+func (gpio *Pin) Interrupt() bool {
+	return reg.Get(gpio.isr, gpio.num, 1) == 1
+}
+
+// WatchButton monitors a pushbutton connected to the GPIO, invoking the
+// argument callback on each stable press or release.
+//
+// Contact bounce is handled by ignoring transitions occurring within the
+// debounce window following the last one, the pin level is then sampled and
+// reported if changed. The button is assumed to be active low (i.e. pressed
+// when the pin reads low, as typical with pull-up resistors).
+//
+// The GPIO is configured as input with transitions latched on both edges in
+// its interrupt status, which is monitored by a goroutine while the
+// interrupt is kept masked. The callback is executed in the same goroutine,
+// until UnwatchButton() is invoked.
+func (gpio *Pin) WatchButton(debounce time.Duration, cb func(pressed bool)) (err error) {
+	if cb == nil {
+		return errors.New("invalid callback")
+	}
+
+	gpio.UnwatchButton()
+	gpio.In()
+
+	if err = gpio.EnableInterrupt(BothEdges); err != nil {
+		return
+	}
+
+	gpio.DisableInterrupt()
+
+	exit := make(chan struct{})
+
+	gpio.hw.Lock()
+	gpio.button = exit
+	gpio.hw.Unlock()
+
+	go gpio.watchButton(debounce, cb, exit)
+
+	return
+}
+
+// UnwatchButton stops monitoring a pushbutton previously configured with
+// WatchButton().
+func (gpio *Pin) UnwatchButton() {
+	gpio.hw.Lock()
+	defer gpio.hw.Unlock()
+
+	if gpio.button != nil {
+		close(gpio.button)
+		gpio.button = nil
+	}
+}
+
+func (gpio *Pin) watchButton(debounce time.Duration, cb func(pressed bool), exit chan struct{}) {
+	pressed := !gpio.Value()
+
+	for {
+		// wait for a transition
+		if !reg.WaitSignal(exit, gpio.isr, gpio.num, 1, 1) {
+			return
+		}
+
+		// wait for the level to settle
+		for gpio.Interrupt() {
+			gpio.ClearInterrupt()
+			time.Sleep(debounce)
+		}
+
+		select {
+		case <-exit:
+			return
+		default:
+		}
+
+		if state := !gpio.Value(); state != pressed {
+			pressed = state
+			cb(pressed)
+		}
+	}
+}
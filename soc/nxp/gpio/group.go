@@ -0,0 +1,122 @@
+// NXP GPIO support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package gpio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// PinGroup instance, representing a contiguous set of GPIOs of a single
+// controller which are updated, or sampled, with a single register access.
+type PinGroup struct {
+	// controller instance
+	hw *GPIO
+
+	pos   int
+	width int
+	mask  uint32
+
+	dir uint32
+	psr uint32
+}
+
+// InitGroup initializes a group of `width` contiguous GPIOs starting from
+// GPIO number `first`, the group value bit 0 maps to the first GPIO.
+//
+// The group pads must be configured in GPIO mode (see iomuxc.Pad.Mode()).
+func (hw *GPIO) InitGroup(first int, width int) (group *PinGroup, err error) {
+	if hw.Base == 0 || hw.CCGR == 0 {
+		return nil, errors.New("invalid GPIO controller instance")
+	}
+
+	mask, err := groupMask(first, width)
+
+	if err != nil {
+		return
+	}
+
+	group = &PinGroup{
+		hw:    hw,
+		pos:   first,
+		width: width,
+		mask:  mask,
+		dir:   hw.Base + GPIO_GDIR,
+		psr:   hw.Base + GPIO_PSR,
+	}
+
+	hw.enableClock()
+
+	return
+}
+
+// groupMask returns the controller register mask of `width` contiguous GPIOs
+// starting from GPIO number `first`.
+func groupMask(first int, width int) (mask uint32, err error) {
+	if first < 0 || width < 1 || first+width > 32 {
+		return 0, fmt.Errorf("invalid GPIO group %d-%d", first, first+width-1)
+	}
+
+	return uint32((uint64(1)<<width)-1) << first, nil
+}
+
+// bits converts a group value to its controller register bits.
+func (group *PinGroup) bits(value uint32) uint32 {
+	return (value << group.pos) & group.mask
+}
+
+// value converts controller register bits to the group value.
+func (group *PinGroup) value(bits uint32) uint32 {
+	return (bits & group.mask) >> group.pos
+}
+
+// Mask returns the controller register mask of the group GPIOs.
+func (group *PinGroup) Mask() uint32 {
+	return group.mask
+}
+
+// Width returns the number of GPIOs in the group.
+func (group *PinGroup) Width() int {
+	return group.width
+}
+
+// Out configures all group GPIOs as output.
+func (group *PinGroup) Out() {
+	group.hw.Lock()
+	defer group.hw.Unlock()
+
+	reg.Write(group.dir, reg.Read(group.dir)|group.mask)
+}
+
+// In configures all group GPIOs as input.
+func (group *PinGroup) In() {
+	group.hw.Lock()
+	defer group.hw.Unlock()
+
+	reg.Write(group.dir, reg.Read(group.dir)&^group.mask)
+}
+
+// Write sets the group GPIO signals to the argument value bits, all signals
+// are updated with a single data register write (see GPIO.WriteAll()).
+//
+// Signals with a setup/hold relationship (e.g. data and strobe) can therefore
+// be sequenced with consecutive invocations, each resulting in a single data
+// register write.
+func (group *PinGroup) Write(value uint32) {
+	group.hw.WriteAll(group.mask, group.bits(value))
+}
+
+// Read returns the group GPIO signal levels, sampled with a single read of
+// the pad status register.
+func (group *PinGroup) Read() uint32 {
+	return group.value(reg.Read(group.psr))
+}
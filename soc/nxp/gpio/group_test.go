@@ -0,0 +1,74 @@
+// NXP GPIO support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package gpio
+
+import (
+	"testing"
+)
+
+func TestPinGroup(t *testing.T) {
+	tests := []struct {
+		first int
+		width int
+		mask  uint32
+		value uint32
+		bits  uint32
+	}{
+		{0, 32, 0xffffffff, 0xdeadbeef, 0xdeadbeef},
+		{31, 1, 0x80000000, 0xffffffff, 0x80000000},
+		{0, 1, 0x00000001, 0x00000003, 0x00000001},
+		{4, 8, 0x00000ff0, 0x000001a5, 0x00000a50},
+	}
+
+	for _, test := range tests {
+		mask, err := groupMask(test.first, test.width)
+
+		if err != nil {
+			t.Fatalf("%d/%d: %v", test.first, test.width, err)
+		}
+
+		if mask != test.mask {
+			t.Errorf("%d/%d: expected mask %#08x, got %#08x", test.first, test.width, test.mask, mask)
+		}
+
+		group := &PinGroup{
+			pos:   test.first,
+			width: test.width,
+			mask:  mask,
+		}
+
+		if bits := group.bits(test.value); bits != test.bits {
+			t.Errorf("%d/%d: expected bits %#08x, got %#08x", test.first, test.width, test.bits, bits)
+		}
+
+		if value, exp := group.value(0xffffffff), mask>>test.first; value != exp {
+			t.Errorf("%d/%d: expected value %#08x, got %#08x", test.first, test.width, exp, value)
+		}
+	}
+}
+
+func TestPinGroupRange(t *testing.T) {
+	tests := []struct {
+		first int
+		width int
+	}{
+		{-1, 1},
+		{0, 0},
+		{0, 33},
+		{31, 2},
+		{32, 1},
+	}
+
+	for _, test := range tests {
+		if _, err := groupMask(test.first, test.width); err == nil {
+			t.Errorf("%d/%d: expected error", test.first, test.width)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// NXP General Purpose Timer (GPT) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+// Package gpt implements a driver for the NXP General Purpose Timer (GPT)
+// adopting the following reference specifications:
+//   - IMX6ULLRM - i.MX 6ULL Applications Processor Reference Manual - Rev 1 2017/11
+//
+// This package is only meant to be used with `GOOS=tamago GOARCH=arm` as
+// supported by the TamaGo framework for bare metal Go on ARM SoCs, see
+// https://github.com/usbarmory/tamago.
+package gpt
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// GPT registers
+// (GPT Memory Map/Register Definition, IMX6ULLRM).
+const (
+	GPTx_CR   = 0x00
+	CR_SWR    = 15
+	CR_FRR    = 9
+	CR_CLKSRC = 6
+	CR_STOPEN = 5
+	CR_DOZEEN = 4
+	CR_WAITEN = 3
+	CR_DBGEN  = 2
+	CR_ENMOD  = 1
+	CR_EN     = 0
+
+	GPTx_PR      = 0x04
+	PR_PRESCALER = 0
+
+	GPTx_SR = 0x08
+	SR_ROV  = 5
+	SR_OF3  = 2
+	SR_OF2  = 1
+	SR_OF1  = 0
+
+	GPTx_IR  = 0x0c
+	IR_ROVIE = 5
+	IR_OF3IE = 2
+	IR_OF2IE = 1
+	IR_OF1IE = 0
+
+	GPTx_OCR1 = 0x10
+	GPTx_CNT  = 0x24
+)
+
+// GPT clock sources
+const (
+	CLKSRC_NONE       = 0b000
+	CLKSRC_PERIPHERAL = 0b001
+	CLKSRC_HIGH_FREQ  = 0b010
+)
+
+// GPT represents a General Purpose Timer instance.
+type GPT struct {
+	sync.Mutex
+
+	// Module index
+	Index int
+	// Base register
+	Base uint32
+	// Clock gate register
+	CCGR uint32
+	// Clock gate (bus clock, the serial clock gate must follow)
+	CG int
+	// Clock retrieval function (PERCLK_CLK_ROOT)
+	Clock func() uint32
+	// Interrupt ID
+	IRQ int
+
+	// control registers
+	cr  uint32
+	pr  uint32
+	sr  uint32
+	ir  uint32
+	cnt uint32
+
+	// counter frequency
+	freq uint32
+}
+
+// Init initializes a General Purpose Timer instance, the counter frequency is
+// derived from the peripheral clock with the closest available prescaler to
+// the argument frequency (see Frequency()).
+//
+// The timer operates in restart mode, where an output compare event on
+// channel 1 resets the counter, allowing periodic events (see
+// EnableCompare()).
+func (hw *GPT) Init(freq uint32) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.Base == 0 || hw.CCGR == 0 || hw.Clock == nil {
+		panic("invalid GPT instance")
+	}
+
+	clk := hw.Clock()
+
+	if freq == 0 || freq > clk {
+		panic("invalid GPT frequency")
+	}
+
+	hw.cr = hw.Base + GPTx_CR
+	hw.pr = hw.Base + GPTx_PR
+	hw.sr = hw.Base + GPTx_SR
+	hw.ir = hw.Base + GPTx_IR
+	hw.cnt = hw.Base + GPTx_CNT
+
+	// enable bus and serial clocks
+	reg.SetN(hw.CCGR, hw.CG, 0b1111, 0b1111)
+
+	// disable and reset
+	reg.Write(hw.cr, 0)
+	reg.Write(hw.ir, 0)
+	reg.Set(hw.cr, CR_SWR)
+	reg.Wait(hw.cr, CR_SWR, 1, 0)
+
+	div := (clk + freq/2) / freq
+
+	if div > 4096 {
+		div = 4096
+	}
+
+	hw.freq = clk / div
+
+	reg.SetN(hw.pr, PR_PRESCALER, 0xfff, div-1)
+	reg.SetN(hw.cr, CR_CLKSRC, 0b111, CLKSRC_HIGH_FREQ)
+
+	// keep counting in low power modes, reset counter on enable
+	reg.Set(hw.cr, CR_WAITEN)
+	reg.Set(hw.cr, CR_ENMOD)
+
+	// clear status
+	reg.Write(hw.sr, 0x3f)
+}
+
+// Frequency returns the counter frequency.
+func (hw *GPT) Frequency() uint32 {
+	return hw.freq
+}
+
+// Start enables the timer, resetting its counter.
+func (hw *GPT) Start() {
+	reg.Set(hw.cr, CR_EN)
+}
+
+// Stop disables the timer.
+func (hw *GPT) Stop() {
+	reg.Clear(hw.cr, CR_EN)
+}
+
+// Count returns the timer counter value.
+func (hw *GPT) Count() uint32 {
+	return reg.Read(hw.cnt)
+}
+
+// EnableCompare sets the argument output compare channel (1-3) to signal an
+// interrupt when the counter reaches the argument value.
+//
+// A compare event on channel 1 also resets the counter, a 1 kHz periodic
+// interrupt can therefore be configured by setting channel 1 with a value of
+// Frequency()/1000.
+func (hw *GPT) EnableCompare(ch int, count uint32) (err error) {
+	if ch < 1 || ch > 3 {
+		return errors.New("invalid output compare channel")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	reg.Write(hw.Base+GPTx_OCR1+uint32(4*(ch-1)), count)
+	reg.Write(hw.sr, 1<<(SR_OF1+ch-1))
+	reg.Set(hw.ir, IR_OF1IE+ch-1)
+
+	return
+}
+
+// DisableCompare disables interrupt generation for the argument output
+// compare channel (1-3).
+func (hw *GPT) DisableCompare(ch int) (err error) {
+	if ch < 1 || ch > 3 {
+		return errors.New("invalid output compare channel")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	reg.Clear(hw.ir, IR_OF1IE+ch-1)
+
+	return
+}
+
+// ClearInterrupt clears all output compare and rollover events, returning
+// the channel mask (bit 0 for channel 1) of compare events that occurred.
+func (hw *GPT) ClearInterrupt() (events int) {
+	sr := reg.Read(hw.sr)
+	reg.Write(hw.sr, sr)
+
+	return int(sr & (1<<SR_OF3 | 1<<SR_OF2 | 1<<SR_OF1))
+}
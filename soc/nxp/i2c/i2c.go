@@ -19,10 +19,13 @@ package i2c
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/usbarmory/tamago/internal/reg"
+	"github.com/usbarmory/tamago/soc/nxp/gpio"
+	"github.com/usbarmory/tamago/soc/nxp/iomuxc"
 )
 
 // I2C registers
@@ -35,29 +38,78 @@ const (
 	I2C_DEFAULT_IFDR = 0x16
 
 	I2Cx_IADR = 0x0000
+	IADR_ADR  = 1
+
 	I2Cx_IFDR = 0x0004
 
 	I2Cx_I2CR = 0x0008
 	I2CR_IEN  = 7
+	I2CR_IIEN = 6
 	I2CR_MSTA = 5
 	I2CR_MTX  = 4
 	I2CR_TXAK = 3
 	I2CR_RSTA = 2
 
 	I2Cx_I2SR = 0x000c
+	I2SR_ICF  = 7
+	I2SR_IAAS = 6
 	I2SR_IBB  = 5
+	I2SR_IAL  = 4
+	I2SR_SRW  = 2
 	I2SR_IIF  = 1
 	I2SR_RXAK = 0
 
 	I2Cx_I2DR = 0x0010
 )
 
+// I2C frequency dividers and their corresponding IFDR values, sorted by
+// divider (p1464, 31.7.2 I2C Frequency Divider Register (I2Cx_IFDR),
+// IMX6ULLRM).
+var dividers = [][2]uint16{
+	{22, 0x20}, {24, 0x21}, {26, 0x22}, {28, 0x23},
+	{30, 0x00}, {32, 0x24}, {36, 0x25}, {40, 0x26},
+	{42, 0x03}, {44, 0x27}, {48, 0x28}, {52, 0x05},
+	{56, 0x29}, {60, 0x06}, {64, 0x2a}, {72, 0x2b},
+	{80, 0x2c}, {88, 0x09}, {96, 0x2d}, {104, 0x0a},
+	{112, 0x2e}, {128, 0x2f}, {144, 0x0c}, {160, 0x30},
+	{192, 0x31}, {224, 0x32}, {240, 0x0f}, {256, 0x33},
+	{288, 0x10}, {320, 0x34}, {384, 0x35}, {448, 0x36},
+	{480, 0x13}, {512, 0x37}, {576, 0x14}, {640, 0x38},
+	{768, 0x39}, {896, 0x3a}, {960, 0x17}, {1024, 0x3b},
+	{1152, 0x18}, {1280, 0x3c}, {1536, 0x3d}, {1792, 0x3e},
+	{1920, 0x1b}, {2048, 0x3f}, {2304, 0x1c}, {2560, 0x1d},
+	{3072, 0x1e}, {3840, 0x1f},
+}
+
 // Configuration constants
 const (
 	// Timeout is the default timeout for I2C operations.
 	Timeout = 100 * time.Millisecond
+
+	// recoveryPulses is the maximum number of clock pulses issued by
+	// Recover().
+	recoveryPulses = 9
+	// recoveryDelay is the SCL half period (100 kHz) used by Recover().
+	recoveryDelay = 5 * time.Microsecond
 )
 
+// Recovery represents the pad configuration required to perform I2C bus
+// recovery (see Recover()).
+type Recovery struct {
+	// SCL pad
+	SCLPad *iomuxc.Pad
+	// SDA pad
+	SDAPad *iomuxc.Pad
+	// SCL GPIO
+	SCL *gpio.Pin
+	// SDA GPIO
+	SDA *gpio.Pin
+	// I2C pad mode (e.g. ALT0)
+	I2CMode uint32
+	// GPIO pad mode (e.g. ALT5)
+	GPIOMode uint32
+}
+
 // I2C represents an I2C port instance.
 type I2C struct {
 	sync.Mutex
@@ -70,11 +122,18 @@ type I2C struct {
 	CCGR uint32
 	// Clock gate
 	CG int
-	// Timeout for I2C operations
+	// Clock retrieval function
+	Clock func() uint32
+	// Timeout for each I2C bus condition or byte transfer wait (default:
+	// Timeout)
 	Timeout time.Duration
 	// Div sets the frequency divider to control the I2C clock rate
 	// (p1464, 31.7.2 I2C Frequency Divider Register (I2Cx_IFDR), IMX6ULLRM).
 	Div uint16
+	// Bus recovery configuration (optional, see Recover())
+	Recovery *Recovery
+	// Slave address (7-bit) matched in slave mode (see Listen())
+	SlaveAddr uint8
 
 	// control registers
 	iadr uint32
@@ -84,8 +143,8 @@ type I2C struct {
 	i2dr uint32
 }
 
-// Init initializes the I2C controller instance. At this time only master mode
-// is supported by this driver.
+// Init initializes the I2C controller instance in master mode, see Listen()
+// for slave mode operation.
 func (hw *I2C) Init() {
 	hw.Lock()
 	defer hw.Unlock()
@@ -119,6 +178,103 @@ func (hw *I2C) Init() {
 	reg.Set16(hw.i2cr, I2CR_IEN)
 }
 
+// SetSpeed configures the I2C clock rate to the highest frequency which does
+// not exceed the argument value, an error is returned if the requested rate is
+// lower than the minimum achievable with the available frequency dividers.
+//
+// The function can be invoked before Init(), to change the initial rate, as
+// well as between transfers.
+func (hw *I2C) SetSpeed(hz int) (err error) {
+	if hw.Clock == nil {
+		return errors.New("invalid I2C clock function")
+	}
+
+	if hz <= 0 {
+		return fmt.Errorf("invalid I2C speed %d", hz)
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	clk := hw.Clock()
+	min := (clk + uint32(hz) - 1) / uint32(hz)
+
+	for _, d := range dividers {
+		if uint32(d[0]) < min {
+			continue
+		}
+
+		hw.Div = d[1]
+
+		if hw.ifdr != 0 {
+			reg.Write16(hw.ifdr, hw.Div)
+		}
+
+		return
+	}
+
+	return fmt.Errorf("unreachable I2C speed %d (clock %d)", hz, clk)
+}
+
+// Recover performs bus recovery, to be used when a target holds SDA low (e.g.
+// after a reset which interrupted a transfer).
+//
+// The SCL and SDA pads are temporarily configured as GPIOs to issue up to 9
+// clock pulses, until SDA is released, followed by a STOP condition, the I2C
+// pad configuration is then restored. The I2C instance Recovery field must
+// be set before invoking this function.
+func (hw *I2C) Recover() (err error) {
+	r := hw.Recovery
+
+	if r == nil || r.SCLPad == nil || r.SDAPad == nil || r.SCL == nil || r.SDA == nil {
+		return errors.New("invalid I2C recovery configuration")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.i2cr != 0 {
+		reg.Clear16(hw.i2cr, I2CR_IEN)
+		defer reg.Set16(hw.i2cr, I2CR_IEN)
+	}
+
+	// bit-bang the bus, high levels are supplied by the I2C pull-ups
+	r.SCL.OpenDrain(true)
+	r.SDA.OpenDrain(true)
+
+	r.SCLPad.Mode(r.GPIOMode)
+	r.SDAPad.Mode(r.GPIOMode)
+
+	defer r.SCLPad.Mode(r.I2CMode)
+	defer r.SDAPad.Mode(r.I2CMode)
+
+	defer r.SCL.OpenDrain(false)
+	defer r.SDA.OpenDrain(false)
+
+	for i := 0; i < recoveryPulses && !r.SDA.Value(); i++ {
+		r.SCL.Low()
+		time.Sleep(recoveryDelay)
+		r.SCL.High()
+		time.Sleep(recoveryDelay)
+	}
+
+	if !r.SDA.Value() {
+		return errors.New("SDA still held low after recovery")
+	}
+
+	// STOP condition: SDA low to high transition while SCL is high
+	r.SCL.Low()
+	time.Sleep(recoveryDelay)
+	r.SDA.Low()
+	time.Sleep(recoveryDelay)
+	r.SCL.High()
+	time.Sleep(recoveryDelay)
+	r.SDA.High()
+	time.Sleep(recoveryDelay)
+
+	return
+}
+
 // Read reads a sequence of bytes from a target device
 // (p167, 16.4.2 Programming the I2C controller for I2C Read, IMX6FG).
 //
@@ -130,6 +286,23 @@ func (hw *I2C) Init() {
 // sending a register address (`SLAVE W|SLAVE R|DATA`) and less than 0 only to
 // send a target read (`SLAVE R|DATA`).
 func (hw *I2C) Read(target uint8, addr uint32, alen int, size int) (buf []byte, err error) {
+	return hw.read(uint16(target), false, addr, alen, size)
+}
+
+// Read10 reads a sequence of bytes from a target device with a 10-bit
+// address, see Read() for the address length (`alen`) parameter usage.
+//
+// As 10-bit target reads always require the target address to be sent in
+// write mode first, an address length less than 0 is equivalent to 0.
+func (hw *I2C) Read10(target uint16, addr uint32, alen int, size int) (buf []byte, err error) {
+	if alen < 0 {
+		alen = 0
+	}
+
+	return hw.read(target, true, addr, alen, size)
+}
+
+func (hw *I2C) read(target uint16, tenBit bool, addr uint32, alen int, size int) (buf []byte, err error) {
 	hw.Lock()
 	defer hw.Unlock()
 
@@ -138,8 +311,8 @@ func (hw *I2C) Read(target uint8, addr uint32, alen int, size int) (buf []byte,
 	}
 	defer hw.stop()
 
-	if alen > 0 {
-		if err = hw.txAddress(target, addr, alen); err != nil {
+	if alen > 0 || tenBit {
+		if err = hw.txAddress(target, tenBit, addr, alen); err != nil {
 			return
 		}
 
@@ -149,9 +322,7 @@ func (hw *I2C) Read(target uint8, addr uint32, alen int, size int) (buf []byte,
 	}
 
 	// send target address with R/W bit set
-	a := byte((target << 1) | 1)
-
-	if err = hw.tx([]byte{a}); err != nil {
+	if err = hw.txTarget(target, tenBit, true); err != nil {
 		return
 	}
 
@@ -172,6 +343,16 @@ func (hw *I2C) Read(target uint8, addr uint32, alen int, size int) (buf []byte,
 // ordinary I2C writes (`SLAVE W|ADDR|DATA`), equal to 0 when not sending a
 // register address (`SLAVE W|DATA`), values less than 0 are not valid.
 func (hw *I2C) Write(buf []byte, target uint8, addr uint32, alen int) (err error) {
+	return hw.write(buf, uint16(target), false, addr, alen)
+}
+
+// Write10 writes a sequence of bytes to a target device with a 10-bit
+// address, see Write() for the address length (`alen`) parameter usage.
+func (hw *I2C) Write10(buf []byte, target uint16, addr uint32, alen int) (err error) {
+	return hw.write(buf, target, true, addr, alen)
+}
+
+func (hw *I2C) write(buf []byte, target uint16, tenBit bool, addr uint32, alen int) (err error) {
 	if alen < 0 {
 		return errors.New("invalid address length")
 	}
@@ -184,27 +365,122 @@ func (hw *I2C) Write(buf []byte, target uint8, addr uint32, alen int) (err error
 	}
 	defer hw.stop()
 
-	if err = hw.txAddress(target, addr, alen); err != nil {
+	if err = hw.txAddress(target, tenBit, addr, alen); err != nil {
 		return
 	}
 
 	return hw.tx(buf)
 }
 
-func (hw *I2C) txAddress(target uint8, addr uint32, alen int) (err error) {
+// WriteRead writes a sequence of bytes to a target device and, after a
+// repeated START, reads from it filling the argument read buffer, within a
+// single transaction (`SLAVE W|DATA|SLAVE R|DATA`).
+//
+// The read phase is omitted when the read buffer is empty.
+func (hw *I2C) WriteRead(target uint8, w []byte, r []byte) (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if err = hw.start(false); err != nil {
+		return
+	}
+	defer hw.stop()
+
+	if err = hw.txTarget(uint16(target), false, false); err != nil {
+		return
+	}
+
+	if err = hw.tx(w); err != nil || len(r) == 0 {
+		return
+	}
+
+	if err = hw.start(true); err != nil {
+		return
+	}
+
+	if err = hw.txTarget(uint16(target), false, true); err != nil {
+		return
+	}
+
+	return hw.rx(r)
+}
+
+// Probe returns whether a target device acknowledges its 7-bit address, sent
+// within an address only write transaction (`SLAVE W`).
+func (hw *I2C) Probe(target uint8) bool {
 	if target > 0x7f {
-		return errors.New("invalid target address")
+		return false
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if err := hw.start(false); err != nil {
+		return false
+	}
+	defer hw.stop()
+
+	return hw.txTarget(uint16(target), false, false) == nil
+}
+
+// Scan probes all non-reserved 7-bit addresses (0x08-0x77), in the same
+// manner as Probe(), returning the ones acknowledged by a target device.
+//
+// Note that an address only write might be interpreted as a command by some
+// devices, which is why tools such as i2cdetect offer alternative probing
+// methods.
+func (hw *I2C) Scan() (targets []uint8) {
+	for target := uint8(0x08); target <= 0x77; target++ {
+		if hw.Probe(target) {
+			targets = append(targets, target)
+		}
+	}
+
+	return
+}
+
+// txTarget sends the target address, 10-bit addresses are sent as the
+// `11110|A9|A8|R/W` header followed, for writes, by the `A7-A0` address byte.
+// A 10-bit read header must only be sent after a repeated START following a
+// 10-bit write header, as it selects the last addressed target.
+func (hw *I2C) txTarget(target uint16, tenBit bool, read bool) (err error) {
+	var a []byte
+
+	if tenBit {
+		if target > 0x3ff {
+			return errors.New("invalid target address")
+		}
+
+		hdr := byte(0xf0 | (target>>7)&0x06)
+
+		if read {
+			a = []byte{hdr | 1}
+		} else {
+			a = []byte{hdr, byte(target & 0xff)}
+		}
+	} else {
+		if target > 0x7f {
+			return errors.New("invalid target address")
+		}
+
+		a = []byte{byte(target << 1)}
+
+		if read {
+			a[0] |= 1
+		}
 	}
 
+	return hw.tx(a)
+}
+
+func (hw *I2C) txAddress(target uint16, tenBit bool, addr uint32, alen int) (err error) {
 	if alen > 4 {
 		return errors.New("invalid register address length")
 	}
 
 	if alen >= 0 {
 		// send target address with R/W bit unset
-		a := byte(target << 1)
-
-		if err = hw.tx([]byte{a}); err != nil {
+		if err = hw.txTarget(target, tenBit, false); err != nil {
 			return
 		}
 	}
@@ -240,7 +516,7 @@ func (hw *I2C) rx(buf []byte) (err error) {
 
 	for i := 0; i < size; i++ {
 		if !reg.WaitFor16(hw.Timeout, hw.i2sr, I2SR_IIF, 1, 1) {
-			return errors.New("timeout on byte reception")
+			return fmt.Errorf("timeout on byte reception (%d/%d, %v)", i+1, size, hw.Timeout)
 		}
 
 		if i == size-2 {
@@ -262,7 +538,7 @@ func (hw *I2C) tx(buf []byte) (err error) {
 		reg.Write16(hw.i2dr, uint16(buf[i]))
 
 		if !reg.WaitFor16(hw.Timeout, hw.i2sr, I2SR_IIF, 1, 1) {
-			return errors.New("timeout on byte transmission")
+			return fmt.Errorf("timeout on byte transmission (%d/%d, %v)", i+1, len(buf), hw.Timeout)
 		}
 
 		if reg.Get16(hw.i2sr, I2SR_RXAK, 1) == 1 {
@@ -279,7 +555,7 @@ func (hw *I2C) start(repeat bool) (err error) {
 	if repeat == false {
 		// wait for bus to be free
 		if !reg.WaitFor16(hw.Timeout, hw.i2sr, I2SR_IBB, 1, 0) {
-			return errors.New("timeout waiting bus to be free")
+			return fmt.Errorf("timeout waiting bus to be free (%v)", hw.Timeout)
 		}
 
 		// enable master mode, generates START signal
@@ -293,7 +569,7 @@ func (hw *I2C) start(repeat bool) (err error) {
 	// wait for bus to be busy
 	if !reg.WaitFor16(hw.Timeout, hw.i2sr, I2SR_IBB, 1, 1) {
 		reg.Clear16(hw.i2cr, pos)
-		return errors.New("timeout waiting bus to be busy")
+		return fmt.Errorf("timeout waiting bus to be busy (%v)", hw.Timeout)
 	}
 
 	if repeat == false {
@@ -0,0 +1,151 @@
+// NXP I2C driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package i2c
+
+import (
+	"errors"
+	"runtime"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// SlaveHandler represents the callbacks invoked in slave mode to service
+// master transactions.
+//
+// The bus clock is stretched while Read executes, as the controller holds
+// SCL low until the data register is written, giving the handler time to
+// prepare data. Write is invoked after the data register is read, which
+// releases SCL, and therefore runs while the master proceeds with the next
+// byte.
+type SlaveHandler struct {
+	// Write is invoked with each byte written by the master.
+	Write func(b byte)
+	// Read is invoked to supply each byte read by the master.
+	Read func() byte
+	// Stop is invoked at the end of each transaction, on STOP or repeated
+	// START conditions (optional).
+	Stop func()
+}
+
+// Listen enables slave mode, responding to the I2C instance SlaveAddr, and
+// services master transactions through the argument handler until the exit
+// channel is closed.
+//
+// The controller must be initialized (see Init()), master transfers cannot
+// be issued while listening.
+func (hw *I2C) Listen(h *SlaveHandler, exit chan struct{}) (err error) {
+	if h == nil || h.Write == nil || h.Read == nil {
+		return errors.New("invalid slave handler")
+	}
+
+	if hw.SlaveAddr == 0 || hw.SlaveAddr > 0x7f {
+		return errors.New("invalid slave address")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.i2cr == 0 {
+		return errors.New("controller not initialized")
+	}
+
+	reg.Write16(hw.iadr, uint16(hw.SlaveAddr)<<IADR_ADR)
+
+	// set slave receive mode
+	reg.Clear16(hw.i2cr, I2CR_MSTA)
+	reg.Clear16(hw.i2cr, I2CR_MTX)
+	reg.Clear16(hw.i2cr, I2CR_TXAK)
+	reg.Clear16(hw.i2sr, I2SR_IIF)
+
+	defer reg.Write16(hw.iadr, 0)
+
+	active := false
+
+	for {
+		select {
+		case <-exit:
+			return
+		default:
+		}
+
+		sr := reg.Read16(hw.i2sr)
+
+		// the controller raises no interrupt on STOP conditions
+		if active && sr&(1<<I2SR_IBB) == 0 {
+			active = false
+
+			if h.Stop != nil {
+				h.Stop()
+			}
+		}
+
+		if sr&(1<<I2SR_IIF) == 0 {
+			// tamago is single-threaded, give other goroutines a chance
+			runtime.Gosched()
+			continue
+		}
+
+		active = hw.slaveEvent(h, sr, active)
+	}
+}
+
+// slaveEvent handles a slave mode interrupt event, it returns whether a
+// transaction is in progress.
+func (hw *I2C) slaveEvent(h *SlaveHandler, sr uint16, active bool) bool {
+	defer reg.Clear16(hw.i2sr, I2SR_IIF)
+
+	if sr&(1<<I2SR_IAL) != 0 {
+		reg.Clear16(hw.i2sr, I2SR_IAL)
+
+		if sr&(1<<I2SR_IAAS) == 0 {
+			return active
+		}
+	}
+
+	if sr&(1<<I2SR_ICF) == 0 {
+		// transfer in progress
+		return active
+	}
+
+	if sr&(1<<I2SR_IAAS) != 0 {
+		// repeated START
+		if active && h.Stop != nil {
+			h.Stop()
+		}
+
+		if sr&(1<<I2SR_SRW) != 0 {
+			// slave transmit
+			reg.Set16(hw.i2cr, I2CR_MTX)
+			reg.Write16(hw.i2dr, uint16(h.Read()))
+		} else {
+			// slave receive
+			reg.Clear16(hw.i2cr, I2CR_MTX)
+			reg.Clear16(hw.i2cr, I2CR_TXAK)
+			// dummy read
+			reg.Read16(hw.i2dr)
+		}
+
+		return true
+	}
+
+	switch {
+	case reg.Get16(hw.i2cr, I2CR_MTX, 1) == 0:
+		h.Write(byte(reg.Read16(hw.i2dr) & 0xff))
+	case sr&(1<<I2SR_RXAK) == 0:
+		reg.Write16(hw.i2dr, uint16(h.Read()))
+	default:
+		// no acknowledgement from master, release the bus
+		reg.Clear16(hw.i2cr, I2CR_MTX)
+		// dummy read
+		reg.Read16(hw.i2dr)
+	}
+
+	return true
+}
@@ -0,0 +1,166 @@
+// NXP I2C driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package i2c
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// SMBus constants
+const (
+	// maximum block transfer size (SMBus 2.0)
+	SMBUS_BLOCK_MAX = 32
+	// Packet Error Code CRC-8 polynomial (x^8 + x^2 + x + 1)
+	SMBUS_PEC_POLY = 0x07
+)
+
+// PEC computes the SMBus Packet Error Code (CRC-8) of the argument buffer,
+// which must include all transaction bytes (target addresses with their R/W
+// bit, commands and data).
+func PEC(buf []byte) (crc byte) {
+	for _, b := range buf {
+		crc ^= b
+
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ SMBUS_PEC_POLY
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return
+}
+
+// ReadBlock performs an SMBus Block Read
+// (`SLAVE W|CMD|SLAVE R|COUNT|DATA|[PEC]`), returning the data bytes without
+// the leading byte count.
+//
+// When `pec` is true the trailing Packet Error Code is read and validated.
+func (hw *I2C) ReadBlock(target uint8, cmd byte, pec bool) (buf []byte, err error) {
+	if target > 0x7f {
+		return nil, errors.New("invalid target address")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if err = hw.start(false); err != nil {
+		return
+	}
+	defer hw.stop()
+
+	if err = hw.txAddress(uint16(target), false, uint32(cmd), 1); err != nil {
+		return
+	}
+
+	if err = hw.start(true); err != nil {
+		return
+	}
+
+	if err = hw.txTarget(uint16(target), false, true); err != nil {
+		return
+	}
+
+	res, err := hw.rxBlock(pec)
+
+	if err != nil {
+		return
+	}
+
+	count := int(res[0])
+
+	if pec {
+		msg := append([]byte{target << 1, cmd, target<<1 | 1}, res[:1+count]...)
+
+		if crc := PEC(msg); crc != res[1+count] {
+			return nil, fmt.Errorf("PEC mismatch (%#x != %#x)", crc, res[1+count])
+		}
+	}
+
+	return res[1 : 1+count], nil
+}
+
+// WriteBlock performs an SMBus Block Write
+// (`SLAVE W|CMD|COUNT|DATA|[PEC]`), the byte count is prepended to the
+// argument data.
+//
+// When `pec` is true the Packet Error Code is appended to the transaction.
+func (hw *I2C) WriteBlock(target uint8, cmd byte, buf []byte, pec bool) (err error) {
+	if target > 0x7f {
+		return errors.New("invalid target address")
+	}
+
+	if len(buf) == 0 || len(buf) > SMBUS_BLOCK_MAX {
+		return errors.New("invalid block size")
+	}
+
+	msg := append([]byte{byte(len(buf))}, buf...)
+
+	if pec {
+		msg = append(msg, PEC(append([]byte{target << 1, cmd}, msg...)))
+	}
+
+	return hw.write(msg, uint16(target), false, uint32(cmd), 1)
+}
+
+// rxBlock receives an SMBus block, including the leading byte count and the
+// optional trailing PEC, acknowledging bytes until the one preceding the last
+// (see rx()).
+//
+// As the byte count is only known after its reception, the byte that
+// follows it is always acknowledged, for the block size is at least 1.
+func (hw *I2C) rxBlock(pec bool) (buf []byte, err error) {
+	// byte count and at least one data byte
+	size := 2
+
+	if pec {
+		size += 1
+	}
+
+	// set read from target bit
+	reg.Clear16(hw.i2cr, I2CR_MTX)
+	reg.Clear16(hw.i2cr, I2CR_TXAK)
+
+	reg.Clear16(hw.i2sr, I2SR_IIF)
+	// dummy read
+	reg.Read16(hw.i2dr)
+
+	for i := 0; i < size; i++ {
+		if !reg.WaitFor16(hw.Timeout, hw.i2sr, I2SR_IIF, 1, 1) {
+			return nil, fmt.Errorf("timeout on byte reception (%d/%d, %v)", i+1, size, hw.Timeout)
+		}
+
+		if i > 0 && i == size-2 {
+			reg.Set16(hw.i2cr, I2CR_TXAK)
+		} else if i == size-1 {
+			hw.stop()
+		}
+
+		b := byte(reg.Read16(hw.i2dr) & 0xff)
+		reg.Clear16(hw.i2sr, I2SR_IIF)
+
+		if i == 0 {
+			if b == 0 || b > SMBUS_BLOCK_MAX {
+				return nil, fmt.Errorf("invalid block size (%d)", b)
+			}
+
+			size += int(b) - 1
+		}
+
+		buf = append(buf, b)
+	}
+
+	return
+}
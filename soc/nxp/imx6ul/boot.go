@@ -0,0 +1,98 @@
+// NXP i.MX6UL boot mode support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package imx6ul
+
+import (
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// System Reset Controller boot mode registers
+const (
+	SRC_SBMR1       = 0x020d8004
+	SBMR1_BOOT_CFG1 = 0
+
+	SRC_SBMR2  = 0x020d801c
+	SBMR2_BMOD = 24
+)
+
+// Boot modes (BMOD)
+const (
+	BMOD_FUSES           = 0b00
+	BMOD_SERIAL_DOWNLOAD = 0b01
+	BMOD_INTERNAL        = 0b10
+)
+
+// BootDevice represents the device the SoC booted from.
+type BootDevice int
+
+// Boot devices (Boot devices (internal boot), IMX6ULLRM)
+const (
+	BOOT_UNKNOWN BootDevice = iota
+	BOOT_NOR
+	BOOT_QSPI
+	BOOT_SPI
+	BOOT_SD
+	BOOT_MMC
+	BOOT_NAND
+	BOOT_SERIAL_DOWNLOAD
+)
+
+// String returns the boot device name.
+func (d BootDevice) String() string {
+	switch d {
+	case BOOT_NOR:
+		return "NOR/EIM"
+	case BOOT_QSPI:
+		return "QSPI"
+	case BOOT_SPI:
+		return "serial ROM (SPI)"
+	case BOOT_SD:
+		return "SD/eSD/SDXC"
+	case BOOT_MMC:
+		return "MMC/eMMC"
+	case BOOT_NAND:
+		return "NAND"
+	case BOOT_SERIAL_DOWNLOAD:
+		return "serial downloader"
+	default:
+		return "unknown"
+	}
+}
+
+// BootMode returns the boot device decoded from the SRC_SBMR1 and SRC_SBMR2
+// boot mode registers.
+//
+// BOOT_SERIAL_DOWNLOAD is returned when the boot mode pins select the serial
+// downloader or, as the boot ROM falls back to it on internal boot failures,
+// when Serial Download Protocol over USB has been detected (see SDP).
+func BootMode() BootDevice {
+	if SDP || reg.Get(SRC_SBMR2, SBMR2_BMOD, 0b11) == BMOD_SERIAL_DOWNLOAD {
+		return BOOT_SERIAL_DOWNLOAD
+	}
+
+	cfg := reg.Get(SRC_SBMR1, SBMR1_BOOT_CFG1, 0xff)
+
+	switch dev := cfg >> 4; {
+	case dev == 0b0000:
+		return BOOT_NOR
+	case dev == 0b0001:
+		return BOOT_QSPI
+	case dev == 0b0011:
+		return BOOT_SPI
+	case dev&0b1110 == 0b0100:
+		return BOOT_SD
+	case dev&0b1110 == 0b0110:
+		return BOOT_MMC
+	case dev&0b1000 == 0b1000:
+		return BOOT_NAND
+	default:
+		return BOOT_UNKNOWN
+	}
+}
@@ -25,12 +25,19 @@ const (
 	CCM_CBCDR      = 0x020c4014
 	CBCDR_IPG_PODF = 8
 
+	CCM_CDHIPR           = 0x020c4048
+	CDHIPR_ARM_PODF_BUSY = 16
+
 	CCM_CSCDR1           = 0x020c4024
 	CSCDR1_USDHC2_PODF   = 16
 	CSCDR1_USDHC1_PODF   = 11
 	CSCDR1_UART_CLK_SEL  = 6
 	CSCDR1_UART_CLK_PODF = 0
 
+	CCM_CSCDR2            = 0x020c4038
+	CSCDR2_ECSPI_CLK_PODF = 19
+	CSCDR2_ECSPI_CLK_SEL  = 18
+
 	CCM_CSCMR1            = 0x020c401c
 	CSCMR1_USDHC2_CLK_SEL = 17
 	CSCMR1_USDHC1_CLK_SEL = 16
@@ -74,6 +81,7 @@ const (
 	CCM_CCGR1 = 0x020c406c
 	CCM_CCGR2 = 0x020c4070
 	CCM_CCGR3 = 0x020c4074
+	CCM_CCGR4 = 0x020c4078
 	CCM_CCGR5 = 0x020c407c
 	CCM_CCGR6 = 0x020c4080
 
@@ -112,11 +120,13 @@ const (
 
 // Operating ARM core frequencies in MHz (care must be taken as not all P/Ns
 // support the entire range)
-// (p24, Table 10. Operating Ranges, IMX6ULLCEC).
+// (p24, Table 10. Operating Ranges, IMX6ULLCEC)
+// (Table 10. Operating Ranges, IMX6ULCEC).
 const (
 	FreqMax = Freq900
 	Freq900 = 900
 	Freq792 = 792
+	Freq696 = 696
 	Freq528 = 528
 	Freq396 = 396
 	Freq198 = 198
@@ -209,6 +219,11 @@ func SetARMFreq(mhz uint32) (err error) {
 		div_select = 66
 		arm_podf = 0
 		uV = 1225000
+	case Freq696:
+		// Table 10. Operating Ranges, IMX6ULCEC
+		div_select = 58
+		arm_podf = 0
+		uV = 1275000
 	case Freq528:
 		div_select = 88
 		arm_podf = 1
@@ -222,7 +237,7 @@ func SetARMFreq(mhz uint32) (err error) {
 		arm_podf = 3
 		uV = 950000
 	default:
-		return errors.New("unsupported")
+		return errors.New("unsupported ARM core frequency")
 	}
 
 	if mhz > curMHz {
@@ -247,6 +262,9 @@ func SetARMFreq(mhz uint32) (err error) {
 	// set core divisor
 	reg.SetN(CCM_CACRR, CACRR_ARM_PODF, 0b111, arm_podf)
 
+	// wait for divider handshake
+	reg.Wait(CCM_CDHIPR, CDHIPR_ARM_PODF_BUSY, 1, 0)
+
 	if mhz < curMHz {
 		setOperatingPoint(uV)
 	}
@@ -278,6 +296,24 @@ func GetHighFrequencyClock() uint32 {
 	return freq / (podf + 1)
 }
 
+// SetHighFrequencyClock controls the PERCLK_CLK_ROOT frequency by setting
+// CSCMR1[PERCLK_CLK_SEL] and CSCMR1[PERCLK_PODF]
+// (p629, Figure 18-2. Clock Tree - Part 1, IMX6ULLRM).
+func SetHighFrequencyClock(podf uint32, clksel uint32) (err error) {
+	if podf > 0x3f {
+		return errors.New("podf value out of range")
+	}
+
+	if clksel > 1 {
+		return errors.New("selector value out of range")
+	}
+
+	reg.SetN(CCM_CSCMR1, CSCMR1_PERCLK_PODF, 0x3f, podf)
+	reg.SetN(CCM_CSCMR1, CSCMR1_PERCLK_SEL, 1, clksel)
+
+	return
+}
+
 // GetPFD returns the fractional divider and frequency in Hz of a PLL PFD
 // (p734, 18.7.15 480MHz Clock (PLL3) Phase Fractional Divider Control Register, IMX6ULLRM)
 // (p736, 18.7.16 480MHz Clock (PLL2) Phase Fractional Divider Control Register, IMX6ULLRM).
@@ -384,6 +420,62 @@ func GetUARTClock() uint32 {
 	return freq / (podf + 1)
 }
 
+// SetUARTClock controls the UART_CLK_ROOT frequency by setting
+// CSCDR1[UART_CLK_SEL] and CSCDR1[UART_CLK_PODF]
+// (p630, Figure 18-3. Clock Tree - Part 2, IMX6ULLRM).
+//
+// UART baud rate dividers are computed on initialization, affected UART
+// instances must therefore be re-initialized after a clock change.
+func SetUARTClock(podf uint32, clksel uint32) (err error) {
+	if podf > 0b111111 {
+		return errors.New("podf value out of range")
+	}
+
+	if clksel > 1 {
+		return errors.New("selector value out of range")
+	}
+
+	reg.SetN(CCM_CSCDR1, CSCDR1_UART_CLK_PODF, 0b111111, podf)
+	reg.SetN(CCM_CSCDR1, CSCDR1_UART_CLK_SEL, 1, clksel)
+
+	return
+}
+
+// GetECSPIClock returns the ECSPI_CLK_ROOT frequency
+// (p630, Figure 18-3. Clock Tree - Part 2, IMX6ULLRM).
+func GetECSPIClock() uint32 {
+	var freq uint32
+
+	if reg.Get(CCM_CSCDR2, CSCDR2_ECSPI_CLK_SEL, 1) == 1 {
+		freq = OSC_FREQ
+	} else {
+		// PLL3_60M
+		freq = PLL3_FREQ / 8
+	}
+
+	podf := reg.Get(CCM_CSCDR2, CSCDR2_ECSPI_CLK_PODF, 0b111111)
+
+	return freq / (podf + 1)
+}
+
+// SetECSPIClock controls the ECSPI_CLK_ROOT frequency by setting
+// CSCDR2[ECSPI_CLK_SEL] and CSCDR2[ECSPI_CLK_PODF]
+// (p630, Figure 18-3. Clock Tree - Part 2, IMX6ULLRM).
+func SetECSPIClock(podf uint32, clksel uint32) (err error) {
+	if podf > 0b111111 {
+		return errors.New("podf value out of range")
+	}
+
+	if clksel > 1 {
+		return errors.New("selector value out of range")
+	}
+
+	reg.SetN(CCM_CSCDR2, CSCDR2_ECSPI_CLK_PODF, 0b111111, podf)
+	reg.SetN(CCM_CSCDR2, CSCDR2_ECSPI_CLK_SEL, 1, clksel)
+
+	return
+}
+
 // GetUSDHCClock returns the USDHCx_CLK_ROOT clock by reading CSCMR1[USDHCx_CLK_SEL]
 // and CSCDR1[USDHCx_PODF]
 // (p629, Figure 18-2. Clock Tree - Part 1, IMX6ULLRM)
@@ -522,3 +614,82 @@ func EnableENETPLL(index int) (err error) {
 
 	return
 }
+
+// ClockGate enables or disables the clock of a peripheral, as identified by
+// its clock gate register and clock gate (see CCGR and CG fields of
+// peripheral instances).
+//
+// A gated peripheral must not be accessed until its clock is re-enabled, its
+// register contents are retained while gated.
+func ClockGate(ccgr uint32, cg int, on bool) {
+	if on {
+		reg.SetN(ccgr, cg, 0b11, 0b11)
+	} else {
+		reg.SetN(ccgr, cg, 0b11, 0b00)
+	}
+}
+
+// ClockEnabled returns whether the clock of a peripheral, as identified by
+// its clock gate register and clock gate, is enabled.
+func ClockEnabled(ccgr uint32, cg int) bool {
+	return reg.Get(ccgr, cg, 0b11) != 0b00
+}
+
+// GetClock returns the root clock frequency of a peripheral, as identified by
+// its clock gate register and clock gate (see CCGR and CG fields of
+// peripheral instances), computed from the current CCM selectors and
+// dividers.
+//
+// The frequency is returned regardless of the clock gate state (see
+// ClockEnabled()), zero is returned for peripherals without a known clock
+// root.
+func GetClock(ccgr uint32, cg int) (hz uint32) {
+	switch ccgr {
+	case CCM_CCGR0:
+		switch cg {
+		case CCGRx_CG6: // ENET1/ENET2
+			return GetPeripheralClock()
+		case CCGRx_CG12: // GPT2
+			return GetHighFrequencyClock()
+		case CCGRx_CG14: // UART2
+			return GetUARTClock()
+		}
+	case CCM_CCGR1:
+		switch cg {
+		case CCGRx_CG0, CCGRx_CG1, CCGRx_CG2, CCGRx_CG3: // ECSPI1-4
+			return GetECSPIClock()
+		case CCGRx_CG5, CCGRx_CG12: // UART3, UART4
+			return GetUARTClock()
+		case CCGRx_CG6, CCGRx_CG7, CCGRx_CG10: // EPIT1, EPIT2, GPT1
+			return GetHighFrequencyClock()
+		}
+	case CCM_CCGR2:
+		switch cg {
+		case CCGRx_CG3, CCGRx_CG5: // I2C1, I2C2
+			return GetHighFrequencyClock()
+		case CCGRx_CG6: // OCOTP
+			return GetPeripheralClock()
+		}
+	case CCM_CCGR4:
+		switch cg {
+		case CCGRx_CG8, CCGRx_CG9, CCGRx_CG10, CCGRx_CG11: // PWM1-4
+			return GetHighFrequencyClock()
+		}
+	case CCM_CCGR5:
+		switch cg {
+		case CCGRx_CG12: // UART1
+			return GetUARTClock()
+		}
+	case CCM_CCGR6:
+		switch cg {
+		case CCGRx_CG1: // USDHC1
+			_, _, hz = GetUSDHCClock(1)
+		case CCGRx_CG2: // USDHC2
+			_, _, hz = GetUSDHCClock(2)
+		case CCGRx_CG13, CCGRx_CG14, CCGRx_CG15, CCGRx_CG8: // PWM5-8
+			return GetHighFrequencyClock()
+		}
+	}
+
+	return
+}
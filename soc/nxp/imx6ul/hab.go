@@ -0,0 +1,195 @@
+// NXP i.MX6UL HAB support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package imx6ul
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/usbarmory/tamago/dma"
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// HAB ROM Vector Table
+// (High Assurance Boot Version 4 Application Programming Interface Reference
+// Manual, HAB4_API).
+const (
+	HAB_RVT_BASE = 0x00000100
+
+	HAB_RVT_HDR           = HAB_RVT_BASE + 0x00
+	HAB_RVT_REPORT_EVENT  = HAB_RVT_BASE + 0x20
+	HAB_RVT_REPORT_STATUS = HAB_RVT_BASE + 0x24
+
+	HAB_TAG_RVT = 0xdd
+	HAB_TAG_EVT = 0xdb
+
+	// maximum number of event records read from the audit log
+	habMaxEvents = 64
+	// event record header size
+	habHeaderSize = 4
+)
+
+// HAB status values
+const (
+	HAB_STS_ANY = 0x00
+	HAB_FAILURE = 0x33
+	HAB_WARNING = 0x69
+	HAB_SUCCESS = 0xf0
+)
+
+// HAB security configuration values
+const (
+	HAB_CFG_RETURN = 0x33
+	HAB_CFG_OPEN   = 0xf0
+	HAB_CFG_CLOSED = 0xcc
+)
+
+// HAB security state values
+const (
+	HAB_STATE_INITIAL   = 0x33
+	HAB_STATE_CHECK     = 0x55
+	HAB_STATE_NONSECURE = 0x66
+	HAB_STATE_TRUSTED   = 0x99
+	HAB_STATE_SECURE    = 0xaa
+	HAB_STATE_FAIL_SOFT = 0xcc
+	HAB_STATE_FAIL_HARD = 0xff
+	HAB_STATE_NONE      = 0xf0
+)
+
+// defined in hab.s
+func hab_call(fn uint32, a0 uint32, a1 uint32, a2 uint32, a3 uint32) uint32
+
+// HABEvent represents a HAB audit log event record.
+type HABEvent struct {
+	// Status (HAB_FAILURE, HAB_WARNING, HAB_SUCCESS)
+	Status uint8
+	// Reason code
+	Reason uint8
+	// Context in which the event was logged
+	Context uint8
+	// Engine associated with the event
+	Engine uint8
+	// Event data
+	Data []byte
+}
+
+// String returns the event status, reason, context and engine.
+func (e HABEvent) String() string {
+	return fmt.Sprintf("status:%#x reason:%#x context:%#x engine:%#x", e.Status, e.Reason, e.Context, e.Engine)
+}
+
+// HABReport represents the HAB status and audit log as reported by the ROM.
+type HABReport struct {
+	// Overall status (HAB_FAILURE, HAB_WARNING, HAB_SUCCESS)
+	Status uint8
+	// Security configuration (see HAB_CFG_*)
+	Config uint8
+	// Security state (see HAB_STATE_*)
+	State uint8
+	// Audit log events
+	Events []HABEvent
+}
+
+// Closed returns whether the SoC security configuration is closed (i.e.
+// Secure Boot is enforced).
+func (r *HABReport) Closed() bool {
+	return r.Config == HAB_CFG_CLOSED
+}
+
+// HABStatus returns the HAB security configuration, state and audit log event
+// records by means of the ROM API report_status and report_event functions.
+//
+// Unlike HAB(), this allows to detect warnings, logged during authentication
+// of the boot image, which do not prevent execution.
+//
+// The HAB ROM Vector Table resides in the first memory page, which is flagged
+// as invalid by arm.InitMMU() to trap null pointers, therefore it must be
+// remapped (see arm.ConfigureMMU) before calling this function. The ROM API is
+// only available when running natively in Secure World.
+func HABStatus() (report *HABReport, err error) {
+	if !Native || ARM.NonSecure() {
+		return nil, errors.New("HAB ROM API unavailable")
+	}
+
+	if hdr := reg.Read(HAB_RVT_HDR); hdr&0xff != HAB_TAG_RVT {
+		return nil, fmt.Errorf("invalid HAB RVT header (%#x)", hdr)
+	}
+
+	reportStatus := reg.Read(HAB_RVT_REPORT_STATUS)
+	reportEvent := reg.Read(HAB_RVT_REPORT_EVENT)
+
+	// config, state and event size arguments
+	argsAddr, args := dma.Reserve(8, 4)
+	defer dma.Release(argsAddr)
+
+	report = &HABReport{
+		Status: uint8(hab_call(reportStatus, uint32(argsAddr), uint32(argsAddr)+1, 0, 0)),
+		Config: args[0],
+		State:  args[1],
+	}
+
+	sizeAddr := uint32(argsAddr) + 4
+
+	for i := uint32(0); i < habMaxEvents; i++ {
+		binary.LittleEndian.PutUint32(args[4:], 0)
+
+		// query event size
+		if hab_call(reportEvent, HAB_STS_ANY, i, 0, sizeAddr) != HAB_SUCCESS {
+			break
+		}
+
+		size := int(binary.LittleEndian.Uint32(args[4:]))
+
+		if size < habHeaderSize+4 {
+			return nil, fmt.Errorf("invalid HAB event size (%d)", size)
+		}
+
+		evt, err := readHABEvent(reportEvent, i, size, sizeAddr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		report.Events = append(report.Events, evt)
+	}
+
+	return
+}
+
+// readHABEvent reads and parses the audit log event record at the argument
+// index.
+func readHABEvent(reportEvent uint32, index uint32, size int, sizeAddr uint32) (evt HABEvent, err error) {
+	addr, buf := dma.Reserve(size, 4)
+	defer dma.Release(addr)
+
+	if hab_call(reportEvent, HAB_STS_ANY, index, uint32(addr), sizeAddr) != HAB_SUCCESS {
+		return evt, fmt.Errorf("could not read HAB event %d", index)
+	}
+
+	// event record header: tag, length (big endian), version
+	if buf[0] != HAB_TAG_EVT {
+		return evt, fmt.Errorf("invalid HAB event %d tag (%#x)", index, buf[0])
+	}
+
+	if n := int(binary.BigEndian.Uint16(buf[1:3])); n >= habHeaderSize+4 && n < size {
+		size = n
+	}
+
+	evt.Status = buf[habHeaderSize+0]
+	evt.Reason = buf[habHeaderSize+1]
+	evt.Context = buf[habHeaderSize+2]
+	evt.Engine = buf[habHeaderSize+3]
+
+	evt.Data = make([]byte, size-habHeaderSize-4)
+	copy(evt.Data, buf[habHeaderSize+4:size])
+
+	return
+}
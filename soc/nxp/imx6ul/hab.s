@@ -0,0 +1,24 @@
+// NXP i.MX6UL HAB support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+// func hab_call(fn uint32, a0 uint32, a1 uint32, a2 uint32, a3 uint32) uint32
+TEXT ·hab_call(SB),$0-24
+	// HAB ROM API functions follow the ARM Procedure Call Standard and
+	// might be Thumb code, BL with a register operand assembles to BLX.
+	MOVW	fn+0(FP), R4
+	MOVW	a0+4(FP), R0
+	MOVW	a1+8(FP), R1
+	MOVW	a2+12(FP), R2
+	MOVW	a3+16(FP), R3
+
+	BL	(R4)
+
+	MOVW	R0, ret+20(FP)
+
+	RET
@@ -28,6 +28,7 @@ package imx6ul
 
 import (
 	"encoding/binary"
+	"errors"
 
 	"github.com/usbarmory/tamago/internal/reg"
 
@@ -35,15 +36,21 @@ import (
 	"github.com/usbarmory/tamago/arm/gic"
 	"github.com/usbarmory/tamago/arm/tzc380"
 
+	"github.com/usbarmory/tamago/soc/nxp/adc"
 	"github.com/usbarmory/tamago/soc/nxp/bee"
 	"github.com/usbarmory/tamago/soc/nxp/caam"
 	"github.com/usbarmory/tamago/soc/nxp/csu"
 	"github.com/usbarmory/tamago/soc/nxp/dcp"
+	"github.com/usbarmory/tamago/soc/nxp/ecspi"
 	"github.com/usbarmory/tamago/soc/nxp/enet"
+	"github.com/usbarmory/tamago/soc/nxp/epit"
 	"github.com/usbarmory/tamago/soc/nxp/gpio"
+	"github.com/usbarmory/tamago/soc/nxp/gpt"
 	"github.com/usbarmory/tamago/soc/nxp/i2c"
 	"github.com/usbarmory/tamago/soc/nxp/ocotp"
+	"github.com/usbarmory/tamago/soc/nxp/pwm"
 	"github.com/usbarmory/tamago/soc/nxp/rngb"
+	"github.com/usbarmory/tamago/soc/nxp/sdma"
 	"github.com/usbarmory/tamago/soc/nxp/snvs"
 	"github.com/usbarmory/tamago/soc/nxp/tempmon"
 	"github.com/usbarmory/tamago/soc/nxp/uart"
@@ -54,6 +61,14 @@ import (
 
 // Peripheral registers
 const (
+	// Analog-to-Digital Converters
+	ADC1_BASE = 0x02198000
+	ADC2_BASE = 0x0219c000
+
+	// Analog-to-Digital Converter interrupts
+	ADC1_IRQ = 32 + 100
+	ADC2_IRQ = 32 + 101
+
 	// Bus Encryption Engine (UL only)
 	BEE_BASE = 0x02044000
 
@@ -76,6 +91,46 @@ const (
 	GPIO4_BASE = 0x020a8000
 	GPIO5_BASE = 0x020ac000
 
+	// General Purpose I/O interrupts (signals 0-15, 16-31)
+	GPIO1_IRQ_LOW  = 32 + 66
+	GPIO1_IRQ_HIGH = 32 + 67
+	GPIO2_IRQ_LOW  = 32 + 68
+	GPIO2_IRQ_HIGH = 32 + 69
+	GPIO3_IRQ_LOW  = 32 + 70
+	GPIO3_IRQ_HIGH = 32 + 71
+	GPIO4_IRQ_LOW  = 32 + 72
+	GPIO4_IRQ_HIGH = 32 + 73
+	GPIO5_IRQ_LOW  = 32 + 74
+	GPIO5_IRQ_HIGH = 32 + 75
+
+	// Enhanced Configurable SPI
+	ECSPI1_BASE = 0x02008000
+	ECSPI2_BASE = 0x0200c000
+	ECSPI3_BASE = 0x02010000
+	ECSPI4_BASE = 0x02014000
+
+	// Enhanced Configurable SPI interrupts
+	ECSPI1_IRQ = 32 + 31
+	ECSPI2_IRQ = 32 + 32
+	ECSPI3_IRQ = 32 + 33
+	ECSPI4_IRQ = 32 + 34
+
+	// Enhanced Periodic Interrupt Timers
+	EPIT1_BASE = 0x020d0000
+	EPIT2_BASE = 0x020d4000
+
+	// Enhanced Periodic Interrupt Timer interrupts
+	EPIT1_IRQ = 32 + 88
+	EPIT2_IRQ = 32 + 89
+
+	// General Purpose Timers
+	GPT1_BASE = 0x02098000
+	GPT2_BASE = 0x020e8000
+
+	// General Purpose Timer interrupts
+	GPT1_IRQ = 32 + 55
+	GPT2_IRQ = 32 + 56
+
 	// Ethernet MAC (UL/ULL only)
 	ENET1_BASE = 0x02188000
 	ENET2_BASE = 0x020b4000
@@ -99,17 +154,45 @@ const (
 	OCRAM_START = 0x00900000
 	OCRAM_SIZE  = 0x20000
 
+	// Pulse Width Modulation
+	PWM1_BASE = 0x02080000
+	PWM2_BASE = 0x02084000
+	PWM3_BASE = 0x02088000
+	PWM4_BASE = 0x0208c000
+	PWM5_BASE = 0x020f0000
+	PWM6_BASE = 0x020f4000
+	PWM7_BASE = 0x020f8000
+	PWM8_BASE = 0x020fc000
+
+	// Pulse Width Modulation interrupts
+	PWM1_IRQ = 32 + 83
+	PWM2_IRQ = 32 + 84
+	PWM3_IRQ = 32 + 85
+	PWM4_IRQ = 32 + 86
+	PWM5_IRQ = 32 + 114
+	PWM6_IRQ = 32 + 115
+	PWM7_IRQ = 32 + 116
+	PWM8_IRQ = 32 + 117
+
 	// True Random Number Generator (ULL/ULZ only)
 	RNGB_BASE = 0x02284000
 
+	// Smart Direct Memory Access
+	SDMA_BASE = 0x020ec000
+	SDMA_IRQ  = 32 + 2
+
 	// Secure Non-Volatile Storage
-	SNVS_BASE = 0x020cc000
+	SNVS_BASE    = 0x020cc000
+	SNVS_IRQ     = 32 + 19
+	SNVS_SEC_IRQ = 32 + 20
 
 	// Temperature Monitor
 	TEMPMON_BASE = 0x020c8180
+	TEMPMON_IRQ  = 32 + 49
 
 	// TrustZone Address Space Controller
 	TZASC_BASE            = 0x021d0000
+	TZASC_IRQ             = 32 + 108
 	TZASC_BYPASS          = 0x020e4024
 	GPR1_TZASC1_BOOT_LOCK = 23
 
@@ -119,6 +202,12 @@ const (
 	UART3_BASE = 0x021ec000
 	UART4_BASE = 0x021f0000
 
+	// Serial port interrupts
+	UART1_IRQ = 32 + 26
+	UART2_IRQ = 32 + 27
+	UART3_IRQ = 32 + 28
+	UART4_IRQ = 32 + 29
+
 	// USB 2.0 controller
 	USB_ANALOG1_BASE   = 0x020c81a0
 	USB_ANALOG2_BASE   = 0x020c8200
@@ -152,6 +241,24 @@ var (
 	// ARM core
 	ARM = &arm.CPU{}
 
+	// Analog-to-Digital Converter 1
+	ADC1 = &adc.ADC{
+		Index: 1,
+		Base:  ADC1_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG8,
+		IRQ:   ADC1_IRQ,
+	}
+
+	// Analog-to-Digital Converter 2
+	ADC2 = &adc.ADC{
+		Index: 2,
+		Base:  ADC2_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG4,
+		IRQ:   ADC2_IRQ,
+	}
+
 	// Bus Encryption Engine (UL only)
 	BEE *bee.BEE
 
@@ -175,42 +282,132 @@ var (
 
 	// GPIO controller 1
 	GPIO1 = &gpio.GPIO{
-		Index: 1,
-		Base:  GPIO1_BASE,
-		CCGR:  CCM_CCGR1,
-		CG:    CCGRx_CG13,
+		Index:   1,
+		Base:    GPIO1_BASE,
+		CCGR:    CCM_CCGR1,
+		CG:      CCGRx_CG13,
+		IRQLow:  GPIO1_IRQ_LOW,
+		IRQHigh: GPIO1_IRQ_HIGH,
 	}
 
 	// GPIO controller 2
 	GPIO2 = &gpio.GPIO{
-		Index: 2,
-		Base:  GPIO2_BASE,
-		CCGR:  CCM_CCGR0,
-		CG:    CCGRx_CG15,
+		Index:   2,
+		Base:    GPIO2_BASE,
+		CCGR:    CCM_CCGR0,
+		CG:      CCGRx_CG15,
+		IRQLow:  GPIO2_IRQ_LOW,
+		IRQHigh: GPIO2_IRQ_HIGH,
 	}
 
 	// GPIO controller 3
 	GPIO3 = &gpio.GPIO{
-		Index: 3,
-		Base:  GPIO3_BASE,
-		CCGR:  CCM_CCGR2,
-		CG:    CCGRx_CG13,
+		Index:   3,
+		Base:    GPIO3_BASE,
+		CCGR:    CCM_CCGR2,
+		CG:      CCGRx_CG13,
+		IRQLow:  GPIO3_IRQ_LOW,
+		IRQHigh: GPIO3_IRQ_HIGH,
 	}
 
 	// GPIO controller 4
 	GPIO4 = &gpio.GPIO{
-		Index: 4,
-		Base:  GPIO4_BASE,
-		CCGR:  CCM_CCGR3,
-		CG:    CCGRx_CG6,
+		Index:   4,
+		Base:    GPIO4_BASE,
+		CCGR:    CCM_CCGR3,
+		CG:      CCGRx_CG6,
+		IRQLow:  GPIO4_IRQ_LOW,
+		IRQHigh: GPIO4_IRQ_HIGH,
 	}
 
 	// GPIO controller 5
 	GPIO5 = &gpio.GPIO{
-		Index: 5,
-		Base:  GPIO5_BASE,
+		Index:   5,
+		Base:    GPIO5_BASE,
+		CCGR:    CCM_CCGR1,
+		CG:      CCGRx_CG15,
+		IRQLow:  GPIO5_IRQ_LOW,
+		IRQHigh: GPIO5_IRQ_HIGH,
+	}
+
+	// ECSPI controller 1
+	ECSPI1 = &ecspi.ECSPI{
+		Index: 1,
+		Base:  ECSPI1_BASE,
 		CCGR:  CCM_CCGR1,
-		CG:    CCGRx_CG15,
+		CG:    CCGRx_CG0,
+		Clock: GetECSPIClock,
+		IRQ:   ECSPI1_IRQ,
+	}
+
+	// ECSPI controller 2
+	ECSPI2 = &ecspi.ECSPI{
+		Index: 2,
+		Base:  ECSPI2_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG1,
+		Clock: GetECSPIClock,
+		IRQ:   ECSPI2_IRQ,
+	}
+
+	// ECSPI controller 3
+	ECSPI3 = &ecspi.ECSPI{
+		Index: 3,
+		Base:  ECSPI3_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG2,
+		Clock: GetECSPIClock,
+		IRQ:   ECSPI3_IRQ,
+	}
+
+	// ECSPI controller 4
+	ECSPI4 = &ecspi.ECSPI{
+		Index: 4,
+		Base:  ECSPI4_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG3,
+		Clock: GetECSPIClock,
+		IRQ:   ECSPI4_IRQ,
+	}
+
+	// Enhanced Periodic Interrupt Timer 1
+	EPIT1 = &epit.EPIT{
+		Index: 1,
+		Base:  EPIT1_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG6,
+		Clock: GetHighFrequencyClock,
+		IRQ:   EPIT1_IRQ,
+	}
+
+	// Enhanced Periodic Interrupt Timer 2
+	EPIT2 = &epit.EPIT{
+		Index: 2,
+		Base:  EPIT2_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG7,
+		Clock: GetHighFrequencyClock,
+		IRQ:   EPIT2_IRQ,
+	}
+
+	// General Purpose Timer 1
+	GPT1 = &gpt.GPT{
+		Index: 1,
+		Base:  GPT1_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG10,
+		Clock: GetHighFrequencyClock,
+		IRQ:   GPT1_IRQ,
+	}
+
+	// General Purpose Timer 2
+	GPT2 = &gpt.GPT{
+		Index: 2,
+		Base:  GPT2_BASE,
+		CCGR:  CCM_CCGR0,
+		CG:    CCGRx_CG12,
+		Clock: GetHighFrequencyClock,
+		IRQ:   GPT2_IRQ,
 	}
 
 	// Ethernet MAC 1 (UL/ULL only)
@@ -223,6 +420,7 @@ var (
 		Base:  I2C1_BASE,
 		CCGR:  CCM_CCGR2,
 		CG:    CCGRx_CG3,
+		Clock: GetHighFrequencyClock,
 	}
 
 	// I2C controller 2
@@ -231,6 +429,7 @@ var (
 		Base:  I2C2_BASE,
 		CCGR:  CCM_CCGR2,
 		CG:    CCGRx_CG5,
+		Clock: GetHighFrequencyClock,
 	}
 
 	// On-Chip OTP Controller
@@ -239,26 +438,118 @@ var (
 		BankBase: OCOTP_BANK_BASE,
 		CCGR:     CCM_CCGR2,
 		CG:       CCGRx_CG6,
+		Clock:    GetPeripheralClock,
+	}
+
+	// PWM controller 1
+	PWM1 = &pwm.PWM{
+		Index: 1,
+		Base:  PWM1_BASE,
+		CCGR:  CCM_CCGR4,
+		CG:    CCGRx_CG8,
+		Clock: GetHighFrequencyClock,
+		IRQ:   PWM1_IRQ,
+	}
+
+	// PWM controller 2
+	PWM2 = &pwm.PWM{
+		Index: 2,
+		Base:  PWM2_BASE,
+		CCGR:  CCM_CCGR4,
+		CG:    CCGRx_CG9,
+		Clock: GetHighFrequencyClock,
+		IRQ:   PWM2_IRQ,
+	}
+
+	// PWM controller 3
+	PWM3 = &pwm.PWM{
+		Index: 3,
+		Base:  PWM3_BASE,
+		CCGR:  CCM_CCGR4,
+		CG:    CCGRx_CG10,
+		Clock: GetHighFrequencyClock,
+		IRQ:   PWM3_IRQ,
+	}
+
+	// PWM controller 4
+	PWM4 = &pwm.PWM{
+		Index: 4,
+		Base:  PWM4_BASE,
+		CCGR:  CCM_CCGR4,
+		CG:    CCGRx_CG11,
+		Clock: GetHighFrequencyClock,
+		IRQ:   PWM4_IRQ,
+	}
+
+	// PWM controller 5
+	PWM5 = &pwm.PWM{
+		Index: 5,
+		Base:  PWM5_BASE,
+		CCGR:  CCM_CCGR6,
+		CG:    CCGRx_CG13,
+		Clock: GetHighFrequencyClock,
+		IRQ:   PWM5_IRQ,
+	}
+
+	// PWM controller 6
+	PWM6 = &pwm.PWM{
+		Index: 6,
+		Base:  PWM6_BASE,
+		CCGR:  CCM_CCGR6,
+		CG:    CCGRx_CG14,
+		Clock: GetHighFrequencyClock,
+		IRQ:   PWM6_IRQ,
+	}
+
+	// PWM controller 7
+	PWM7 = &pwm.PWM{
+		Index: 7,
+		Base:  PWM7_BASE,
+		CCGR:  CCM_CCGR6,
+		CG:    CCGRx_CG15,
+		Clock: GetHighFrequencyClock,
+		IRQ:   PWM7_IRQ,
+	}
+
+	// PWM controller 8
+	PWM8 = &pwm.PWM{
+		Index: 8,
+		Base:  PWM8_BASE,
+		CCGR:  CCM_CCGR6,
+		CG:    CCGRx_CG8,
+		Clock: GetHighFrequencyClock,
+		IRQ:   PWM8_IRQ,
 	}
 
 	// True Random Number Generator (ULL/ULZ only)
 	RNGB *rngb.RNGB
 
+	// Smart Direct Memory Access
+	SDMA = &sdma.SDMA{
+		Base: SDMA_BASE,
+		CCGR: CCM_CCGR5,
+		CG:   CCGRx_CG3,
+		IRQ:  SDMA_IRQ,
+	}
+
 	// Secure Non-Volatile Storage
 	SNVS = &snvs.SNVS{
 		Base: SNVS_BASE,
 		CCGR: CCM_CCGR5,
 		CG:   CCGRx_CG9,
+		IRQ:  SNVS_SEC_IRQ,
 	}
 
 	// Temperature Monitor
 	TEMPMON = &tempmon.TEMPMON{
 		Base: TEMPMON_BASE,
+		IRQ:  TEMPMON_IRQ,
 	}
 
 	// TrustZone Address Space Controller
 	TZASC = &tzc380.TZASC{
 		Base:              TZASC_BASE,
+		IRQ:               TZASC_IRQ,
 		Bypass:            TZASC_BYPASS,
 		SecureBootLockReg: IOMUXC_GPR_GPR1,
 		SecureBootLockPos: GPR1_TZASC1_BOOT_LOCK,
@@ -271,6 +562,7 @@ var (
 		CCGR:  CCM_CCGR5,
 		CG:    CCGRx_CG12,
 		Clock: GetUARTClock,
+		IRQ:   UART1_IRQ,
 	}
 
 	// Serial port 2
@@ -280,6 +572,27 @@ var (
 		CCGR:  CCM_CCGR0,
 		CG:    CCGRx_CG14,
 		Clock: GetUARTClock,
+		IRQ:   UART2_IRQ,
+	}
+
+	// Serial port 3
+	UART3 = &uart.UART{
+		Index: 3,
+		Base:  UART3_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG5,
+		Clock: GetUARTClock,
+		IRQ:   UART3_IRQ,
+	}
+
+	// Serial port 4
+	UART4 = &uart.UART{
+		Index: 4,
+		Base:  UART4_BASE,
+		CCGR:  CCM_CCGR1,
+		CG:    CCGRx_CG12,
+		Clock: GetUARTClock,
+		IRQ:   UART4_IRQ,
 	}
 
 	// USB controller 1
@@ -369,8 +682,8 @@ func SiliconVersion() (sv, family, revMajor, revMinor uint32) {
 
 // UniqueID returns the NXP SoC Device Unique 64-bit ID.
 func UniqueID() (uid [8]byte) {
-	cfg0, _ := OCOTP.Read(0, 1)
-	cfg1, _ := OCOTP.Read(0, 2)
+	cfg0, _ := OCOTP.Read(ocotp.CFG_BANK, ocotp.CFG0)
+	cfg1, _ := OCOTP.Read(ocotp.CFG_BANK, ocotp.CFG1)
 
 	binary.LittleEndian.PutUint32(uid[0:4], cfg0)
 	binary.LittleEndian.PutUint32(uid[4:8], cfg1)
@@ -384,7 +697,7 @@ func Model() (model string) {
 	case IMX6UL:
 		model = "i.MX6UL"
 	case IMX6ULL:
-		cfg5, _ := OCOTP.Read(0, 6)
+		cfg5, _ := OCOTP.Read(ocotp.CFG_BANK, ocotp.CFG5)
 
 		if (cfg5>>6)&1 == 1 {
 			model = "i.MX6ULZ"
@@ -403,3 +716,17 @@ func Model() (model string) {
 func HAB() bool {
 	return SNVS.Available()
 }
+
+// Temperature returns the on-die temperature in degrees Celsius, as measured
+// by the Temperature Monitor (TEMPMON) using the calibration data fused in
+// OCOTP_ANA1.
+//
+// The TEMPMON is only initialized when running natively in Secure World,
+// otherwise an error is returned.
+func Temperature() (float64, error) {
+	if !Native || ARM.NonSecure() {
+		return 0, errors.New("temperature monitor unavailable")
+	}
+
+	return float64(TEMPMON.Read()), nil
+}
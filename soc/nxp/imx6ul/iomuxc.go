@@ -0,0 +1,176 @@
+// NXP i.MX6UL configuration and support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package imx6ul
+
+import (
+	"github.com/usbarmory/tamago/soc/nxp/iomuxc"
+)
+
+// IOMUXC pad control register offset, relative to the mux control register of
+// the same pad (e.g. CSI_DATA00 mux 0x020e01e4, pad 0x020e0470).
+const IOMUXC_SW_PAD_CTL_OFF = 0x28c
+
+// IOMUXC mux control registers
+const (
+	IOMUXC_SW_MUX_CTL_PAD_JTAG_MOD       = 0x020e0044
+	IOMUXC_SW_MUX_CTL_PAD_JTAG_TMS       = 0x020e0048
+	IOMUXC_SW_MUX_CTL_PAD_JTAG_TDO       = 0x020e004c
+	IOMUXC_SW_MUX_CTL_PAD_JTAG_TDI       = 0x020e0050
+	IOMUXC_SW_MUX_CTL_PAD_JTAG_TCK       = 0x020e0054
+	IOMUXC_SW_MUX_CTL_PAD_JTAG_TRST_B    = 0x020e0058
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO00     = 0x020e005c
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO01     = 0x020e0060
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO02     = 0x020e0064
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO03     = 0x020e0068
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO04     = 0x020e006c
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO05     = 0x020e0070
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO06     = 0x020e0074
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO07     = 0x020e0078
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO08     = 0x020e007c
+	IOMUXC_SW_MUX_CTL_PAD_GPIO1_IO09     = 0x020e0080
+	IOMUXC_SW_MUX_CTL_PAD_UART1_TX_DATA  = 0x020e0084
+	IOMUXC_SW_MUX_CTL_PAD_UART1_RX_DATA  = 0x020e0088
+	IOMUXC_SW_MUX_CTL_PAD_UART1_CTS_B    = 0x020e008c
+	IOMUXC_SW_MUX_CTL_PAD_UART1_RTS_B    = 0x020e0090
+	IOMUXC_SW_MUX_CTL_PAD_UART2_TX_DATA  = 0x020e0094
+	IOMUXC_SW_MUX_CTL_PAD_UART2_RX_DATA  = 0x020e0098
+	IOMUXC_SW_MUX_CTL_PAD_UART2_CTS_B    = 0x020e009c
+	IOMUXC_SW_MUX_CTL_PAD_UART2_RTS_B    = 0x020e00a0
+	IOMUXC_SW_MUX_CTL_PAD_UART3_TX_DATA  = 0x020e00a4
+	IOMUXC_SW_MUX_CTL_PAD_UART3_RX_DATA  = 0x020e00a8
+	IOMUXC_SW_MUX_CTL_PAD_UART3_CTS_B    = 0x020e00ac
+	IOMUXC_SW_MUX_CTL_PAD_UART3_RTS_B    = 0x020e00b0
+	IOMUXC_SW_MUX_CTL_PAD_UART4_TX_DATA  = 0x020e00b4
+	IOMUXC_SW_MUX_CTL_PAD_UART4_RX_DATA  = 0x020e00b8
+	IOMUXC_SW_MUX_CTL_PAD_UART5_TX_DATA  = 0x020e00bc
+	IOMUXC_SW_MUX_CTL_PAD_UART5_RX_DATA  = 0x020e00c0
+	IOMUXC_SW_MUX_CTL_PAD_ENET1_RX_DATA0 = 0x020e00c4
+	IOMUXC_SW_MUX_CTL_PAD_ENET1_RX_DATA1 = 0x020e00c8
+	IOMUXC_SW_MUX_CTL_PAD_ENET1_RX_EN    = 0x020e00cc
+	IOMUXC_SW_MUX_CTL_PAD_ENET1_TX_DATA0 = 0x020e00d0
+	IOMUXC_SW_MUX_CTL_PAD_ENET1_TX_DATA1 = 0x020e00d4
+	IOMUXC_SW_MUX_CTL_PAD_ENET1_TX_EN    = 0x020e00d8
+	IOMUXC_SW_MUX_CTL_PAD_ENET1_TX_CLK   = 0x020e00dc
+	IOMUXC_SW_MUX_CTL_PAD_ENET1_RX_ER    = 0x020e00e0
+	IOMUXC_SW_MUX_CTL_PAD_ENET2_RX_DATA0 = 0x020e00e4
+	IOMUXC_SW_MUX_CTL_PAD_ENET2_RX_DATA1 = 0x020e00e8
+	IOMUXC_SW_MUX_CTL_PAD_ENET2_RX_EN    = 0x020e00ec
+	IOMUXC_SW_MUX_CTL_PAD_ENET2_TX_DATA0 = 0x020e00f0
+	IOMUXC_SW_MUX_CTL_PAD_ENET2_TX_DATA1 = 0x020e00f4
+	IOMUXC_SW_MUX_CTL_PAD_ENET2_TX_EN    = 0x020e00f8
+	IOMUXC_SW_MUX_CTL_PAD_ENET2_TX_CLK   = 0x020e00fc
+	IOMUXC_SW_MUX_CTL_PAD_ENET2_RX_ER    = 0x020e0100
+	IOMUXC_SW_MUX_CTL_PAD_LCD_CLK        = 0x020e0104
+	IOMUXC_SW_MUX_CTL_PAD_LCD_ENABLE     = 0x020e0108
+	IOMUXC_SW_MUX_CTL_PAD_LCD_HSYNC      = 0x020e010c
+	IOMUXC_SW_MUX_CTL_PAD_LCD_VSYNC      = 0x020e0110
+	IOMUXC_SW_MUX_CTL_PAD_LCD_RESET      = 0x020e0114
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA00     = 0x020e0118
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA01     = 0x020e011c
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA02     = 0x020e0120
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA03     = 0x020e0124
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA04     = 0x020e0128
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA05     = 0x020e012c
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA06     = 0x020e0130
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA07     = 0x020e0134
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA08     = 0x020e0138
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA09     = 0x020e013c
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA10     = 0x020e0140
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA11     = 0x020e0144
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA12     = 0x020e0148
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA13     = 0x020e014c
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA14     = 0x020e0150
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA15     = 0x020e0154
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA16     = 0x020e0158
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA17     = 0x020e015c
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA18     = 0x020e0160
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA19     = 0x020e0164
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA20     = 0x020e0168
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA21     = 0x020e016c
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA22     = 0x020e0170
+	IOMUXC_SW_MUX_CTL_PAD_LCD_DATA23     = 0x020e0174
+	IOMUXC_SW_MUX_CTL_PAD_NAND_RE_B      = 0x020e0178
+	IOMUXC_SW_MUX_CTL_PAD_NAND_WE_B      = 0x020e017c
+	IOMUXC_SW_MUX_CTL_PAD_NAND_DATA00    = 0x020e0180
+	IOMUXC_SW_MUX_CTL_PAD_NAND_DATA01    = 0x020e0184
+	IOMUXC_SW_MUX_CTL_PAD_NAND_DATA02    = 0x020e0188
+	IOMUXC_SW_MUX_CTL_PAD_NAND_DATA03    = 0x020e018c
+	IOMUXC_SW_MUX_CTL_PAD_NAND_DATA04    = 0x020e0190
+	IOMUXC_SW_MUX_CTL_PAD_NAND_DATA05    = 0x020e0194
+	IOMUXC_SW_MUX_CTL_PAD_NAND_DATA06    = 0x020e0198
+	IOMUXC_SW_MUX_CTL_PAD_NAND_DATA07    = 0x020e019c
+	IOMUXC_SW_MUX_CTL_PAD_NAND_ALE       = 0x020e01a0
+	IOMUXC_SW_MUX_CTL_PAD_NAND_WP_B      = 0x020e01a4
+	IOMUXC_SW_MUX_CTL_PAD_NAND_READY_B   = 0x020e01a8
+	IOMUXC_SW_MUX_CTL_PAD_NAND_CE0_B     = 0x020e01ac
+	IOMUXC_SW_MUX_CTL_PAD_NAND_CE1_B     = 0x020e01b0
+	IOMUXC_SW_MUX_CTL_PAD_NAND_CLE       = 0x020e01b4
+	IOMUXC_SW_MUX_CTL_PAD_NAND_DQS       = 0x020e01b8
+	IOMUXC_SW_MUX_CTL_PAD_SD1_CMD        = 0x020e01bc
+	IOMUXC_SW_MUX_CTL_PAD_SD1_CLK        = 0x020e01c0
+	IOMUXC_SW_MUX_CTL_PAD_SD1_DATA0      = 0x020e01c4
+	IOMUXC_SW_MUX_CTL_PAD_SD1_DATA1      = 0x020e01c8
+	IOMUXC_SW_MUX_CTL_PAD_SD1_DATA2      = 0x020e01cc
+	IOMUXC_SW_MUX_CTL_PAD_SD1_DATA3      = 0x020e01d0
+	IOMUXC_SW_MUX_CTL_PAD_CSI_MCLK       = 0x020e01d4
+	IOMUXC_SW_MUX_CTL_PAD_CSI_PIXCLK     = 0x020e01d8
+	IOMUXC_SW_MUX_CTL_PAD_CSI_VSYNC      = 0x020e01dc
+	IOMUXC_SW_MUX_CTL_PAD_CSI_HSYNC      = 0x020e01e0
+	IOMUXC_SW_MUX_CTL_PAD_CSI_DATA00     = 0x020e01e4
+	IOMUXC_SW_MUX_CTL_PAD_CSI_DATA01     = 0x020e01e8
+	IOMUXC_SW_MUX_CTL_PAD_CSI_DATA02     = 0x020e01ec
+	IOMUXC_SW_MUX_CTL_PAD_CSI_DATA03     = 0x020e01f0
+	IOMUXC_SW_MUX_CTL_PAD_CSI_DATA04     = 0x020e01f4
+	IOMUXC_SW_MUX_CTL_PAD_CSI_DATA05     = 0x020e01f8
+	IOMUXC_SW_MUX_CTL_PAD_CSI_DATA06     = 0x020e01fc
+	IOMUXC_SW_MUX_CTL_PAD_CSI_DATA07     = 0x020e0200
+)
+
+// IOMUXC daisy chain (input select) registers
+const (
+	IOMUXC_ENET1_REF_CLK1_SELECT_INPUT  = 0x020e0574
+	IOMUXC_ENET1_MAC0_MDIO_SELECT_INPUT = 0x020e0578
+	IOMUXC_ENET2_REF_CLK2_SELECT_INPUT  = 0x020e057c
+	IOMUXC_ENET2_MAC0_MDIO_SELECT_INPUT = 0x020e0580
+
+	IOMUXC_I2C1_SCL_SELECT_INPUT = 0x020e05a4
+	IOMUXC_I2C1_SDA_SELECT_INPUT = 0x020e05a8
+	IOMUXC_I2C2_SCL_SELECT_INPUT = 0x020e05ac
+	IOMUXC_I2C2_SDA_SELECT_INPUT = 0x020e05b0
+
+	IOMUXC_UART1_RTS_B_SELECT_INPUT   = 0x020e0620
+	IOMUXC_UART1_RX_DATA_SELECT_INPUT = 0x020e0624
+	IOMUXC_UART2_RTS_B_SELECT_INPUT   = 0x020e0628
+	IOMUXC_UART2_RX_DATA_SELECT_INPUT = 0x020e062c
+	IOMUXC_UART3_RTS_B_SELECT_INPUT   = 0x020e0630
+	IOMUXC_UART3_RX_DATA_SELECT_INPUT = 0x020e0634
+	IOMUXC_UART4_RTS_B_SELECT_INPUT   = 0x020e0638
+	IOMUXC_UART4_RX_DATA_SELECT_INPUT = 0x020e063c
+	IOMUXC_UART5_RTS_B_SELECT_INPUT   = 0x020e0640
+	IOMUXC_UART5_RX_DATA_SELECT_INPUT = 0x020e0644
+
+	IOMUXC_USDHC1_WP_SELECT_INPUT = 0x020e066c
+	IOMUXC_USDHC2_WP_SELECT_INPUT = 0x020e069c
+)
+
+// Pad returns the iomuxc.Pad instance for the argument mux control register
+// (see IOMUXC_SW_MUX_CTL_PAD_* constants) and optional daisy chain register
+// (see IOMUXC_*_SELECT_INPUT constants, 0 if not required), the pad control
+// register is derived from the mux one.
+//
+// The pad iomux mode and input selection are configured with the Mode() and
+// Select() functions of the returned instance.
+func Pad(mux uint32, daisy uint32) *iomuxc.Pad {
+	return &iomuxc.Pad{
+		Mux:   mux,
+		Pad:   mux + IOMUXC_SW_PAD_CTL_OFF,
+		Daisy: daisy,
+	}
+}
@@ -0,0 +1,117 @@
+// NXP i.MX6UL low power mode support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package imx6ul
+
+import (
+	"errors"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// Low power mode registers
+const (
+	CCM_CLPCR                = 0x020c4054
+	CLPCR_VSTBY              = 8
+	CLPCR_SBYOS              = 6
+	CLPCR_ARM_CLK_DIS_ON_LPM = 5
+	CLPCR_LPM                = 0
+
+	GPC_IMR1 = 0x020dc008
+
+	GPR1_GINT = 12
+)
+
+// LPMode represents a CCM low power mode (CLPCR LPM).
+type LPMode uint32
+
+// Low power modes
+const (
+	// Run mode
+	LPM_RUN LPMode = 0b00
+	// Wait mode, the ARM core clock is gated while peripherals are kept
+	// running.
+	LPM_WAIT LPMode = 0b01
+	// Stop mode, all clocks are gated and PLLs are powered down, DRAM is
+	// kept in self-refresh.
+	LPM_STOP LPMode = 0b10
+)
+
+// first interrupt ID routed through the General Power Controller
+const gpcFirstIRQ = 32
+
+// SetWakeupSource configures whether the argument interrupt ID can wake up
+// the SoC from low power modes, by masking or unmasking it in the General
+// Power Controller (GPC) interrupt mask registers.
+//
+// All interrupts are wake-up sources at reset, the GIC and peripheral must be
+// separately configured to generate the interrupt (e.g. GPIOx_IRQ_LOW for GPIO
+// interrupts or SNVS_IRQ for the SNVS RTC alarm).
+func SetWakeupSource(id int, enable bool) error {
+	if id < gpcFirstIRQ || id >= gpcFirstIRQ+4*32 {
+		return errors.New("invalid interrupt ID")
+	}
+
+	n := id - gpcFirstIRQ
+	imr := uint32(GPC_IMR1 + 4*(n/32))
+
+	reg.SetTo(imr, n%32, !enable)
+
+	return nil
+}
+
+// EnterLowPower configures the argument low power mode and executes a Wait
+// For Interrupt instruction, returning only on wake-up (see
+// SetWakeupSource()).
+//
+// The low power mode is entered by the CCM upon WFI execution, peripheral
+// clocks are gated according to their CCGR settings (clock gates configured
+// as 0b11 are kept running in wait mode and gated in stop mode). On return the
+// original low power configuration is restored, reverting the CCM to run
+// mode.
+//
+// The function is only available when running natively in Secure World.
+func EnterLowPower(mode LPMode) (err error) {
+	if !Native || ARM.NonSecure() {
+		return errors.New("low power modes unavailable")
+	}
+
+	switch mode {
+	case LPM_RUN:
+		return
+	case LPM_WAIT, LPM_STOP:
+	default:
+		return errors.New("invalid low power mode")
+	}
+
+	clpcr := reg.Read(CCM_CLPCR)
+	defer reg.Write(CCM_CLPCR, clpcr)
+
+	val := clpcr &^ (0b11<<CLPCR_LPM | 1<<CLPCR_VSTBY | 1<<CLPCR_SBYOS)
+	val |= uint32(mode)<<CLPCR_LPM | 1<<CLPCR_ARM_CLK_DIS_ON_LPM
+
+	// ERR007265: CCM: When improper low-power sequence is used, the SoC
+	// enters low power mode before the ARM core executes WFI.
+	//
+	// The workaround keeps IRQ #32 (IOMUXC) pending, unmasking it in the
+	// GPC only while the low power mode is set.
+	reg.Set(IOMUXC_GPR_GPR1, GPR1_GINT)
+	imr1 := reg.Read(GPC_IMR1)
+
+	reg.Clear(GPC_IMR1, 0)
+	reg.Write(CCM_CLPCR, val)
+	reg.Write(GPC_IMR1, imr1|1)
+
+	ARM.WaitForInterrupt()
+
+	reg.Write(GPC_IMR1, imr1)
+	reg.Clear(IOMUXC_GPR_GPR1, GPR1_GINT)
+
+	return
+}
@@ -0,0 +1,106 @@
+// NXP i.MX6UL DRAM support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package imx6ul
+
+import (
+	"github.com/usbarmory/tamago/bits"
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// MMDC registers
+// (Multi Mode DDR Controller, IMX6ULLRM).
+const (
+	MMDC_P0_BASE = 0x021b0000
+
+	MMDC_MDCTL  = MMDC_P0_BASE + 0x000
+	MDCTL_SDE_0 = 31
+	MDCTL_SDE_1 = 30
+	MDCTL_ROW   = 24
+	MDCTL_COL   = 20
+	MDCTL_DSIZ  = 16
+
+	MMDC_MDMISC       = MMDC_P0_BASE + 0x018
+	MDMISC_DDR_4_BANK = 5
+)
+
+// DefaultRAMSize is the DRAM size returned by RAMSize() when the MMDC
+// configuration cannot be used, it matches the smallest supported board
+// configuration.
+const DefaultRAMSize = 0x10000000 // 256 MB
+
+// RAMSize returns the size of the populated DRAM, computed from the Multi
+// Mode DDR Controller (MMDC) chip select, row, column, bank and data width
+// configuration.
+//
+// DefaultRAMSize is returned if the MMDC has not been configured yet (e.g.
+// when running from OCRAM before DRAM initialization) or holds reserved
+// values.
+func RAMSize() uint {
+	mdctl := reg.Read(MMDC_MDCTL)
+	mdmisc := reg.Read(MMDC_MDMISC)
+
+	if bits.Get(&mdctl, MDCTL_SDE_0, 1) == 0 {
+		return DefaultRAMSize
+	}
+
+	var row, col, banks, width, cs uint
+
+	switch r := bits.Get(&mdctl, MDCTL_ROW, 0b111); r {
+	case 0b111:
+		return DefaultRAMSize
+	default:
+		row = 11 + uint(r)
+	}
+
+	switch bits.Get(&mdctl, MDCTL_COL, 0b111) {
+	case 0b000:
+		col = 9
+	case 0b001:
+		col = 10
+	case 0b010:
+		col = 11
+	case 0b011:
+		col = 8
+	case 0b100:
+		col = 12
+	default:
+		return DefaultRAMSize
+	}
+
+	switch bits.Get(&mdctl, MDCTL_DSIZ, 0b11) {
+	case 0b00:
+		width = 2
+	case 0b01:
+		width = 4
+	default:
+		return DefaultRAMSize
+	}
+
+	if bits.Get(&mdmisc, MDMISC_DDR_4_BANK, 1) == 1 {
+		banks = 4
+	} else {
+		banks = 8
+	}
+
+	if bits.Get(&mdctl, MDCTL_SDE_1, 1) == 1 {
+		cs = 2
+	} else {
+		cs = 1
+	}
+
+	size := uint64(1<<(row+col)) * uint64(banks*width*cs)
+
+	// the DDR address space is limited to 2GB
+	if size > 0x80000000 {
+		return DefaultRAMSize
+	}
+
+	return uint(size)
+}
@@ -25,10 +25,17 @@ const (
 // Note that only the SoC itself is guaranteed to restart as, depending on the
 // board hardware layout, the system might remain powered (which might not be
 // desirable). See respective board packages for cold reset options.
+//
+// The function does not return.
 func Reset() {
 	// enable warm reset
 	reg.Clear(SRC_SCR, SCR_WARM_RESET_ENABLE)
 
 	// assert software reset
 	WDOG1.SoftwareReset()
+
+	// wait for reset assertion
+	for {
+		ARM.WaitForInterrupt()
+	}
 }
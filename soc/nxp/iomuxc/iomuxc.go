@@ -15,6 +15,8 @@
 package iomuxc
 
 import (
+	"fmt"
+
 	"github.com/usbarmory/tamago/internal/reg"
 )
 
@@ -53,6 +55,15 @@ const (
 )
 
 // Pad instance.
+//
+// Each SoC pad is controlled through a mux (IOMUXC_SW_MUX_CTL_PAD_*) and a pad
+// (IOMUXC_SW_PAD_CTL_PAD_*) register, which are named after the pad and not
+// after the GPIO signal which it can be routed to. The pad to GPIO mapping
+// (e.g. pad CSI_DATA00 to GPIO4_IO21 in ALT5 mode) and the register offsets
+// are listed in the IOMUXC chapter of the SoC reference manual, on the
+// i.MX6UL the pad control register of a muxed pad is located at
+// IOMUXC_SW_PAD_CTL_PAD_<pad> = mux register + 0x28c (e.g. CSI_DATA00 mux
+// 0x020e01e4, pad 0x020e0470).
 type Pad struct {
 	// Mux register (e.g. IOMUXC_SW_MUX_CTL_PAD_*)
 	Mux uint32
@@ -97,3 +108,62 @@ func (pad *Pad) Select(input uint32) {
 
 	reg.Write(pad.Daisy, input)
 }
+
+// PullUp enables the pad pull-up resistor with the argument value in ohms
+// (22000, 47000 or 100000).
+func (pad *Pad) PullUp(ohms int) (err error) {
+	var pus uint32
+
+	switch ohms {
+	case 22000:
+		pus = SW_PAD_CTL_PUS_PULL_UP_22K
+	case 47000:
+		pus = SW_PAD_CTL_PUS_PULL_UP_47K
+	case 100000:
+		pus = SW_PAD_CTL_PUS_PULL_UP_100K
+	default:
+		return fmt.Errorf("unsupported pull-up value %d", ohms)
+	}
+
+	pad.pull(pus)
+
+	return
+}
+
+// PullDown enables the pad pull-down resistor with the argument value in ohms
+// (100000).
+func (pad *Pad) PullDown(ohms int) (err error) {
+	if ohms != 100000 {
+		return fmt.Errorf("unsupported pull-down value %d", ohms)
+	}
+
+	pad.pull(SW_PAD_CTL_PUS_PULL_DOWN_100K)
+
+	return
+}
+
+// DisablePull disables the pad pull-up/pull-down resistor and keeper.
+func (pad *Pad) DisablePull() {
+	reg.Clear(pad.Pad, SW_PAD_CTL_PKE)
+}
+
+func (pad *Pad) pull(pus uint32) {
+	reg.SetN(pad.Pad, SW_PAD_CTL_PUS, 0b11, pus)
+	// select pull over keeper
+	reg.Set(pad.Pad, SW_PAD_CTL_PUE)
+	// enable pull/keeper
+	reg.Set(pad.Pad, SW_PAD_CTL_PKE)
+}
+
+// DriveStrength configures the pad output driver strength, the argument level
+// must be within 0 (output driver disabled) and 7 (R0/7, strongest), see
+// SW_PAD_CTL_DSE_* constants.
+func (pad *Pad) DriveStrength(level int) (err error) {
+	if level < SW_PAD_CTL_DSE_OUTPUT_DRIVER_DISABLED || level > SW_PAD_CTL_DSE_2_R0_7 {
+		return fmt.Errorf("invalid drive strength %d", level)
+	}
+
+	reg.SetN(pad.Pad, SW_PAD_CTL_DSE, 0b111, uint32(level))
+
+	return
+}
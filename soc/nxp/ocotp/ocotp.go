@@ -25,10 +25,15 @@
 package ocotp
 
 import (
+	"crypto/subtle"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"net"
 	"sync"
 	"time"
 
+	"github.com/usbarmory/tamago/bits"
 	"github.com/usbarmory/tamago/internal/reg"
 )
 
@@ -43,7 +48,52 @@ const (
 	CTRL_ADDR           = 0
 
 	OCOTP_CTRL_CLR = 0x0008
-	OCOTP_DATA     = 0x0020
+
+	OCOTP_TIMING       = 0x0010
+	TIMING_WAIT        = 22
+	TIMING_STROBE_READ = 16
+	TIMING_RELAX       = 12
+	TIMING_STROBE_PROG = 0
+
+	OCOTP_DATA = 0x0020
+)
+
+// MAC address fuses location
+// (p2388, 37.5 OCOTP Memory Map/Register Definition, IMX6ULLRM).
+const (
+	MAC_BANK = 4
+	MAC0     = 2
+	MAC1     = 3
+	MAC2     = 4
+)
+
+// Fuse locations
+// (Fusemap, IMX6ULLRM).
+const (
+	CFG_BANK = 0
+	LOCK     = 0
+	CFG0     = 1
+	CFG1     = 2
+	CFG2     = 3
+	CFG3     = 4
+	CFG4     = 5
+	CFG5     = 6
+	CFG6     = 7
+
+	SRK_BANK  = 3
+	SRK0      = 0
+	SRK_WORDS = 8
+
+	SJC_BANK  = 4
+	SJC_RESP0 = 0
+	SJC_RESP1 = 1
+)
+
+// OCOTP_CFG5 fields
+const (
+	CFG5_JTAG_SMODE  = 22
+	CFG5_SJC_DISABLE = 20
+	CFG5_SEC_CONFIG  = 1
 )
 
 // Configuration constants
@@ -54,6 +104,11 @@ const (
 	BankSize = 8
 	// Timeout is the default timeout for OCOTP operations.
 	Timeout = 10 * time.Millisecond
+
+	// fuse programming timings (see OCOTP_TIMING)
+	relaxNs      = 20
+	strobeReadNs = 37
+	strobeProgUs = 10
 )
 
 type OCOTP struct {
@@ -69,12 +124,16 @@ type OCOTP struct {
 	CCGR uint32
 	// Clock gate
 	CG int
+	// Clock retrieval function (IPG_CLK_ROOT), when defined the fuse
+	// programming timings are configured accordingly by Blow()
+	Clock func() uint32
 	// Timeout for OCOTP controller operations
 	Timeout time.Duration
 
 	// control registers
 	ctrl     uint32
 	ctrl_clr uint32
+	timing   uint32
 	data     uint32
 }
 
@@ -93,6 +152,7 @@ func (hw *OCOTP) Init() {
 
 	hw.ctrl = hw.Base + OCOTP_CTRL
 	hw.ctrl_clr = hw.Base + OCOTP_CTRL_CLR
+	hw.timing = hw.Base + OCOTP_TIMING
 	hw.data = hw.Base + OCOTP_DATA
 
 	// enable clock
@@ -123,9 +183,38 @@ func (hw *OCOTP) Read(bank int, word int) (value uint32, err error) {
 	return
 }
 
+func (hw *OCOTP) valid(bank int, word int) bool {
+	return bank >= 0 && bank < hw.Banks && word >= 0 && word < BankSize
+}
+
+// setTiming configures the fuse programming timings for the IPG_CLK_ROOT
+// frequency.
+func (hw *OCOTP) setTiming() {
+	if hw.Clock == nil {
+		// The default OCOTP_TIMING values work for the default
+		// IPG_CLK_ROOT frequency of 66 MHz.
+		return
+	}
+
+	hz := uint64(hw.Clock())
+
+	relax := (hz*relaxNs+1e9-1)/1e9 - 1
+	strobeRead := (hz*strobeReadNs+1e9-1)/1e9 + 2*(relax+1) - 1
+	strobeProg := (hz*strobeProgUs+1e6/2)/1e6 + 2*(relax+1) - 1
+
+	timing := reg.Read(hw.timing)
+	bits.SetN(&timing, TIMING_STROBE_PROG, 0xfff, uint32(strobeProg))
+	bits.SetN(&timing, TIMING_RELAX, 0xf, uint32(relax))
+	bits.SetN(&timing, TIMING_STROBE_READ, 0x3f, uint32(strobeRead))
+	reg.Write(hw.timing, timing)
+}
+
 // Blow fuses a value in the argument bank and word location.
 // (p2384, 37.3.1.3 Fuse and Shadow Register Writes, IMX6ULLRM).
 //
+// An error is returned, and no fuse is blown, if the location is write
+// locked.
+//
 // WARNING: Fusing SoC OTPs is an **irreversible** action that permanently
 // fuses values on the device. This means that any errors in the process, or
 // lost fused data such as cryptographic key material, might result in a
@@ -133,16 +222,22 @@ func (hw *OCOTP) Read(bank int, word int) (value uint32, err error) {
 //
 // The use of this function is therefore **at your own risk**.
 func (hw *OCOTP) Blow(bank int, word int, value uint32) (err error) {
+	if !hw.valid(bank, word) {
+		return errors.New("invalid argument")
+	}
+
 	hw.Lock()
 	defer hw.Unlock()
 
+	if hw.ctrl == 0 {
+		return errors.New("OCOTP controller is not initialized")
+	}
+
 	if !reg.WaitFor(Timeout, hw.ctrl, CTRL_BUSY, 1, 0) {
 		return errors.New("OCOTP controller busy")
 	}
 
-	// We do not configure the OCOTP_TIMING register. Timings depend on
-	// IPG_CLK_ROOT frequency. Default values work for default frequency of
-	// 66 MHz.
+	hw.setTiming()
 
 	// p2393, OCOTP_CTRLn field descriptions, IMX6ULLRM
 
@@ -157,7 +252,8 @@ func (hw *OCOTP) Blow(bank int, word int, value uint32) (err error) {
 	reg.Write(hw.data, value)
 
 	if err = hw.checkOp(); err != nil {
-		return
+		// writes to locked locations are rejected with an error
+		return fmt.Errorf("could not blow bank %d word %d (locked?), %v", bank, word, err)
 	}
 
 	// 2385, 37.3.1.4 Write Postamble, IMX6ULLRM
@@ -179,6 +275,20 @@ func (hw *OCOTP) checkOp() (err error) {
 	return
 }
 
+// Reload reloads the memory mapped shadow registers from the OTP fuse banks,
+// so that subsequent Read() invocations reflect the fuses state (e.g. after
+// fuses have been blown by another boot stage).
+func (hw *OCOTP) Reload() (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.ctrl == 0 {
+		return errors.New("OCOTP controller is not initialized")
+	}
+
+	return hw.shadowReload()
+}
+
 // shadowReload reloads memory mapped shadow registers from OTP fuse banks
 // (p2383, 37.3.1.1 Shadow Register Reload, IMX6ULLRM).
 func (hw *OCOTP) shadowReload() (err error) {
@@ -193,3 +303,130 @@ func (hw *OCOTP) shadowReload() (err error) {
 
 	return hw.checkOp()
 }
+
+// MAC returns the Ethernet MAC address, for the argument ENET controller
+// index (1 or 2), fused in the i.MX6UL/i.MX6ULL OCOTP_MAC0, OCOTP_MAC1 and
+// OCOTP_MAC fuses.
+//
+// The ENET1 MAC address is stored, in big endian order, across OCOTP_MAC1
+// bits [15:0] and OCOTP_MAC0, the ENET2 MAC address across OCOTP_MAC and
+// OCOTP_MAC1 bits [31:16].
+func (hw *OCOTP) MAC(index int) (mac net.HardwareAddr, err error) {
+	var fuses [3]uint32
+
+	if index != 1 && index != 2 {
+		return nil, errors.New("invalid ENET index")
+	}
+
+	for i, word := range []int{MAC0, MAC1, MAC2} {
+		if fuses[i], err = hw.Read(MAC_BANK, word); err != nil {
+			return
+		}
+	}
+
+	return macAddress(index, fuses[0], fuses[1], fuses[2]), nil
+}
+
+// macAddress assembles the argument ENET controller MAC address from the
+// OCOTP_MAC0, OCOTP_MAC1 and OCOTP_MAC fuse words (see MAC()).
+func macAddress(index int, mac0 uint32, mac1 uint32, mac2 uint32) (mac net.HardwareAddr) {
+	mac = make(net.HardwareAddr, 6)
+
+	switch index {
+	case 1:
+		binary.BigEndian.PutUint16(mac[0:2], uint16(mac1))
+		binary.BigEndian.PutUint32(mac[2:6], mac0)
+	case 2:
+		binary.BigEndian.PutUint32(mac[0:4], mac2)
+		binary.BigEndian.PutUint16(mac[4:6], uint16(mac1>>16))
+	default:
+		return nil
+	}
+
+	return
+}
+
+// BootCfg returns the boot configuration fuses (OCOTP_CFG4), holding
+// BOOT_CFG1 in bits [7:0], BOOT_CFG2 in bits [15:8], BOOT_CFG3 in bits
+// [23:16] and BOOT_CFG4 in bits [31:24].
+func (hw *OCOTP) BootCfg() (cfg uint32, err error) {
+	return hw.Read(CFG_BANK, CFG4)
+}
+
+// SRKHash returns the 256-bit Super Root Key hash fused in OCOTP_SRK0 to
+// OCOTP_SRK7.
+//
+// The hash is assembled with each word in little endian order, matching the
+// layout of the SRK fuse table file generated by the NXP Code Signing Tool.
+func (hw *OCOTP) SRKHash() (hash []byte, err error) {
+	var val uint32
+
+	hash = make([]byte, SRK_WORDS*WordSize)
+
+	for i := 0; i < SRK_WORDS; i++ {
+		if val, err = hw.Read(SRK_BANK, SRK0+i); err != nil {
+			return nil, err
+		}
+
+		binary.LittleEndian.PutUint32(hash[i*WordSize:], val)
+	}
+
+	return
+}
+
+// SRKHashMatches returns whether the fused Super Root Key hash matches the
+// argument one (e.g. the SRK table hash computed at build time), a read
+// error results in a mismatch.
+func (hw *OCOTP) SRKHashMatches(expected [32]byte) bool {
+	hash, err := hw.SRKHash()
+
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(hash, expected[:]) == 1
+}
+
+// SJC returns the Secure JTAG Controller configuration fused in OCOTP_CFG5,
+// the JTAG security mode (JTAG_SMODE) and whether the SJC is disabled
+// (SJC_DISABLE).
+func (hw *OCOTP) SJC() (mode uint32, disabled bool, err error) {
+	cfg5, err := hw.Read(CFG_BANK, CFG5)
+
+	if err != nil {
+		return
+	}
+
+	mode = bits.Get(&cfg5, CFG5_JTAG_SMODE, 0b11)
+	disabled = bits.Get(&cfg5, CFG5_SJC_DISABLE, 1) == 1
+
+	return
+}
+
+// SJCResponse returns the 56-bit Secure JTAG Controller response key, fused
+// across OCOTP_SJC_RESP0 (bits [31:0]) and OCOTP_SJC_RESP1 (bits [55:32]).
+func (hw *OCOTP) SJCResponse() (resp uint64, err error) {
+	var lo, hi uint32
+
+	if lo, err = hw.Read(SJC_BANK, SJC_RESP0); err != nil {
+		return
+	}
+
+	if hi, err = hw.Read(SJC_BANK, SJC_RESP1); err != nil {
+		return
+	}
+
+	return uint64(hi&0xffffff)<<32 | uint64(lo), nil
+}
+
+// SecConfig returns whether the SoC security configuration is closed
+// (SEC_CONFIG[1] fused in OCOTP_CFG5).
+func (hw *OCOTP) SecConfig() (closed bool, err error) {
+	cfg5, err := hw.Read(CFG_BANK, CFG5)
+
+	if err != nil {
+		return
+	}
+
+	return bits.Get(&cfg5, CFG5_SEC_CONFIG, 1) == 1, nil
+}
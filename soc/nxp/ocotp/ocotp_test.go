@@ -0,0 +1,48 @@
+// NXP i.MX6 On-Chip OTP Controller (OCOTP_CTRL) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package ocotp
+
+import (
+	"testing"
+)
+
+func TestMACAddress(t *testing.T) {
+	const (
+		mac0 = 0x33445566
+		mac1 = 0xaabb1122
+		mac2 = 0xccddeeff
+	)
+
+	tests := []struct {
+		index int
+		mac   string
+	}{
+		{1, "11:22:33:44:55:66"},
+		{2, "cc:dd:ee:ff:aa:bb"},
+		{0, ""},
+		{3, ""},
+	}
+
+	for _, test := range tests {
+		mac := macAddress(test.index, mac0, mac1, mac2)
+
+		if test.mac == "" {
+			if mac != nil {
+				t.Errorf("ENET%d: expected nil, got %s", test.index, mac)
+			}
+
+			continue
+		}
+
+		if mac.String() != test.mac {
+			t.Errorf("ENET%d: expected %s, got %s", test.index, test.mac, mac)
+		}
+	}
+}
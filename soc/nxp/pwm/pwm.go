@@ -0,0 +1,283 @@
+// NXP Pulse Width Modulation (PWM) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+// Package pwm implements a driver for NXP Pulse Width Modulation (PWM)
+// controllers adopting the following reference specifications:
+//   - IMX6ULLRM - i.MX 6ULL Applications Processor Reference Manual - Rev 1 2017/11
+//
+// This package is only meant to be used with `GOOS=tamago GOARCH=arm` as
+// supported by the TamaGo framework for bare metal Go on ARM SoCs, see
+// https://github.com/usbarmory/tamago.
+package pwm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/usbarmory/tamago/bits"
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// PWM registers
+// (PWM Memory Map/Register Definition, IMX6ULLRM).
+const (
+	PWMx_PWMCR   = 0x00
+	PWMCR_STOPEN = 25
+	PWMCR_DOZEN  = 24
+	PWMCR_WAITEN = 23
+	PWMCR_DBGEN  = 22
+	PWMCR_POUTC  = 18
+	PWMCR_CLKSRC = 16
+	PWMCR_PRESC  = 4
+	PWMCR_SWR    = 3
+	PWMCR_EN     = 0
+
+	PWMx_PWMSR   = 0x04
+	PWMSR_FWE    = 6
+	PWMSR_CMP    = 5
+	PWMSR_ROV    = 4
+	PWMSR_FE     = 3
+	PWMSR_FIFOAV = 0
+
+	PWMx_PWMIR  = 0x08
+	PWMx_PWMSAR = 0x0c
+	PWMx_PWMPR  = 0x10
+	PWMx_PWMCNR = 0x14
+)
+
+// PWM clock sources
+const (
+	CLKSRC_OFF        = 0b00
+	CLKSRC_PERIPHERAL = 0b01
+	CLKSRC_HIGH_FREQ  = 0b10
+)
+
+// Configuration constants
+const (
+	// sample FIFO depth
+	FIFO_DEPTH = 4
+	// maximum period register value
+	MAX_PERIOD = 0xfffe
+	// maximum clock prescaler
+	MAX_PRESCALER = 4096
+
+	// Timeout is the default timeout for sample FIFO availability.
+	Timeout = 100 * time.Millisecond
+)
+
+// PWM represents a Pulse Width Modulation controller instance.
+type PWM struct {
+	sync.Mutex
+
+	// Controller index
+	Index int
+	// Base register
+	Base uint32
+	// Clock gate register
+	CCGR uint32
+	// Clock gate
+	CG int
+	// Clock retrieval function (PERCLK_CLK_ROOT)
+	Clock func() uint32
+	// Interrupt ID
+	IRQ int
+
+	// control registers
+	pwmcr  uint32
+	pwmsr  uint32
+	pwmir  uint32
+	pwmsar uint32
+	pwmpr  uint32
+
+	// period in clock cycles
+	period uint32
+	// duty cycle percentage
+	duty int
+}
+
+// Init initializes the PWM controller instance, the output is disabled until
+// Enable() is invoked.
+func (hw *PWM) Init() {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.Base == 0 || hw.CCGR == 0 || hw.Clock == nil {
+		panic("invalid PWM controller instance")
+	}
+
+	hw.pwmcr = hw.Base + PWMx_PWMCR
+	hw.pwmsr = hw.Base + PWMx_PWMSR
+	hw.pwmir = hw.Base + PWMx_PWMIR
+	hw.pwmsar = hw.Base + PWMx_PWMSAR
+	hw.pwmpr = hw.Base + PWMx_PWMPR
+
+	// enable clock
+	reg.SetN(hw.CCGR, hw.CG, 0b11, 0b11)
+
+	hw.reset()
+}
+
+func (hw *PWM) reset() {
+	reg.Write(hw.pwmcr, 0)
+	reg.Set(hw.pwmcr, PWMCR_SWR)
+	reg.Wait(hw.pwmcr, PWMCR_SWR, 1, 0)
+
+	reg.Write(hw.pwmir, 0)
+	// clear status
+	reg.Write(hw.pwmsr, 1<<PWMSR_FWE|1<<PWMSR_CMP|1<<PWMSR_ROV)
+
+	reg.SetN(hw.pwmcr, PWMCR_CLKSRC, 0b11, CLKSRC_HIGH_FREQ)
+	// keep running in low power modes
+	reg.Set(hw.pwmcr, PWMCR_WAITEN)
+}
+
+// SetFrequency configures the PWM output frequency, the duty cycle is
+// preserved. The output is briefly disabled if already enabled.
+func (hw *PWM) SetFrequency(hz int) (err error) {
+	if hz <= 0 {
+		return fmt.Errorf("invalid PWM frequency %d", hz)
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.pwmcr == 0 {
+		return errors.New("controller is not initialized")
+	}
+
+	// PWMO frequency = clk / (PRESCALER + 1) / (PWMPR + 2)
+	cycles := uint64(hw.Clock()) / uint64(hz)
+	presc := cycles/(MAX_PERIOD+2) + 1
+
+	if presc > MAX_PRESCALER || cycles/presc < 2 {
+		return fmt.Errorf("unsupported PWM frequency %d", hz)
+	}
+
+	enabled := reg.Get(hw.pwmcr, PWMCR_EN, 1) == 1
+
+	// reset the sample FIFO for immediate effect
+	hw.reset()
+
+	hw.period = uint32(cycles / presc)
+
+	reg.SetN(hw.pwmcr, PWMCR_PRESC, 0xfff, uint32(presc-1))
+	reg.Write(hw.pwmpr, hw.period-2)
+
+	if err = hw.setSample(hw.duty); err != nil {
+		return
+	}
+
+	if enabled {
+		reg.Set(hw.pwmcr, PWMCR_EN)
+	}
+
+	return
+}
+
+func (hw *PWM) setSample(percent int) (err error) {
+	sample := uint64(hw.period) * uint64(percent) / 100
+
+	if reg.Get(hw.pwmcr, PWMCR_EN, 1) == 1 {
+		start := time.Now()
+
+		// wait for sample FIFO availability
+		for reg.Get(hw.pwmsr, PWMSR_FIFOAV, 0b111) >= FIFO_DEPTH {
+			if time.Since(start) >= Timeout {
+				return errors.New("sample FIFO full")
+			}
+		}
+	}
+
+	reg.Write(hw.pwmsar, uint32(sample))
+	hw.duty = percent
+
+	return
+}
+
+// SetDutyCycle configures the PWM output duty cycle as a percentage of its
+// period, the new value takes effect at the next period or, when the output
+// is disabled, on Enable().
+func (hw *PWM) SetDutyCycle(percent int) (err error) {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("invalid duty cycle %d", percent)
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.period == 0 {
+		return errors.New("PWM frequency is not set")
+	}
+
+	// samples queued while disabled would be output on the next enable
+	if reg.Get(hw.pwmcr, PWMCR_EN, 1) == 0 {
+		hw.duty = percent
+		return
+	}
+
+	return hw.setSample(percent)
+}
+
+// flush discards queued samples, by resetting the controller, and restores
+// the current period and duty cycle.
+func (hw *PWM) flush() (err error) {
+	presc := reg.Get(hw.pwmcr, PWMCR_PRESC, 0xfff)
+
+	hw.reset()
+
+	reg.SetN(hw.pwmcr, PWMCR_PRESC, 0xfff, presc)
+	reg.Write(hw.pwmpr, hw.period-2)
+
+	return hw.setSample(hw.duty)
+}
+
+// Enable activates the PWM output.
+func (hw *PWM) Enable() (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.period == 0 {
+		return errors.New("PWM frequency is not set")
+	}
+
+	if reg.Get(hw.pwmcr, PWMCR_EN, 1) == 1 {
+		return
+	}
+
+	// discard samples left over since Disable()
+	if err = hw.flush(); err != nil {
+		return
+	}
+
+	reg.Set(hw.pwmcr, PWMCR_EN)
+
+	return
+}
+
+// Disable deactivates the PWM output.
+func (hw *PWM) Disable() {
+	hw.Lock()
+	defer hw.Unlock()
+
+	reg.Clear(hw.pwmcr, PWMCR_EN)
+}
+
+// Status returns the number of samples in the sample FIFO and whether roll-over
+// and compare events occurred.
+func (hw *PWM) Status() (fifo int, rollover bool, compare bool) {
+	sr := reg.Read(hw.pwmsr)
+
+	fifo = int(bits.Get(&sr, PWMSR_FIFOAV, 0b111))
+	rollover = bits.Get(&sr, PWMSR_ROV, 1) == 1
+	compare = bits.Get(&sr, PWMSR_CMP, 1) == 1
+
+	return
+}
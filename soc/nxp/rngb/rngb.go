@@ -17,8 +17,13 @@
 package rngb
 
 import (
+	"errors"
+	"fmt"
+	"runtime"
 	"sync"
+	"time"
 
+	"github.com/usbarmory/tamago/bits"
 	"github.com/usbarmory/tamago/internal/reg"
 	"github.com/usbarmory/tamago/internal/rng"
 )
@@ -47,6 +52,28 @@ const (
 	RNG_OUT = 0x14
 )
 
+// RNGB constants
+const (
+	// self-test and seeding timeout
+	RNG_TIMEOUT = 1 * time.Second
+)
+
+// Status represents the RNGB status.
+type Status struct {
+	// Self-test done
+	SelfTestDone bool
+	// Self-test failure
+	SelfTestFail bool
+	// Seed generation done
+	SeedDone bool
+	// Error condition
+	Error bool
+	// Error status register (RNG_ESR)
+	ErrorStatus uint32
+	// Output FIFO level (in 32-bit words)
+	FIFOLevel int
+}
+
 // RNGB represents the RNGB instance.
 type RNGB struct {
 	sync.Mutex
@@ -133,3 +160,124 @@ func (hw *RNGB) GetRandomData(b []byte) {
 		}
 	}
 }
+
+// Read fills p with random bytes gathered from the RNGB module, blocking
+// until enough entropy is available while yielding to other goroutines. It
+// implements the io.Reader interface, see ReadAvailable() for non-blocking
+// reads.
+//
+// An error is returned if the RNGB reports an error condition, in which case
+// p might be only partially filled.
+func (hw *RNGB) Read(p []byte) (n int, err error) {
+	if hw.sr == 0 {
+		return 0, errors.New("RNG is not initialized")
+	}
+
+	for n < len(p) {
+		if sr := reg.Read(hw.sr); bits.Get(&sr, RNG_SR_ERR, 1) != 0 {
+			return n, fmt.Errorf("RNG error, status:%#x esr:%#x", sr, reg.Read(hw.esr))
+		}
+
+		if reg.Get(hw.sr, RNG_SR_FIFO_LVL, 0b1111) > 0 {
+			n = rng.Fill(p, n, reg.Read(hw.out))
+		} else {
+			// tamago is single-threaded, give other goroutines a chance
+			runtime.Gosched()
+		}
+	}
+
+	return
+}
+
+// Available returns the number of 32-bit words of random data ready in the
+// output FIFO.
+func (hw *RNGB) Available() int {
+	if hw.sr == 0 {
+		return 0
+	}
+
+	return int(reg.Get(hw.sr, RNG_SR_FIFO_LVL, 0b1111))
+}
+
+// ReadAvailable fills p with the random bytes available in the output FIFO,
+// without waiting for more entropy to be generated, and returns the number of
+// bytes read (see Available()).
+//
+// An error is returned if the RNGB reports an error condition.
+func (hw *RNGB) ReadAvailable(p []byte) (n int, err error) {
+	if hw.sr == 0 {
+		return 0, errors.New("RNG is not initialized")
+	}
+
+	for n < len(p) {
+		sr := reg.Read(hw.sr)
+
+		if bits.Get(&sr, RNG_SR_ERR, 1) != 0 {
+			return n, fmt.Errorf("RNG error, status:%#x esr:%#x", sr, reg.Read(hw.esr))
+		}
+
+		if bits.Get(&sr, RNG_SR_FIFO_LVL, 0b1111) == 0 {
+			break
+		}
+
+		n = rng.Fill(p, n, reg.Read(hw.out))
+	}
+
+	return
+}
+
+// Status returns the RNGB status, including the results of the continuous
+// health checks which are reported as error conditions.
+func (hw *RNGB) Status() (st Status) {
+	if hw.sr == 0 {
+		return
+	}
+
+	sr := reg.Read(hw.sr)
+
+	st.SelfTestDone = bits.Get(&sr, RNG_SR_STDN, 1) == 1
+	st.SelfTestFail = bits.Get(&sr, RNG_SR_ST_PF, 1) == 1
+	st.SeedDone = bits.Get(&sr, RNG_SR_SDN, 1) == 1
+	st.Error = bits.Get(&sr, RNG_SR_ERR, 1) == 1
+	st.ErrorStatus = reg.Read(hw.esr)
+	st.FIFOLevel = int(bits.Get(&sr, RNG_SR_FIFO_LVL, 0b1111))
+
+	return
+}
+
+// SelfTest runs the RNGB self-test and, on success, generates a new seed.
+// Random data is not available while the self-test is in progress.
+func (hw *RNGB) SelfTest() (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.sr == 0 {
+		return errors.New("RNG is not initialized")
+	}
+
+	// clear errors
+	reg.Set(hw.cmd, RNG_CMD_CE)
+
+	// perform self-test
+	reg.Set(hw.cmd, RNG_CMD_ST)
+
+	if !reg.WaitFor(RNG_TIMEOUT, hw.sr, RNG_SR_STDN, 1, 1) {
+		return errors.New("self-test timeout")
+	}
+
+	if sr := reg.Read(hw.sr); bits.Get(&sr, RNG_SR_ERR, 1) != 0 || bits.Get(&sr, RNG_SR_ST_PF, 1) != 0 {
+		return fmt.Errorf("self-test failure, status:%#x esr:%#x", sr, reg.Read(hw.esr))
+	}
+
+	// generate a seed
+	reg.Set(hw.cmd, RNG_CMD_GS)
+
+	if !reg.WaitFor(RNG_TIMEOUT, hw.sr, RNG_SR_SDN, 1, 1) {
+		return errors.New("seeding timeout")
+	}
+
+	// clear interrupts
+	reg.Set(hw.cmd, RNG_CMD_CI)
+
+	return
+}
@@ -0,0 +1,279 @@
+// NXP Smart Direct Memory Access (SDMA) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package sdma
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/usbarmory/tamago/dma"
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// Transfer directions
+const (
+	MEMORY_TO_PERIPHERAL = iota
+	PERIPHERAL_TO_MEMORY
+)
+
+// maximum byte count for a single buffer descriptor
+const maxCount = 0xffff
+
+// Config represents an SDMA channel configuration.
+type Config struct {
+	// Transfer direction
+	Direction int
+	// Script address (e.g. SCRIPT_MCU_2_APP)
+	Script uint16
+	// DMA request event (see SDMA event mapping, IMX6ULLRM)
+	Event int
+	// Peripheral access width in bytes (1, 2 or 4)
+	Width int
+	// Peripheral FIFO watermark level in bytes
+	Watermark uint32
+	// Channel priority (1-7, defaults to 1)
+	Priority int
+	// Transfer timeout for each buffer descriptor (up to 65535 bytes), a
+	// zero value waits indefinitely as peripheral paced transfers depend
+	// on its data rate or, when receiving, on external data.
+	Timeout time.Duration
+}
+
+// Channel represents an allocated SDMA channel.
+type Channel struct {
+	sync.Mutex
+	Config
+
+	sdma *SDMA
+	n    int
+
+	// buffer descriptor
+	bd     []byte
+	bdAddr uint32
+}
+
+// Request allocates and configures an SDMA channel for the argument
+// configuration.
+func (hw *SDMA) Request(cfg Config) (ch *Channel, err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.ccb == nil {
+		return nil, errors.New("controller not initialized")
+	}
+
+	if cfg.Direction != MEMORY_TO_PERIPHERAL && cfg.Direction != PERIPHERAL_TO_MEMORY {
+		return nil, errors.New("invalid direction")
+	}
+
+	if cfg.Event < 0 || cfg.Event >= EVENTS {
+		return nil, errors.New("invalid event")
+	}
+
+	switch cfg.Width {
+	case 1, 2, 4:
+	default:
+		return nil, errors.New("invalid width")
+	}
+
+	if cfg.Priority == 0 {
+		cfg.Priority = 1
+	}
+
+	if cfg.Priority < 1 || cfg.Priority > 7 {
+		return nil, errors.New("invalid priority")
+	}
+
+	for i := 1; i < CHANNELS; i++ {
+		if hw.channels[i] != nil {
+			continue
+		}
+
+		ch = &Channel{
+			Config: cfg,
+			sdma:   hw,
+			n:      i,
+		}
+
+		break
+	}
+
+	if ch == nil {
+		return nil, errors.New("no channel available")
+	}
+
+	addr, bd := dma.Reserve(bdSize, 4)
+	ch.bd = bd
+	ch.bdAddr = uint32(addr)
+
+	hw.channels[ch.n] = ch
+	hw.setBufferDescriptor(ch.n, ch.bdAddr)
+	hw.setOwnership(ch.n, true)
+
+	reg.Write(hw.chnpri0+uint32(4*ch.n), uint32(cfg.Priority))
+	reg.Set(hw.chnenbl0+uint32(4*cfg.Event), ch.n)
+
+	return
+}
+
+// Free releases an SDMA channel, which must no longer be used.
+func (ch *Channel) Free() {
+	hw := ch.sdma
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.channels[ch.n] != ch {
+		return
+	}
+
+	reg.Write(hw.stop_stat, 1<<ch.n)
+	reg.Clear(hw.chnenbl0+uint32(4*ch.Event), ch.n)
+	reg.Write(hw.chnpri0+uint32(4*ch.n), 0)
+
+	hw.setBufferDescriptor(ch.n, 0)
+	hw.channels[ch.n] = nil
+
+	dma.Release(uint(ch.bdAddr))
+}
+
+// loadContext loads the channel script context through channel 0
+// (SDMA context switching, IMX6ULLRM).
+func (ch *Channel) loadContext(per uint32) error {
+	hw := ch.sdma
+
+	for i := range hw.ctx {
+		hw.ctx[i] = 0
+	}
+
+	var evtLow, evtHigh uint32
+
+	if ch.Event < 32 {
+		evtLow = 1 << ch.Event
+	} else {
+		evtHigh = 1 << (ch.Event - 32)
+	}
+
+	// program counter
+	binary.LittleEndian.PutUint32(hw.ctx[0:], uint32(ch.Script))
+
+	// general purpose registers (GR0-GR7)
+	binary.LittleEndian.PutUint32(hw.ctx[(2+0)*4:], evtHigh)
+	binary.LittleEndian.PutUint32(hw.ctx[(2+1)*4:], evtLow)
+	binary.LittleEndian.PutUint32(hw.ctx[(2+6)*4:], per)
+	binary.LittleEndian.PutUint32(hw.ctx[(2+7)*4:], ch.Watermark)
+
+	return hw.runChannel0(C0_SETDM, CONTEXT_SIZE, hw.ctxAddr, uint32(CONTEXT_ADDR+CONTEXT_SIZE*ch.n))
+}
+
+// Transfer performs a DMA transfer of the argument length from the source to
+// the destination address, according to the channel direction one address is
+// the peripheral FIFO while the other must point to DMA memory (see package
+// dma).
+//
+// The function waits for the transfer completion, which is paced by the
+// peripheral DMA request events, within the channel timeout (see
+// Config.Timeout). The controller is not locked while waiting, allowing
+// concurrent transfers on other channels.
+func (ch *Channel) Transfer(src uint32, dst uint32, n int) (err error) {
+	var per, mem uint32
+	var cmd uint32
+
+	hw := ch.sdma
+
+	ch.Lock()
+	defer ch.Unlock()
+
+	if n <= 0 || n%ch.Width != 0 {
+		return errors.New("invalid transfer length")
+	}
+
+	switch ch.Direction {
+	case MEMORY_TO_PERIPHERAL:
+		mem = src
+		per = dst
+	case PERIPHERAL_TO_MEMORY:
+		mem = dst
+		per = src
+	}
+
+	switch ch.Width {
+	case 4:
+		cmd = 0
+	case 1:
+		cmd = 1
+	case 2:
+		cmd = 2
+	}
+
+	for n > 0 {
+		count := n
+
+		if count > maxCount {
+			count = maxCount &^ (ch.Width - 1)
+		}
+
+		if err = ch.start(per, mem, count, cmd); err != nil {
+			return
+		}
+
+		if ch.Timeout == 0 {
+			reg.Wait(hw.intr, ch.n, 1, 1)
+		} else if !reg.WaitFor(ch.Timeout, hw.intr, ch.n, 1, 1) {
+			reg.Write(hw.stop_stat, 1<<ch.n)
+			return errors.New("transfer timeout")
+		}
+
+		reg.Write(hw.intr, 1<<ch.n)
+
+		if binary.LittleEndian.Uint32(ch.bd[0:])&(1<<BD_RROR) != 0 {
+			return errors.New("transfer error")
+		}
+
+		mem += uint32(count)
+		n -= count
+	}
+
+	return
+}
+
+// start loads the channel context and buffer descriptor for a single
+// transfer and starts the channel.
+func (ch *Channel) start(per uint32, mem uint32, count int, cmd uint32) (err error) {
+	var mode uint32
+
+	hw := ch.sdma
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.channels[ch.n] != ch {
+		return errors.New("channel not allocated")
+	}
+
+	if err = ch.loadContext(per); err != nil {
+		return
+	}
+
+	mode |= cmd << BD_COMMAND
+	mode |= 1<<BD_DONE | 1<<BD_WRAP | 1<<BD_INTR
+	mode |= uint32(count) << BD_COUNT
+
+	binary.LittleEndian.PutUint32(ch.bd[0:], mode)
+	binary.LittleEndian.PutUint32(ch.bd[4:], mem)
+	binary.LittleEndian.PutUint32(ch.bd[8:], 0)
+
+	hw.setBufferDescriptor(ch.n, ch.bdAddr)
+	reg.Write(hw.hstart, 1<<ch.n)
+
+	return
+}
@@ -0,0 +1,293 @@
+// NXP Smart Direct Memory Access (SDMA) driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+// Package sdma implements a driver for the NXP Smart Direct Memory Access
+// (SDMA) controller adopting the following reference specifications:
+//   - IMX6ULLRM - i.MX 6ULL Applications Processor Reference Manual - Rev 1 2017/11
+//
+// The driver supports memory-to-peripheral and peripheral-to-memory transfers
+// through the SDMA ROM scripts, custom scripts can be loaded in SDMA program
+// memory with LoadScript().
+//
+// This package is only meant to be used with `GOOS=tamago GOARCH=arm` as
+// supported by the TamaGo framework for bare metal Go on ARM SoCs, see
+// https://github.com/usbarmory/tamago.
+package sdma
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/usbarmory/tamago/dma"
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// SDMA registers
+// (SDMA Memory Map/Register Definition, IMX6ULLRM).
+const (
+	SDMAARM_MC0PTR    = 0x000
+	SDMAARM_INTR      = 0x004
+	SDMAARM_STOP_STAT = 0x008
+	SDMAARM_HSTART    = 0x00c
+	SDMAARM_EVTOVR    = 0x010
+	SDMAARM_DSPOVR    = 0x014
+	SDMAARM_HOSTOVR   = 0x018
+
+	SDMAARM_CONFIG = 0x038
+	CONFIG_ACR     = 4
+	CONFIG_CSM     = 0
+
+	SDMAARM_CHN0ADDR  = 0x05c
+	CHN0ADDR_SMSZ     = 14
+	CHN0ADDR_CHN0ADDR = 0
+
+	SDMAARM_CHNPRI0  = 0x100
+	SDMAARM_CHNENBL0 = 0x200
+)
+
+// SDMA context switching modes
+const (
+	CSM_STATIC  = 0b00
+	CSM_DYNAMIC = 0b11
+)
+
+// SDMA controller parameters
+const (
+	// number of channels
+	CHANNELS = 32
+	// number of DMA request events
+	EVENTS = 48
+
+	// channel 0 boot script address
+	BOOT_ADDR = 0x050
+	// context area address in SDMA data memory
+	CONTEXT_ADDR = 2048
+	// context size in 32-bit words
+	CONTEXT_SIZE = 32
+
+	// channel control block size
+	ccbSize = 16
+	// buffer descriptor size
+	bdSize = 12
+)
+
+// Channel 0 commands
+const (
+	C0_SETDM = 0x01
+	C0_SETPM = 0x04
+)
+
+// Buffer descriptor fields
+const (
+	BD_COMMAND = 24
+	BD_STATUS  = 16
+	BD_COUNT   = 0
+
+	BD_DONE = BD_STATUS + 0
+	BD_WRAP = BD_STATUS + 1
+	BD_CONT = BD_STATUS + 2
+	BD_INTR = BD_STATUS + 3
+	BD_RROR = BD_STATUS + 4
+	BD_LAST = BD_STATUS + 5
+	BD_EXTD = BD_STATUS + 7
+)
+
+// SDMA ROM script addresses (i.MX6 ROM)
+const (
+	SCRIPT_AP_2_AP      = 642
+	SCRIPT_APP_2_MCU    = 683
+	SCRIPT_MCU_2_APP    = 747
+	SCRIPT_UART_2_MCU   = 817
+	SCRIPT_SHP_2_MCU    = 891
+	SCRIPT_MCU_2_SHP    = 960
+	SCRIPT_UARTSH_2_MCU = 1032
+)
+
+// Timeout is the timeout for SDMA channel 0 commands (e.g. script and context
+// loading), channel transfers use Config.Timeout.
+const Timeout = 100 * time.Millisecond
+
+// SDMA represents the Smart Direct Memory Access controller instance.
+type SDMA struct {
+	sync.Mutex
+
+	// Base register
+	Base uint32
+	// Clock gate register
+	CCGR uint32
+	// Clock gate
+	CG int
+	// Interrupt ID
+	IRQ int
+
+	// control registers
+	mc0ptr    uint32
+	intr      uint32
+	stop_stat uint32
+	hstart    uint32
+	evtovr    uint32
+	dspovr    uint32
+	hostovr   uint32
+	config    uint32
+	chn0addr  uint32
+	chnpri0   uint32
+	chnenbl0  uint32
+
+	// channel control blocks
+	ccb     []byte
+	ccbAddr uint32
+
+	// channel 0 buffer descriptor
+	bd0     []byte
+	bd0Addr uint32
+
+	// channel context buffer
+	ctx     []byte
+	ctxAddr uint32
+
+	// allocated channels
+	channels [CHANNELS]*Channel
+}
+
+// Init initializes the SDMA controller, channel 0 is reserved as command
+// channel for context and script loading.
+func (hw *SDMA) Init() {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.Base == 0 || hw.CCGR == 0 {
+		panic("invalid SDMA instance")
+	}
+
+	hw.mc0ptr = hw.Base + SDMAARM_MC0PTR
+	hw.intr = hw.Base + SDMAARM_INTR
+	hw.stop_stat = hw.Base + SDMAARM_STOP_STAT
+	hw.hstart = hw.Base + SDMAARM_HSTART
+	hw.evtovr = hw.Base + SDMAARM_EVTOVR
+	hw.dspovr = hw.Base + SDMAARM_DSPOVR
+	hw.hostovr = hw.Base + SDMAARM_HOSTOVR
+	hw.config = hw.Base + SDMAARM_CONFIG
+	hw.chn0addr = hw.Base + SDMAARM_CHN0ADDR
+	hw.chnpri0 = hw.Base + SDMAARM_CHNPRI0
+	hw.chnenbl0 = hw.Base + SDMAARM_CHNENBL0
+
+	// enable clock
+	reg.SetN(hw.CCGR, hw.CG, 0b11, 0b11)
+
+	reg.Write(hw.mc0ptr, 0)
+
+	// disable all events and channels
+	for i := 0; i < EVENTS; i++ {
+		reg.Write(hw.chnenbl0+uint32(4*i), 0)
+	}
+
+	for i := 0; i < CHANNELS; i++ {
+		reg.Write(hw.chnpri0+uint32(4*i), 0)
+	}
+
+	if hw.ccb == nil {
+		addr, ccb := dma.Reserve(CHANNELS*ccbSize, 4)
+		hw.ccb = ccb
+		hw.ccbAddr = uint32(addr)
+
+		addr, bd0 := dma.Reserve(bdSize, 4)
+		hw.bd0 = bd0
+		hw.bd0Addr = uint32(addr)
+
+		addr, ctx := dma.Reserve(CONTEXT_SIZE*4, 4)
+		hw.ctx = ctx
+		hw.ctxAddr = uint32(addr)
+	}
+
+	for i := range hw.ccb {
+		hw.ccb[i] = 0
+	}
+
+	hw.setBufferDescriptor(0, hw.bd0Addr)
+	hw.setOwnership(0, false)
+
+	// set command channel boot script
+	reg.SetN(hw.chn0addr, CHN0ADDR_CHN0ADDR, 0x3fff, BOOT_ADDR)
+	// set 32-word context size, matching the C0_SETDM context layout
+	reg.Set(hw.chn0addr, CHN0ADDR_SMSZ)
+
+	reg.Write(hw.config, CSM_STATIC<<CONFIG_CSM)
+
+	reg.Write(hw.mc0ptr, hw.ccbAddr)
+
+	reg.Write(hw.chnpri0, 7)
+}
+
+// setBufferDescriptor sets the channel control block current and base buffer
+// descriptor pointers.
+func (hw *SDMA) setBufferDescriptor(n int, addr uint32) {
+	off := n * ccbSize
+
+	binary.LittleEndian.PutUint32(hw.ccb[off:], addr)
+	binary.LittleEndian.PutUint32(hw.ccb[off+4:], addr)
+}
+
+// setOwnership configures whether a channel is started by DMA request events
+// (event) or only by the ARM core.
+func (hw *SDMA) setOwnership(n int, event bool) {
+	reg.SetTo(hw.evtovr, n, !event)
+	reg.Clear(hw.hostovr, n)
+	reg.Set(hw.dspovr, n)
+}
+
+// runChannel0 executes a channel 0 command on the buffer at the argument
+// address.
+func (hw *SDMA) runChannel0(cmd uint32, count int, addr uint32, ext uint32) (err error) {
+	var mode uint32
+
+	mode |= cmd << BD_COMMAND
+	mode |= 1<<BD_DONE | 1<<BD_WRAP | 1<<BD_EXTD
+	mode |= uint32(count) << BD_COUNT
+
+	binary.LittleEndian.PutUint32(hw.bd0[0:], mode)
+	binary.LittleEndian.PutUint32(hw.bd0[4:], addr)
+	binary.LittleEndian.PutUint32(hw.bd0[8:], ext)
+
+	reg.Write(hw.hstart, 1<<0)
+
+	if !reg.WaitFor(Timeout, hw.stop_stat, 0, 1, 0) {
+		return errors.New("timeout waiting for channel 0")
+	}
+
+	reg.Write(hw.intr, 1<<0)
+
+	// switch to dynamic context switching after the first run
+	reg.SetN(hw.config, CONFIG_CSM, 0b11, CSM_DYNAMIC)
+
+	if binary.LittleEndian.Uint32(hw.bd0[0:])&(1<<BD_RROR) != 0 {
+		return errors.New("channel 0 command error")
+	}
+
+	return
+}
+
+// LoadScript loads a custom script in SDMA program memory at the argument
+// address (in 16-bit words), the script can then be used as channel Script.
+func (hw *SDMA) LoadScript(addr uint16, code []byte) (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if len(code) == 0 || len(code)%2 != 0 || len(code) > 0xffff {
+		return errors.New("invalid script size")
+	}
+
+	ptr, buf := dma.Reserve(len(code), 4)
+	defer dma.Release(ptr)
+
+	copy(buf, code)
+
+	return hw.runChannel0(C0_SETPM, len(code)/2, uint32(ptr), uint32(addr))
+}
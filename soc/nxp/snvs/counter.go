@@ -0,0 +1,63 @@
+// NXP Secure Non-Volatile Storage (SNVS) support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package snvs
+
+import (
+	"errors"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// MonotonicCounter returns the 48-bit value of the LP Secure Monotonic
+// Counter, which can only be incremented (see IncrementCounter()).
+func (hw *SNVS) MonotonicCounter() (count uint64, err error) {
+	if hw.Base == 0 {
+		return 0, errors.New("invalid SNVS instance")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	msb := uint64(reg.Get(hw.Base+SNVS_LPSMCMR, LPSMCMR_MON_CNTR, 0xffff))
+	lsb := uint64(reg.Read(hw.Base + SNVS_LPSMCLR))
+
+	return msb<<32 | lsb, nil
+}
+
+// IncrementCounter enables, if necessary, and increments the LP Secure
+// Monotonic Counter.
+//
+// A counter rollover triggers a security violation, after which the counter
+// can no longer be used.
+func (hw *SNVS) IncrementCounter() (err error) {
+	if hw.Base == 0 {
+		return errors.New("invalid SNVS instance")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if reg.Get(hw.Base+SNVS_LPLR, LPLR_MC_HL, 1) == 1 {
+		return errors.New("monotonic counter is locked")
+	}
+
+	reg.Set(hw.Base+SNVS_LPCR, LPCR_MC_ENV)
+
+	prev := reg.Read(hw.Base + SNVS_LPSMCLR)
+
+	// any write increments the counter
+	reg.Write(hw.Base+SNVS_LPSMCLR, 0)
+
+	if reg.Read(hw.Base+SNVS_LPSMCLR) == prev {
+		return errors.New("monotonic counter increment failed")
+	}
+
+	return
+}
@@ -0,0 +1,70 @@
+// NXP Secure Non-Volatile Storage (SNVS) support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package snvs
+
+import (
+	"errors"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// GPRCount is the number of 32-bit LP General Purpose Registers.
+const GPRCount = 1
+
+func (hw *SNVS) gpr(n int) (addr uint32, err error) {
+	if hw.Base == 0 {
+		return 0, errors.New("invalid SNVS instance")
+	}
+
+	if n < 0 || n >= GPRCount {
+		return 0, errors.New("invalid general purpose register")
+	}
+
+	return hw.Base + SNVS_LPGPR + uint32(n*4), nil
+}
+
+// ReadGPR returns the value of an LP General Purpose Register.
+//
+// The LP General Purpose Registers are retained across resets, as long as
+// the SNVS low power domain is powered, and are cleared on power loss or
+// zeroized on LP security violations.
+func (hw *SNVS) ReadGPR(n int) (val uint32, err error) {
+	addr, err := hw.gpr(n)
+
+	if err != nil {
+		return
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	return reg.Read(addr), nil
+}
+
+// WriteGPR sets the value of an LP General Purpose Register, see ReadGPR()
+// for its persistence.
+func (hw *SNVS) WriteGPR(n int, val uint32) (err error) {
+	addr, err := hw.gpr(n)
+
+	if err != nil {
+		return
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if reg.Get(hw.Base+SNVS_LPLR, LPLR_GPR_HL, 1) == 1 {
+		return errors.New("general purpose register is locked")
+	}
+
+	reg.Write(addr, val)
+
+	return
+}
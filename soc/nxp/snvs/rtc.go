@@ -0,0 +1,195 @@
+// NXP Secure Non-Volatile Storage (SNVS) support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package snvs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// RTC constants
+const (
+	// LP Secure Real Time Counter frequency
+	RTC_FREQ = 32768
+	// LP Secure Real Time Counter width
+	RTC_BITS = 47
+
+	// Timeout for RTC enable/disable operations, which are synchronized
+	// to the 32 kHz clock.
+	RTC_TIMEOUT = 10 * time.Millisecond
+)
+
+// rtc returns the LP Secure Real Time Counter value, the counter is read
+// until two consecutive reads match as it is asynchronous to the register
+// bus (see LP Secure Real Time Counter, IMX6ULLSRM).
+func (hw *SNVS) rtc() (val uint64) {
+	var prev uint64
+
+	read := func() uint64 {
+		msb := uint64(reg.Get(hw.Base+SNVS_LPSRTCMR, 0, 0x7fff))
+		lsb := uint64(reg.Read(hw.Base + SNVS_LPSRTCLR))
+		return msb<<32 | lsb
+	}
+
+	prev = read()
+
+	for {
+		if val = read(); val == prev {
+			return
+		}
+
+		prev = val
+	}
+}
+
+// Now returns the current time as maintained by the LP Secure Real Time
+// Counter, which keeps counting across resets (and power cycles with a coin
+// cell battery), see SetTime().
+func (hw *SNVS) Now() time.Time {
+	if hw.Base == 0 {
+		return time.Time{}
+	}
+
+	ticks := hw.rtc()
+	sec := int64(ticks / RTC_FREQ)
+	nsec := int64((ticks % RTC_FREQ) * 1e9 / RTC_FREQ)
+
+	return time.Unix(sec, nsec)
+}
+
+// SetTime programs and enables the LP Secure Real Time Counter with the
+// argument time, which must not precede the Unix epoch.
+func (hw *SNVS) SetTime(t time.Time) (err error) {
+	if hw.Base == 0 {
+		return errors.New("invalid SNVS instance")
+	}
+
+	if t.Unix() < 0 {
+		return errors.New("invalid time")
+	}
+
+	ticks := uint64(t.Unix())*RTC_FREQ + uint64(t.Nanosecond())*RTC_FREQ/1e9
+
+	if ticks >= 1<<RTC_BITS {
+		return errors.New("invalid time")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if reg.Get(hw.Base+SNVS_LPLR, LPLR_SRTC_HL, 1) == 1 {
+		return errors.New("RTC is locked")
+	}
+
+	// disable counter
+	reg.Clear(hw.Base+SNVS_LPCR, LPCR_SRTC_ENV)
+
+	if !reg.WaitFor(RTC_TIMEOUT, hw.Base+SNVS_LPCR, LPCR_SRTC_ENV, 1, 0) {
+		return errors.New("RTC disable timeout")
+	}
+
+	reg.Write(hw.Base+SNVS_LPSRTCMR, uint32(ticks>>32))
+	reg.Write(hw.Base+SNVS_LPSRTCLR, uint32(ticks))
+
+	// enable counter
+	reg.Set(hw.Base+SNVS_LPCR, LPCR_SRTC_ENV)
+
+	if !reg.WaitFor(RTC_TIMEOUT, hw.Base+SNVS_LPCR, LPCR_SRTC_ENV, 1, 1) {
+		return errors.New("RTC enable timeout")
+	}
+
+	return
+}
+
+// setAlarm enables or disables the LP Time Alarm and its wake-up interrupt.
+func (hw *SNVS) setAlarm(enable bool) (err error) {
+	var val uint32
+
+	if enable {
+		val = 1
+	}
+
+	reg.SetTo(hw.Base+SNVS_LPCR, LPCR_LPWUI_EN, enable)
+	reg.SetTo(hw.Base+SNVS_LPCR, LPCR_LPTA_EN, enable)
+
+	if !reg.WaitFor(RTC_TIMEOUT, hw.Base+SNVS_LPCR, LPCR_LPTA_EN, 1, val) {
+		return errors.New("RTC alarm timeout")
+	}
+
+	return
+}
+
+// SetAlarm programs the LP Time Alarm to the argument time, with one second
+// resolution, and enables the alarm wake-up interrupt which can be used to
+// resume the SoC from low power modes.
+//
+// The LP Secure Real Time Counter must be enabled (see SetTime()), the alarm
+// status can be queried with Alarm() and cleared with ClearAlarm().
+func (hw *SNVS) SetAlarm(t time.Time) (err error) {
+	if hw.Base == 0 {
+		return errors.New("invalid SNVS instance")
+	}
+
+	// the alarm is compared against the RTC seconds (bits 46:15)
+	if t.Unix() < 0 || t.Unix() >= 1<<(RTC_BITS-15) {
+		return errors.New("invalid time")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if reg.Get(hw.Base+SNVS_LPCR, LPCR_SRTC_ENV, 1) == 0 {
+		return errors.New("RTC is not enabled")
+	}
+
+	if err = hw.setAlarm(false); err != nil {
+		return
+	}
+
+	reg.Write(hw.Base+SNVS_LPTAR, uint32(t.Unix()))
+
+	// clear any previous alarm event
+	reg.Write(hw.Base+SNVS_LPSR, 1<<LPSR_LPTA)
+
+	return hw.setAlarm(true)
+}
+
+// Alarm returns the programmed LP Time Alarm and whether the alarm has been
+// triggered.
+func (hw *SNVS) Alarm() (t time.Time, triggered bool) {
+	if hw.Base == 0 {
+		return
+	}
+
+	t = time.Unix(int64(reg.Read(hw.Base+SNVS_LPTAR)), 0)
+	triggered = reg.Get(hw.Base+SNVS_LPSR, LPSR_LPTA, 1) == 1
+
+	return
+}
+
+// ClearAlarm disables the LP Time Alarm and clears its event status.
+func (hw *SNVS) ClearAlarm() (err error) {
+	if hw.Base == 0 {
+		return errors.New("invalid SNVS instance")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if err = hw.setAlarm(false); err != nil {
+		return
+	}
+
+	reg.Write(hw.Base+SNVS_LPSR, 1<<LPSR_LPTA)
+
+	return
+}
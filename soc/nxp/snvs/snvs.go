@@ -18,12 +18,23 @@
 package snvs
 
 import (
+	"sync"
+
 	"github.com/usbarmory/tamago/bits"
 	"github.com/usbarmory/tamago/internal/reg"
 )
 
 // SNVS registers
 const (
+	SNVS_HPSICR     = 0x0c
+	HPSICR_LPSVI_EN = 31
+
+	SNVS_HPSVCR        = 0x10
+	HPSVCR_LPSV_CFG    = 30
+	LPSV_CFG_DISABLED  = 0b00
+	LPSV_CFG_NON_FATAL = 0b01
+	LPSV_CFG_FATAL     = 0b10
+
 	SNVS_HPSR           = 0x14
 	HPSR_OTPMK_ZERO     = 27
 	HPSR_OTPMK_SYNDROME = 16
@@ -31,16 +42,54 @@ const (
 	HPSR_SSM_STATE    = 8
 	SSM_STATE_TRUSTED = 0b1101
 	SSM_STATE_SECURE  = 0b1111
+
+	SNVS_LPLR    = 0x34
+	LPLR_GPR_HL  = 5
+	LPLR_MC_HL   = 4
+	LPLR_SRTC_HL = 2
+
+	SNVS_LPCR     = 0x38
+	LPCR_LPWUI_EN = 3
+	LPCR_MC_ENV   = 2
+	LPCR_LPTA_EN  = 1
+	LPCR_SRTC_ENV = 0
+
+	SNVS_LPTDCR   = 0x48
+	LPTDCR_ET2P   = 12
+	LPTDCR_ET1P   = 11
+	LPTDCR_ET2_EN = 10
+	LPTDCR_ET1_EN = 9
+
+	SNVS_LPSR = 0x4c
+	LPSR_ET2D = 10
+	LPSR_ET1D = 9
+	LPSR_LPTA = 0
+
+	SNVS_LPSRTCMR = 0x50
+	SNVS_LPSRTCLR = 0x54
+
+	SNVS_LPTAR = 0x58
+
+	SNVS_LPSMCMR     = 0x5c
+	LPSMCMR_MC_ERA   = 16
+	LPSMCMR_MON_CNTR = 0
+	SNVS_LPSMCLR     = 0x60
+
+	SNVS_LPGPR = 0x68
 )
 
 // SNVS represents the SNVS instance.
 type SNVS struct {
+	sync.Mutex
+
 	// Base register
 	Base uint32
 	// Clock gate register
 	CCGR uint32
 	// Clock gate
 	CG int
+	// Security violation interrupt ID
+	IRQ int
 }
 
 // Init initializes the SNVS controller.
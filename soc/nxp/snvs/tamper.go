@@ -0,0 +1,108 @@
+// NXP Secure Non-Volatile Storage (SNVS) support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package snvs
+
+import (
+	"errors"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// tamper returns the LPTDCR enable and polarity and LPSR detection bit
+// positions for the argument external tamper detector.
+func tamper(pin int) (en int, pol int, det int, err error) {
+	switch pin {
+	case 1:
+		return LPTDCR_ET1_EN, LPTDCR_ET1P, LPSR_ET1D, nil
+	case 2:
+		return LPTDCR_ET2_EN, LPTDCR_ET2P, LPSR_ET2D, nil
+	default:
+		return 0, 0, 0, errors.New("invalid tamper detector")
+	}
+}
+
+// EnableTamper enables the argument external tamper detector (1 or 2), the
+// activeLow argument selects the tamper pin polarity.
+//
+// A detected tamper event triggers an LP security violation, which is
+// signaled through the SNVS security violation interrupt (see IRQ).
+//
+// The zeroize argument selects whether the violation is treated as fatal,
+// in which case the SNVS transitions to the soft fail state, blocking
+// access to the master key, otherwise it is only reported.
+func (hw *SNVS) EnableTamper(pin int, activeLow bool, zeroize bool) (err error) {
+	en, pol, det, err := tamper(pin)
+
+	if err != nil {
+		return
+	}
+
+	cfg := uint32(LPSV_CFG_NON_FATAL)
+
+	if zeroize {
+		cfg = LPSV_CFG_FATAL
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	// clear any stale event
+	reg.Write(hw.Base+SNVS_LPSR, 1<<det)
+
+	reg.SetN(hw.Base+SNVS_HPSVCR, HPSVCR_LPSV_CFG, 0b11, cfg)
+	reg.Set(hw.Base+SNVS_HPSICR, HPSICR_LPSVI_EN)
+
+	reg.SetTo(hw.Base+SNVS_LPTDCR, pol, activeLow)
+	reg.Set(hw.Base+SNVS_LPTDCR, en)
+
+	return
+}
+
+// DisableTamper disables the argument external tamper detector (1 or 2).
+func (hw *SNVS) DisableTamper(pin int) (err error) {
+	en, _, _, err := tamper(pin)
+
+	if err != nil {
+		return
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	reg.Clear(hw.Base+SNVS_LPTDCR, en)
+
+	return
+}
+
+// Tamper returns whether a tamper event has been detected on the argument
+// external tamper detector (1 or 2).
+func (hw *SNVS) Tamper(pin int) bool {
+	_, _, det, err := tamper(pin)
+
+	if err != nil {
+		return false
+	}
+
+	return reg.Get(hw.Base+SNVS_LPSR, det, 1) == 1
+}
+
+// ClearTamper clears the tamper event status of the argument external tamper
+// detector (1 or 2).
+func (hw *SNVS) ClearTamper(pin int) (err error) {
+	_, _, det, err := tamper(pin)
+
+	if err != nil {
+		return
+	}
+
+	reg.Write(hw.Base+SNVS_LPSR, 1<<det)
+
+	return
+}
@@ -7,7 +7,7 @@
 // Use of this source code is governed by the license
 // that can be found in the LICENSE file.
 
-// Package bee implements a driver for the NXP Temperature Monitor (TEMPMON)
+// Package tempmon implements a driver for the NXP Temperature Monitor (TEMPMON)
 // adopting the following reference specifications:
 //   - IMX6ULLRM - i.MX 6ULL Applications Processor Reference Manual - Rev 1 2017/11
 //
@@ -17,6 +17,7 @@
 package tempmon
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/usbarmory/tamago/bits"
@@ -29,6 +30,7 @@ const (
 	TEMPMON_TEMPSENSE0_SET = 0x04
 	TEMPMON_TEMPSENSE0_CLR = 0x08
 
+	TEMPSENSE0_ALARM_VALUE  = 20
 	TEMPSENSE0_TEMP_CNT     = 8
 	TEMPSENSE0_FINISHED     = 2
 	TEMPSENSE0_MEASURE_TEMP = 1
@@ -47,14 +49,20 @@ type TEMPMON struct {
 
 	// Base register
 	Base uint32
+	// Interrupt ID
+	IRQ int
 
 	// control registers
 	sense0     uint32
 	sense0_set uint32
 	sense0_clr uint32
 	sense1     uint32
+	sense1_set uint32
 	sense1_clr uint32
 
+	// periodic measurement mode
+	periodic bool
+
 	// calibration points
 	hotTemp   uint32
 	hotCount  uint32
@@ -76,6 +84,7 @@ func (hw *TEMPMON) Init(calibrationData uint32) {
 	hw.sense0_clr = hw.Base + TEMPMON_TEMPSENSE0_CLR
 
 	hw.sense1 = hw.Base + TEMPMON_TEMPSENSE1
+	hw.sense1_set = hw.Base + TEMPMON_TEMPSENSE1_SET
 	hw.sense1_clr = hw.Base + TEMPMON_TEMPSENSE1_CLR
 
 	hw.hotTemp = bits.Get(&calibrationData, 0, 0xff)
@@ -83,18 +92,22 @@ func (hw *TEMPMON) Init(calibrationData uint32) {
 	hw.roomCount = bits.Get(&calibrationData, 20, 0xfff)
 }
 
-// Read performs a single on-die temperature measurement.
+// Read performs a single on-die temperature measurement, when periodic
+// measurements are enabled (see SetAlarm()) the last measurement is returned.
 func (hw *TEMPMON) Read() float32 {
 	hw.Lock()
 	defer hw.Unlock()
 
-	// enable sensor only during single measurement
-	reg.Set(hw.sense0_clr, TEMPSENSE0_POWER_DOWN)
-	defer reg.Set(hw.sense0_set, TEMPSENSE0_POWER_DOWN)
+	if !hw.periodic {
+		// enable sensor only during single measurement
+		reg.Set(hw.sense0_clr, TEMPSENSE0_POWER_DOWN)
+		defer reg.Set(hw.sense0_set, TEMPSENSE0_POWER_DOWN)
+
+		// start a single measurement
+		reg.SetN(hw.sense1_clr, TEMPSENSE1_MEASURE_FREQ, 0xffff, 0xffff)
+		reg.Set(hw.sense0_set, TEMPSENSE0_MEASURE_TEMP)
+	}
 
-	// start and wait for a single measurement
-	reg.SetN(hw.sense1_clr, TEMPSENSE1_MEASURE_FREQ, 0xffff, 0xffff)
-	reg.Set(hw.sense0_set, TEMPSENSE0_MEASURE_TEMP)
 	reg.Wait(hw.sense0, TEMPSENSE0_FINISHED, 1, 1)
 
 	cnt := reg.Get(hw.sense0, TEMPSENSE0_TEMP_CNT, 0xfff)
@@ -102,6 +115,63 @@ func (hw *TEMPMON) Read() float32 {
 	return temp(cnt, hw.hotTemp, hw.hotCount, hw.roomCount)
 }
 
+// SetAlarm configures the over-temperature alarm threshold, in degrees
+// Celsius, and enables periodic measurements with the argument interval
+// expressed in 32768 Hz RTC clock cycles.
+//
+// The alarm interrupt (see IRQ) is raised whenever a measurement exceeds the
+// threshold, the sensor remains powered until DisableAlarm() is invoked.
+func (hw *TEMPMON) SetAlarm(celsius float32, interval uint16) (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.hotCount == 0 || hw.roomCount <= hw.hotCount {
+		return errors.New("invalid calibration data")
+	}
+
+	if interval == 0 {
+		return errors.New("invalid measurement interval")
+	}
+
+	cnt := count(celsius, hw.hotTemp, hw.hotCount, hw.roomCount)
+
+	reg.SetN(hw.sense0, TEMPSENSE0_ALARM_VALUE, 0xfff, cnt)
+
+	reg.SetN(hw.sense1_clr, TEMPSENSE1_MEASURE_FREQ, 0xffff, 0xffff)
+	reg.Write(hw.sense1_set, uint32(interval)<<TEMPSENSE1_MEASURE_FREQ)
+
+	reg.Set(hw.sense0_clr, TEMPSENSE0_POWER_DOWN)
+	reg.Set(hw.sense0_set, TEMPSENSE0_MEASURE_TEMP)
+
+	hw.periodic = true
+
+	return
+}
+
+// Alarm returns the configured over-temperature alarm threshold in degrees
+// Celsius.
+func (hw *TEMPMON) Alarm() float32 {
+	hw.Lock()
+	defer hw.Unlock()
+
+	cnt := reg.Get(hw.sense0, TEMPSENSE0_ALARM_VALUE, 0xfff)
+
+	return temp(cnt, hw.hotTemp, hw.hotCount, hw.roomCount)
+}
+
+// DisableAlarm stops periodic measurements and powers down the sensor,
+// reverting to single measurements on each Read().
+func (hw *TEMPMON) DisableAlarm() {
+	hw.Lock()
+	defer hw.Unlock()
+
+	reg.Set(hw.sense0_clr, TEMPSENSE0_MEASURE_TEMP)
+	reg.SetN(hw.sense1_clr, TEMPSENSE1_MEASURE_FREQ, 0xffff, 0xffff)
+	reg.Set(hw.sense0_set, TEMPSENSE0_POWER_DOWN)
+
+	hw.periodic = false
+}
+
 // p3531, 52.2 Software Usage Guidelines, IMX6ULLRM
 func temp(cnt, hotTemp, hotCount, roomCount uint32) float32 {
 	nm := float32(cnt)
@@ -112,3 +182,23 @@ func temp(cnt, hotTemp, hotCount, roomCount uint32) float32 {
 
 	return t2 - (nm-n2)*((t2-t1)/(n1-n2))
 }
+
+// count is the inverse of temp and returns the sensor count corresponding to
+// the argument temperature.
+func count(celsius float32, hotTemp, hotCount, roomCount uint32) uint32 {
+	t1 := float32(25.0)
+	t2 := float32(hotTemp)
+	n1 := float32(roomCount)
+	n2 := float32(hotCount)
+
+	nm := n2 + (t2-celsius)*((n1-n2)/(t2-t1))
+
+	switch {
+	case nm < 0:
+		return 0
+	case nm > 0xfff:
+		return 0xfff
+	}
+
+	return uint32(nm)
+}
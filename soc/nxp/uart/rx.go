@@ -0,0 +1,199 @@
+// NXP UART driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package uart
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/usbarmory/tamago/bits"
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// DefaultRxBufferSize is the default receive ring buffer size for interrupt
+// driven reception (see EnableRxInterrupt()).
+const DefaultRxBufferSize = 4096
+
+// ErrBreak is returned by Read(), with interrupt driven reception, once all
+// characters received before a break condition have been read.
+var ErrBreak = errors.New("break condition detected")
+
+// ring represents a receive or transmit ring buffer.
+type ring struct {
+	sync.Mutex
+
+	buf []byte
+	// read index
+	r int
+	// number of buffered characters
+	n int
+	// number of discarded characters
+	overflow int
+	// number of characters preceding a break condition (-1 if none)
+	brk int
+}
+
+func (rb *ring) put(c byte) {
+	rb.Lock()
+	defer rb.Unlock()
+
+	if rb.n == len(rb.buf) {
+		rb.overflow++
+		return
+	}
+
+	rb.buf[(rb.r+rb.n)%len(rb.buf)] = c
+	rb.n++
+}
+
+// write buffers as many characters as possible from the argument slice,
+// without overflowing, returning their number.
+func (rb *ring) write(buf []byte) (n int) {
+	rb.Lock()
+	defer rb.Unlock()
+
+	for n = 0; n < len(buf) && rb.n < len(rb.buf); n++ {
+		rb.buf[(rb.r+rb.n)%len(rb.buf)] = buf[n]
+		rb.n++
+	}
+
+	return
+}
+
+func (rb *ring) mark() {
+	rb.Lock()
+	defer rb.Unlock()
+
+	if rb.brk < 0 {
+		rb.brk = rb.n
+	}
+}
+
+func (rb *ring) get(buf []byte) (n int, brk bool) {
+	rb.Lock()
+	defer rb.Unlock()
+
+	size := len(buf)
+
+	if rb.brk >= 0 && rb.brk < size {
+		size = rb.brk
+	}
+
+	for n = 0; n < size && rb.n > 0; n++ {
+		buf[n] = rb.buf[rb.r]
+		rb.r = (rb.r + 1) % len(rb.buf)
+		rb.n--
+	}
+
+	if rb.brk >= 0 {
+		rb.brk -= n
+
+		if rb.brk == 0 {
+			rb.brk = -1
+			brk = true
+		}
+	}
+
+	return
+}
+
+// EnableRxInterrupt enables interrupt driven reception, received characters
+// are buffered in a ring buffer of the argument size (DefaultRxBufferSize
+// when 0) from which Rx() and Read() retrieve data.
+//
+// Received break conditions are reported by Read() with ErrBreak, after all
+// characters received before the break have been read.
+//
+// The UART interrupt (see the IRQ field) must be enabled on the interrupt
+// controller and handled by invoking ServiceInterrupts().
+func (hw *UART) EnableRxInterrupt(bufSize int) {
+	if bufSize <= 0 {
+		bufSize = DefaultRxBufferSize
+	}
+
+	hw.Lock()
+	hw.rx = &ring{
+		buf: make([]byte, bufSize),
+		brk: -1,
+	}
+	hw.Unlock()
+
+	reg.Set(hw.ucr1, UCR1_RRDYEN)
+}
+
+// DisableRxInterrupt disables interrupt driven reception, any data buffered
+// and not yet read is discarded.
+func (hw *UART) DisableRxInterrupt() {
+	reg.Clear(hw.ucr1, UCR1_RRDYEN)
+
+	hw.Lock()
+	hw.rx = nil
+	hw.Unlock()
+}
+
+// RxOverflow returns the number of received characters which have been
+// discarded, since interrupt driven reception has been enabled, due to a
+// full ring buffer.
+func (hw *UART) RxOverflow() int {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.rx == nil {
+		return 0
+	}
+
+	hw.rx.Lock()
+	defer hw.rx.Unlock()
+
+	return hw.rx.overflow
+}
+
+// ServiceInterrupts drains the RxFIFO into the receive ring buffer and
+// refills the TxFIFO from the transmit ring buffer, it must be invoked to
+// handle UART interrupts when interrupt driven reception or transmission is
+// enabled (see EnableRxInterrupt() and EnableTxInterrupt()).
+func (hw *UART) ServiceInterrupts() {
+	hw.Lock()
+	rx := hw.rx
+	tx := hw.tx
+	hw.Unlock()
+
+	if tx != nil {
+		hw.fill(tx)
+	}
+
+	if rx == nil {
+		return
+	}
+
+	for hw.rxReady() {
+		urxd := reg.Read(hw.urxd)
+
+		if bits.Get(&urxd, URXD_BRK, 1) == 1 {
+			// clear status
+			reg.Write(hw.usr2, 1<<USR2_BRCD)
+			rx.mark()
+			continue
+		}
+
+		if bits.Get(&urxd, URXD_PRERR, 0b11111) != 0 {
+			continue
+		}
+
+		rx.put(byte(bits.Get(&urxd, URXD_RX_DATA, 0xff)))
+	}
+}
+
+func (hw *UART) rxBuffer() *ring {
+	hw.Lock()
+	defer hw.Unlock()
+
+	return hw.rx
+}
@@ -0,0 +1,128 @@
+// NXP UART driver
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package uart
+
+import (
+	"runtime"
+
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// DefaultTxBufferSize is the default transmit ring buffer size for interrupt
+// driven transmission (see EnableTxInterrupt()).
+const DefaultTxBufferSize = 4096
+
+// EnableTxInterrupt enables interrupt driven transmission, data passed to
+// Write() is buffered in a ring buffer of the argument size
+// (DefaultTxBufferSize when 0) which is drained into the TxFIFO on transmitter
+// ready interrupts. Write() blocks only while the ring buffer is full.
+//
+// Tx() remains polled, therefore its characters can be transmitted ahead of
+// buffered data.
+//
+// The UART must be initialized (see Init()) and its interrupt (see the IRQ
+// field) must be enabled on the interrupt controller and handled by invoking
+// ServiceInterrupts().
+func (hw *UART) EnableTxInterrupt(bufSize int) {
+	if bufSize <= 0 {
+		bufSize = DefaultTxBufferSize
+	}
+
+	hw.Flush()
+
+	hw.Lock()
+	hw.tx = &ring{
+		buf: make([]byte, bufSize),
+		brk: -1,
+	}
+	hw.Unlock()
+}
+
+// DisableTxInterrupt disables interrupt driven transmission, any buffered
+// data is transmitted before returning.
+func (hw *UART) DisableTxInterrupt() {
+	hw.Flush()
+
+	hw.Lock()
+	hw.tx = nil
+	hw.Unlock()
+
+	reg.Clear(hw.ucr1, UCR1_TRDYEN)
+}
+
+// Flush waits for all buffered data to be transmitted, including the
+// contents of the TxFIFO and transmit shift register, it should be used to
+// ensure output is drained (e.g. before a reset).
+func (hw *UART) Flush() {
+	// nothing to flush before Init()
+	if hw.uts == 0 {
+		return
+	}
+
+	if tx := hw.txBuffer(); tx != nil {
+		for hw.pending(tx) {
+			// tamago is single-threaded, give other goroutines a chance
+			runtime.Gosched()
+		}
+	}
+
+	// wait for TxFIFO and shift register to be empty
+	reg.Wait(hw.uts, UTS_TXEMPTY, 1, 1)
+	reg.Wait(hw.usr2, USR2_TXDC, 1, 1)
+}
+
+// queue buffers the argument data for interrupt driven transmission, waiting
+// for room in the ring buffer when full.
+func (hw *UART) queue(tx *ring, buf []byte) {
+	for len(buf) > 0 {
+		n := tx.write(buf)
+		buf = buf[n:]
+
+		// enable transmitter ready interrupt
+		reg.Set(hw.ucr1, UCR1_TRDYEN)
+
+		if len(buf) > 0 {
+			// tamago is single-threaded, give other goroutines a chance
+			runtime.Gosched()
+		}
+	}
+}
+
+// fill refills the TxFIFO from the transmit ring buffer, the transmitter
+// ready interrupt is disabled once the ring buffer is empty.
+func (hw *UART) fill(tx *ring) {
+	tx.Lock()
+	defer tx.Unlock()
+
+	for tx.n > 0 && !hw.txFull() {
+		reg.Write(hw.utxd, uint32(tx.buf[tx.r]))
+		tx.r = (tx.r + 1) % len(tx.buf)
+		tx.n--
+	}
+
+	if tx.n == 0 {
+		reg.Clear(hw.ucr1, UCR1_TRDYEN)
+	}
+}
+
+// pending returns whether the transmit ring buffer holds data.
+func (hw *UART) pending(tx *ring) bool {
+	tx.Lock()
+	defer tx.Unlock()
+
+	return tx.n > 0
+}
+
+func (hw *UART) txBuffer() *ring {
+	hw.Lock()
+	defer hw.Unlock()
+
+	return hw.tx
+}
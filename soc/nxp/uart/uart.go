@@ -17,6 +17,10 @@
 package uart
 
 import (
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/usbarmory/tamago/bits"
 	"github.com/usbarmory/tamago/internal/reg"
 )
@@ -101,6 +105,8 @@ const (
 	UFCR_RXTL   = 0
 
 	UARTx_USR2 = 0x0098
+	USR2_TXDC  = 3
+	USR2_BRCD  = 2
 	USR2_RDR   = 0
 
 	UARTx_UESC = 0x009c
@@ -108,12 +114,22 @@ const (
 	UARTx_UBIR = 0x00a4
 	UARTx_UBMR = 0x00a8
 
-	UARTx_UTS  = 0x00b4
-	UTS_TXFULL = 4
+	UARTx_UTS   = 0x00b4
+	UTS_TXEMPTY = 6
+	UTS_TXFULL  = 4
+)
+
+// UART parity modes
+const (
+	ParityNone = iota
+	ParityEven
+	ParityOdd
 )
 
 // UART represents a serial port instance.
 type UART struct {
+	sync.Mutex
+
 	// Controller index
 	Index int
 	// Base register
@@ -124,6 +140,8 @@ type UART struct {
 	CG int
 	// Clock retrieval function
 	Clock func() uint32
+	// Interrupt ID
+	IRQ int
 	// port speed
 	Baudrate uint32
 	// DTE mode
@@ -145,6 +163,11 @@ type UART struct {
 	ubir uint32
 	ubmr uint32
 	uts  uint32
+
+	// interrupt driven receive buffer
+	rx *ring
+	// interrupt driven transmit buffer
+	tx *ring
 }
 
 // Init initializes and enables the UART for RS-232 mode,
@@ -228,6 +251,27 @@ func (hw *UART) setup() {
 	// set UFCR
 	reg.Write(hw.ufcr, ufcr)
 
+	hw.setBaudrate()
+
+	var ucr2 uint32
+	// 8-bit transmit and receive character length
+	bits.Set(&ucr2, UCR2_WS)
+	// Enable the transmitter
+	bits.Set(&ucr2, UCR2_TXEN)
+	// Enable the receiver
+	bits.Set(&ucr2, UCR2_RXEN)
+	// Software reset
+	bits.Set(&ucr2, UCR2_SRST)
+
+	// set UCR2
+	reg.Write(hw.ucr2, ucr2)
+	// set flow control
+	hw.setFlowControl(hw.Flow)
+	// Enable the UART
+	reg.Set(hw.ucr1, UCR1_UARTEN)
+}
+
+func (hw *UART) setBaudrate() {
 	// p3592, 55.5 Binary Rate Multiplier (BRM), IMX6ULLRM
 	//
 	//              ref_clk_freq
@@ -244,36 +288,97 @@ func (hw *UART) setup() {
 	reg.Write(hw.ubir, 15)
 	// set UBMR
 	reg.Write(hw.ubmr, ubmr)
+}
 
-	var ucr2 uint32
-	// 8-bit transmit and receive character length
-	bits.Set(&ucr2, UCR2_WS)
-	// Enable the transmitter
-	bits.Set(&ucr2, UCR2_TXEN)
-	// Enable the receiver
-	bits.Set(&ucr2, UCR2_RXEN)
-	// Software reset
-	bits.Set(&ucr2, UCR2_SRST)
-
-	if hw.Flow {
+func (hw *UART) setFlowControl(enable bool) {
+	if enable {
 		// Receiver controls CTS
-		bits.Set(&ucr2, UCR2_CTSC)
+		reg.Set(hw.ucr2, UCR2_CTSC)
+		// Transmitter obeys the RTS pin
+		reg.Clear(hw.ucr2, UCR2_IRTS)
 
 		// 16 characters in the RxFIFO as the maximum value leads to
 		// overflow even with hardware flow control in place.
 		reg.SetN(hw.ucr4, UCR4_CTSTL, 0b111111, 16)
 	} else {
+		// CTS pin controlled by the CTS bit
+		reg.Clear(hw.ucr2, UCR2_CTSC)
 		// Ignore the RTS pin
-		bits.Set(&ucr2, UCR2_IRTS)
+		reg.Set(hw.ucr2, UCR2_IRTS)
 
 		// 32 characters in the RxFIFO (maximum)
 		reg.SetN(hw.ucr4, UCR4_CTSTL, 0b111111, 32)
 	}
+}
+
+// EnableFlowControl enables RTS/CTS hardware flow control, it is equivalent
+// to initializing the UART with the Flow field set.
+//
+// The receiver deasserts CTS when the RxFIFO reaches the CTS trigger level,
+// while the transmitter pauses when RTS is deasserted by the remote end,
+// Tx() therefore waits for TxFIFO room until transmission is resumed.
+//
+// Note that signal naming follows the UART DCE mode (CTS_B is an output,
+// RTS_B an input), on the i.MX6UL family all UART instances expose
+// UARTx_CTS_B and UARTx_RTS_B signals which must be muxed through the
+// IOMUXC (e.g. UART1_CTS_B and UART1_RTS_B pads in ALT0 mode for UART1).
+func (hw *UART) EnableFlowControl() {
+	hw.Flow = true
+	hw.setFlowControl(true)
+}
+
+// DisableFlowControl disables RTS/CTS hardware flow control.
+func (hw *UART) DisableFlowControl() {
+	hw.Flow = false
+	hw.setFlowControl(false)
+}
+
+// SetBaudRate changes the UART port speed, pending transmissions are
+// completed before the change takes place.
+func (hw *UART) SetBaudRate(baud int) (err error) {
+	if baud <= 0 {
+		return fmt.Errorf("invalid baud rate %d", baud)
+	}
+
+	// wait for TxFIFO and shift register to be empty
+	reg.Wait(hw.uts, UTS_TXEMPTY, 1, 1)
+	reg.Wait(hw.usr2, USR2_TXDC, 1, 1)
+
+	hw.Baudrate = uint32(baud)
+	hw.setBaudrate()
+
+	return
+}
+
+// Configure sets the UART frame format, the arguments specify the character
+// length (7 or 8 bits), the parity mode (see Parity* constants) and the
+// number of stop bits (1 or 2).
+//
+// The UART must be initialized (see Init()) and the configuration applies to
+// the following transmitted and received characters.
+func (hw *UART) Configure(wordLength int, parity int, stop int) (err error) {
+	if wordLength != 7 && wordLength != 8 {
+		return fmt.Errorf("unsupported word length %d", wordLength)
+	}
+
+	if stop != 1 && stop != 2 {
+		return fmt.Errorf("unsupported number of stop bits %d", stop)
+	}
+
+	if parity != ParityNone && parity != ParityEven && parity != ParityOdd {
+		return fmt.Errorf("unsupported parity %d", parity)
+	}
+
+	ucr2 := reg.Read(hw.ucr2)
+
+	bits.SetTo(&ucr2, UCR2_WS, wordLength == 8)
+	bits.SetTo(&ucr2, UCR2_PREN, parity != ParityNone)
+	bits.SetTo(&ucr2, UCR2_PROE, parity == ParityOdd)
+	bits.SetTo(&ucr2, UCR2_STPB, stop == 2)
 
-	// set UCR2
 	reg.Write(hw.ucr2, ucr2)
-	// Enable the UART
-	reg.Set(hw.ucr1, UCR1_UARTEN)
+
+	return
 }
 
 // Enable enables the UART, this is only required after an explicit disable
@@ -287,7 +392,35 @@ func (hw *UART) Disable() {
 	reg.Clear(hw.ucr1, UCR1_UARTEN)
 }
 
+// SendBreak transmits a break condition for the argument duration.
+func (hw *UART) SendBreak(d time.Duration) {
+	// wait for pending transmissions
+	reg.Wait(hw.uts, UTS_TXEMPTY, 1, 1)
+	reg.Wait(hw.usr2, USR2_TXDC, 1, 1)
+
+	reg.Set(hw.ucr1, UCR1_SNDBRK)
+	time.Sleep(d)
+	reg.Clear(hw.ucr1, UCR1_SNDBRK)
+}
+
+// Break returns whether a break condition has been detected, on the receive
+// line, since its last invocation.
+func (hw *UART) Break() bool {
+	if reg.Get(hw.usr2, USR2_BRCD, 1) == 0 {
+		return false
+	}
+
+	// clear status
+	reg.Write(hw.usr2, 1<<USR2_BRCD)
+
+	return true
+}
+
 // Tx transmits a single character to the serial port.
+//
+// Transmission is always polled, also with interrupt driven transmission
+// (see EnableTxInterrupt()), as the function is suitable for runtime and
+// panic output (e.g. printk).
 func (hw *UART) Tx(c byte) {
 	for hw.txFull() {
 		// wait for TX FIFO to have room for a character
@@ -297,6 +430,14 @@ func (hw *UART) Tx(c byte) {
 
 // Rx receives a single character from the serial port.
 func (hw *UART) Rx() (c byte, valid bool) {
+	if rx := hw.rxBuffer(); rx != nil {
+		buf := make([]byte, 1)
+		n, _ := rx.get(buf)
+		valid = n == 1
+
+		return buf[0], valid
+	}
+
 	if !hw.rxReady() {
 		return
 	}
@@ -311,7 +452,15 @@ func (hw *UART) Rx() (c byte, valid bool) {
 }
 
 // Write data from buffer to serial port.
+//
+// With interrupt driven transmission (see EnableTxInterrupt()) the function
+// returns as soon as all data is buffered, see Flush().
 func (hw *UART) Write(buf []byte) (n int, _ error) {
+	if tx := hw.txBuffer(); tx != nil {
+		hw.queue(tx, buf)
+		return len(buf), nil
+	}
+
 	for n = 0; n < len(buf); n++ {
 		hw.Tx(buf[n])
 	}
@@ -323,6 +472,16 @@ func (hw *UART) Write(buf []byte) (n int, _ error) {
 func (hw *UART) Read(buf []byte) (n int, _ error) {
 	var valid bool
 
+	if rx := hw.rxBuffer(); rx != nil {
+		n, brk := rx.get(buf)
+
+		if brk {
+			return n, ErrBreak
+		}
+
+		return n, nil
+	}
+
 	for n = 0; n < len(buf); n++ {
 		buf[n], valid = hw.Rx()
 
@@ -0,0 +1,311 @@
+// USB CDC-ACM function support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package usb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// CDC-ACM constants
+const (
+	// Line Coding Structure length
+	LINE_CODING_LENGTH = 7
+
+	// Control Signal Bitmap Values for SetControlLineState
+	CONTROL_LINE_DTR = 0
+	CONTROL_LINE_RTS = 1
+)
+
+// ACM transmit queue size, in Write() invocations
+const acmTxQueueSize = 16
+
+// LineCoding implements the Line Coding Structure, USB Class Definitions for
+// Communication Devices 1.1.
+type LineCoding struct {
+	// data terminal rate, in bits per second
+	DTERate uint32
+	// stop bits (0: 1, 1: 1.5, 2: 2)
+	CharFormat uint8
+	// parity (0: none, 1: odd, 2: even, 3: mark, 4: space)
+	ParityType uint8
+	// data bits (5, 6, 7, 8 or 16)
+	DataBits uint8
+}
+
+// Bytes converts the line coding structure to byte array format.
+func (lc *LineCoding) Bytes() []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, lc)
+	return buf.Bytes()
+}
+
+// ACM represents a CDC Abstract Control Model (ACM) function, implementing a
+// virtual serial port through one bulk endpoint pair and one interrupt
+// notification endpoint.
+//
+// The ACM instance implements io.ReadWriter, Read() blocks until data is
+// received from the host while Write() queues data for transmission.
+type ACM struct {
+	sync.Mutex
+
+	// Control interface
+	Control *InterfaceDescriptor
+	// Data interface
+	Data *InterfaceDescriptor
+
+	// Line coding, as set by the host
+	LineCoding LineCoding
+	// Control line state (see CONTROL_LINE_*), as set by the host
+	ControlLineState uint16
+
+	bus *USB
+
+	rx    []byte
+	ready chan struct{}
+
+	tx     chan []byte
+	notify chan []byte
+}
+
+// NewACM builds a CDC-ACM function and adds it to the argument configuration,
+// the argument endpoint numbers are used for the interrupt notification
+// endpoint and the bulk data endpoint pair.
+//
+// The function is added with an Interface Association Descriptor, the device
+// must therefore use the Miscellaneous Device Class (see
+// ConfigurationDescriptor.AddFunction()).
+func NewACM(bus *USB, conf *ConfigurationDescriptor, notifyEP int, dataEP int) (acm *ACM, err error) {
+	if notifyEP < 1 || notifyEP >= MAX_ENDPOINTS || dataEP < 1 || dataEP >= MAX_ENDPOINTS {
+		return nil, errors.New("invalid endpoint number")
+	}
+
+	if notifyEP == dataEP {
+		return nil, errors.New("notification and data endpoints must differ")
+	}
+
+	acm = &ACM{
+		LineCoding: LineCoding{
+			DTERate:  115200,
+			DataBits: 8,
+		},
+		bus:    bus,
+		ready:  make(chan struct{}, 1),
+		tx:     make(chan []byte, acmTxQueueSize),
+		notify: make(chan []byte, 1),
+	}
+
+	iad := &InterfaceAssociationDescriptor{}
+	iad.SetDefaults()
+	iad.FunctionClass = COMMUNICATION_INTERFACE_CLASS
+	iad.FunctionSubClass = ACM_SUBCLASS
+	iad.FunctionProtocol = AT_COMMAND_PROTOCOL
+
+	acm.Control = &InterfaceDescriptor{}
+	acm.Control.SetDefaults()
+	acm.Control.NumEndpoints = 1
+	acm.Control.InterfaceClass = COMMUNICATION_INTERFACE_CLASS
+	acm.Control.InterfaceSubClass = ACM_SUBCLASS
+	acm.Control.InterfaceProtocol = AT_COMMAND_PROTOCOL
+	acm.Control.Setup = acm.setup
+
+	ep := &EndpointDescriptor{}
+	ep.SetDefaults()
+	ep.EndpointAddress = 0x80 | uint8(notifyEP)
+	ep.Attributes = INTERRUPT
+	ep.MaxPacketSize = 16
+	ep.Interval = 9
+	ep.Function = acm.notifyTx
+
+	acm.Control.Endpoints = append(acm.Control.Endpoints, ep)
+
+	acm.Data = &InterfaceDescriptor{}
+	acm.Data.SetDefaults()
+	acm.Data.NumEndpoints = 2
+	acm.Data.InterfaceClass = DATA_INTERFACE_CLASS
+
+	ep = &EndpointDescriptor{}
+	ep.SetDefaults()
+	ep.EndpointAddress = 0x80 | uint8(dataEP)
+	ep.Attributes = BULK
+	ep.Function = acm.dataTx
+
+	acm.Data.Endpoints = append(acm.Data.Endpoints, ep)
+
+	ep = &EndpointDescriptor{}
+	ep.SetDefaults()
+	ep.EndpointAddress = uint8(dataEP)
+	ep.Attributes = BULK
+	ep.Function = acm.dataRx
+
+	acm.Data.Endpoints = append(acm.Data.Endpoints, ep)
+
+	if err = conf.AddFunction(iad, acm.Control, acm.Data); err != nil {
+		return nil, err
+	}
+
+	header := &CDCHeaderDescriptor{}
+	header.SetDefaults()
+
+	cm := &CDCCallManagementDescriptor{}
+	cm.SetDefaults()
+	cm.DataInterface = acm.Data.InterfaceNumber
+
+	// SET_LINE_CODING, GET_LINE_CODING, SET_CONTROL_LINE_STATE,
+	// SERIAL_STATE and SEND_BREAK support
+	acmDesc := &CDCAbstractControlManagementDescriptor{}
+	acmDesc.SetDefaults()
+	acmDesc.Capabilities = 0b0110
+
+	union := &CDCUnionDescriptor{}
+	union.SetDefaults()
+	union.MasterInterface = acm.Control.InterfaceNumber
+	union.SlaveInterface0 = acm.Data.InterfaceNumber
+
+	acm.Control.ClassDescriptors = append(acm.Control.ClassDescriptors,
+		header.Bytes(),
+		cm.Bytes(),
+		acmDesc.Bytes(),
+		union.Bytes(),
+	)
+
+	return
+}
+
+func (acm *ACM) setup(setup *SetupData) (in []byte, ack bool, done bool, err error) {
+	// wValue is byte swapped by getSetup()
+	value := setup.Value>>8 | setup.Value<<8
+
+	switch setup.Request {
+	case SET_LINE_CODING:
+		var buf []byte
+
+		if buf, err = acm.bus.rx(0, make([]byte, LINE_CODING_LENGTH)); err != nil {
+			return
+		}
+
+		if len(buf) < LINE_CODING_LENGTH {
+			return nil, false, true, errors.New("invalid line coding")
+		}
+
+		acm.Lock()
+		binary.Read(bytes.NewReader(buf), binary.LittleEndian, &acm.LineCoding)
+		acm.Unlock()
+
+		return nil, true, true, nil
+	case GET_LINE_CODING:
+		acm.Lock()
+		defer acm.Unlock()
+
+		return trim(acm.LineCoding.Bytes(), setup.Length), false, true, nil
+	case SET_CONTROL_LINE_STATE:
+		acm.Lock()
+		acm.ControlLineState = value
+		acm.Unlock()
+
+		return nil, true, true, nil
+	case SEND_BREAK:
+		return nil, true, true, nil
+	}
+
+	return
+}
+
+// SerialState queues a SERIAL_STATE notification to the host with the
+// argument UART state bitmap (e.g. bit 0 DCD, bit 1 DSR), replacing any
+// pending one.
+func (acm *ACM) SerialState(state uint16) {
+	buf := new(bytes.Buffer)
+
+	// Notification format, USB Class Definitions for Communication
+	// Devices 1.1
+	buf.WriteByte(0b10100001)
+	buf.WriteByte(SERIAL_STATE)
+	binary.Write(buf, binary.LittleEndian, uint16(0))
+	binary.Write(buf, binary.LittleEndian, uint16(acm.Control.InterfaceNumber))
+	binary.Write(buf, binary.LittleEndian, uint16(2))
+	binary.Write(buf, binary.LittleEndian, state)
+
+	select {
+	case <-acm.notify:
+	default:
+	}
+
+	acm.notify <- buf.Bytes()
+}
+
+func (acm *ACM) notifyTx(_ []byte, lastErr error) (in []byte, err error) {
+	select {
+	case in = <-acm.notify:
+	default:
+	}
+
+	return
+}
+
+func (acm *ACM) dataTx(_ []byte, lastErr error) (in []byte, err error) {
+	select {
+	case in = <-acm.tx:
+	default:
+	}
+
+	return
+}
+
+func (acm *ACM) dataRx(out []byte, lastErr error) (_ []byte, err error) {
+	acm.Lock()
+	acm.rx = append(acm.rx, out...)
+	acm.Unlock()
+
+	select {
+	case acm.ready <- struct{}{}:
+	default:
+	}
+
+	return
+}
+
+// Read reads data received from the host, blocking until at least one byte
+// is available.
+func (acm *ACM) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return
+	}
+
+	for {
+		acm.Lock()
+
+		if len(acm.rx) > 0 {
+			n = copy(p, acm.rx)
+			acm.rx = acm.rx[n:]
+			acm.Unlock()
+
+			return
+		}
+
+		acm.Unlock()
+
+		<-acm.ready
+	}
+}
+
+// Write queues data for transmission to the host, blocking only when the
+// transmit queue is full.
+func (acm *ACM) Write(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return
+	}
+
+	acm.tx <- append([]byte{}, p...)
+
+	return len(p), nil
+}
@@ -12,6 +12,7 @@
 // specifications:
 //   - IMX6ULLRM - i.MX 6ULL Applications Processor Reference Manual - Rev 1 2017/11
 //   - USB2.0    - USB Specification Revision 2.0
+//   - EHCI      - Enhanced Host Controller Interface Specification for Universal Serial Bus - Revision 1.0
 //
 // This package is only meant to be used with `GOOS=tamago GOARCH=arm` as
 // supported by the TamaGo framework for bare metal Go on ARM SoCs, see
@@ -42,10 +43,13 @@ const (
 	// p3823, 56.6 USB Core Memory Map/Register Definition, IMX6ULLRM
 
 	USB_UOGx_USBCMD = 0x140
+	USBCMD_ASE      = 5
 	USBCMD_RST      = 1
 	USBCMD_RS       = 0
 
 	USB_UOGx_USBSTS = 0x144
+	USBSTS_AS       = 15
+	USBSTS_HCH      = 12
 	USBSTS_URI      = 6
 	USBSTS_UI       = 0
 
@@ -58,10 +62,20 @@ const (
 	USB_UOGx_ENDPTLISTADDR = 0x158
 	ENDPTLISTADDR_EPBASE   = 11
 
+	USB_UOGx_ASYNCLISTADDR = 0x158
+
 	USB_UOGx_PORTSC1 = 0x184
 	PORTSC_PTS_1     = 30
 	PORTSC_PSPD      = 26
+	PORTSC_PP        = 12
 	PORTSC_PR        = 8
+	PORTSC_SUSP      = 7
+	PORTSC_FPR       = 6
+	PORTSC_OCC       = 5
+	PORTSC_PEC       = 3
+	PORTSC_PE        = 2
+	PORTSC_CSC       = 1
+	PORTSC_CCS       = 0
 
 	USB_UOGx_OTGSC = 0x1a4
 	OTGSC_OT       = 3
@@ -150,8 +164,8 @@ type USB struct {
 	// USB device configuration
 	Device *Device
 
-	// EP1-N transfer completion rendezvous point
-	event *sync.Cond
+	// EP1-N transfer completion signals (interrupt mode)
+	done [MAX_ENDPOINTS][2]chan struct{}
 	// EP1-N cancellation signal
 	exit chan struct{}
 	// EP-1-N completion synchronization
@@ -180,6 +194,11 @@ type USB struct {
 	epListAddr uint32
 	// cache for endpoint queue heads pointers
 	dQH [MAX_ENDPOINTS][2]uint32
+	// cache for endpoint transfer types
+	transferType [MAX_ENDPOINTS][2]int
+
+	// host mode asynchronous schedule head
+	asyncHead uint32
 }
 
 // Init initializes the USB controller.
@@ -234,23 +253,43 @@ func (hw *USB) Init() {
 	reg.Set(hw.chrg, CHRG_DETECT_CHK_CHRG_B)
 }
 
-// Speed returns the port speed.
-func (hw *USB) Speed() (speed string) {
+// Speed represents a USB port speed.
+type Speed uint32
+
+// USB port speeds (Port Status & Control (USB_nPORTSC1), IMX6ULLRM)
+const (
+	FULL_SPEED Speed = 0b00
+	LOW_SPEED  Speed = 0b01
+	HIGH_SPEED Speed = 0b10
+)
+
+// String returns the speed name.
+func (s Speed) String() string {
+	switch s {
+	case FULL_SPEED:
+		return "full"
+	case LOW_SPEED:
+		return "low"
+	case HIGH_SPEED:
+		return "high"
+	default:
+		return "invalid"
+	}
+}
+
+// Speed returns the port speed, in device mode this is the speed negotiated
+// with the host after bus reset.
+func (hw *USB) Speed() Speed {
 	hw.Lock()
 	defer hw.Unlock()
 
-	switch reg.Get(hw.sc, PORTSC_PSPD, 0b11) {
-	case 0b00:
-		speed = "full"
-	case 0b01:
-		speed = "low"
-	case 0b10:
-		speed = "high"
-	case 0b11:
-		panic("invalid port speed")
-	}
+	return Speed(reg.Get(hw.sc, PORTSC_PSPD, 0b11))
+}
 
-	return
+// Connected returns whether the port is connected, in device mode this
+// indicates that the device is attached to a host and has been reset.
+func (hw *USB) Connected() bool {
+	return reg.Get(hw.sc, PORTSC_CCS, 1) == 1
 }
 
 // PowerDown shuts down the USB PHY.
@@ -283,6 +322,8 @@ func (hw *USB) Reset() {
 	reg.WriteBack(hw.complete)
 	// flush endpoint buffers
 	reg.Write(hw.flush, 0xffffffff)
+	// return to default address
+	reg.Write(hw.addr, 0)
 
 	reg.Wait(hw.sc, PORTSC_PR, 1, 0)
 
@@ -25,6 +25,15 @@ const (
 	INTERFACE_LENGTH             = 9
 	ENDPOINT_LENGTH              = 7
 	DEVICE_QUALIFIER_LENGTH      = 10
+	BOS_LENGTH                   = 5
+	USB_2_0_EXTENSION_LENGTH     = 7
+)
+
+// Device capability types
+const (
+	WIRELESS_USB      = 0x01
+	USB_2_0_EXTENSION = 0x02
+	PLATFORM          = 0x05
 )
 
 // DeviceDescriptor implements
@@ -105,6 +114,38 @@ func (d *ConfigurationDescriptor) AddInterface(iface *InterfaceDescriptor) {
 	d.Interfaces = append(d.Interfaces, iface)
 }
 
+// AddFunction adds a function, made of one or more interfaces, to a
+// configuration. Functions with multiple interfaces are grouped by the
+// argument Interface Association Descriptor, which is updated with the first
+// interface number and interface count, for composite devices.
+//
+// Note that composite devices using IADs must use the Miscellaneous Device
+// Class (DeviceClass 0xef, DeviceSubClass 0x02, DeviceProtocol 0x01) in their
+// Device Descriptor.
+func (d *ConfigurationDescriptor) AddFunction(iad *InterfaceAssociationDescriptor, ifaces ...*InterfaceDescriptor) (err error) {
+	if len(ifaces) == 0 {
+		return errors.New("function must have at least one interface")
+	}
+
+	if ifaces[0].AlternateSetting != 0 {
+		return errors.New("function must start with a default interface setting")
+	}
+
+	first := d.NumInterfaces
+
+	for _, iface := range ifaces {
+		d.AddInterface(iface)
+	}
+
+	if iad != nil {
+		iad.FirstInterface = first
+		iad.InterfaceCount = d.NumInterfaces - first
+		ifaces[0].IAD = iad
+	}
+
+	return
+}
+
 // Bytes converts the descriptor structure to byte array format.
 func (d *ConfigurationDescriptor) Bytes() []byte {
 	buf := new(bytes.Buffer)
@@ -164,6 +205,11 @@ type InterfaceDescriptor struct {
 
 	Endpoints        []*EndpointDescriptor
 	ClassDescriptors [][]byte
+
+	// Optional class-specific setup handler, invoked for class and
+	// vendor requests directed to this interface in place of
+	// Device.Setup.
+	Setup SetupFunction
 }
 
 // SetDefaults initializes default values for the USB interface descriptor.
@@ -260,6 +306,19 @@ func (d *EndpointDescriptor) TransferType() int {
 	return int(d.Attributes & 0b11)
 }
 
+// MaxPacketLength returns the endpoint maximum packet length, excluding
+// additional transaction opportunities.
+func (d *EndpointDescriptor) MaxPacketLength() int {
+	return int(d.MaxPacketSize & 0x7ff)
+}
+
+// Transactions returns the number of transactions per microframe for
+// high-bandwidth isochronous and interrupt endpoints
+// (Table 9-13 wMaxPacketSize bits 12..11, USB2.0).
+func (d *EndpointDescriptor) Transactions() int {
+	return int((d.MaxPacketSize>>11)&0b11) + 1
+}
+
 // Bytes converts the descriptor structure to byte array format.
 func (d *EndpointDescriptor) Bytes() []byte {
 	buf := new(bytes.Buffer)
@@ -340,6 +399,80 @@ func (d *DeviceQualifierDescriptor) Bytes() []byte {
 	return buf.Bytes()
 }
 
+// BOSDescriptor implements
+// BOS Descriptor, USB 2.0 Link Power Management Addendum.
+type BOSDescriptor struct {
+	Length         uint8
+	DescriptorType uint8
+	TotalLength    uint16
+	NumDeviceCaps  uint8
+
+	// Device Capability descriptors
+	Capabilities [][]byte
+}
+
+// SetDefaults initializes default values for the BOS descriptor.
+func (d *BOSDescriptor) SetDefaults() {
+	d.Length = BOS_LENGTH
+	d.DescriptorType = BOS
+}
+
+// AddCapability adds a Device Capability descriptor (e.g. the
+// USB20ExtensionDescriptor or a platform capability such as WebUSB) to the
+// BOS descriptor.
+func (d *BOSDescriptor) AddCapability(desc []byte) {
+	d.Capabilities = append(d.Capabilities, desc)
+}
+
+// Bytes converts the descriptor structure to byte array format, including
+// its Device Capability descriptors.
+func (d *BOSDescriptor) Bytes() []byte {
+	var caps []byte
+
+	for _, desc := range d.Capabilities {
+		caps = append(caps, desc...)
+	}
+
+	d.TotalLength = uint16(int(d.Length) + len(caps))
+	d.NumDeviceCaps = uint8(len(d.Capabilities))
+
+	buf := new(bytes.Buffer)
+
+	binary.Write(buf, binary.LittleEndian, d.Length)
+	binary.Write(buf, binary.LittleEndian, d.DescriptorType)
+	binary.Write(buf, binary.LittleEndian, d.TotalLength)
+	binary.Write(buf, binary.LittleEndian, d.NumDeviceCaps)
+	buf.Write(caps)
+
+	return buf.Bytes()
+}
+
+// USB20ExtensionDescriptor implements
+// USB 2.0 Extension Descriptor, USB 2.0 Link Power Management Addendum.
+type USB20ExtensionDescriptor struct {
+	Length            uint8
+	DescriptorType    uint8
+	DevCapabilityType uint8
+	Attributes        uint32
+}
+
+// SetDefaults initializes default values for the USB 2.0 Extension
+// descriptor, advertising Link Power Management (LPM) support.
+func (d *USB20ExtensionDescriptor) SetDefaults() {
+	d.Length = USB_2_0_EXTENSION_LENGTH
+	d.DescriptorType = DEVICE_CAPABILITY
+	d.DevCapabilityType = USB_2_0_EXTENSION
+	// LPM
+	d.Attributes = 1 << 1
+}
+
+// Bytes converts the descriptor structure to byte array format.
+func (d *USB20ExtensionDescriptor) Bytes() []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, d)
+	return buf.Bytes()
+}
+
 // SetupFunction represents the function to process class-specific setup
 // requests.
 //
@@ -360,29 +493,42 @@ type Device struct {
 	Qualifier      *DeviceQualifierDescriptor
 	Configurations []*ConfigurationDescriptor
 	Strings        [][]byte
+	BOS            *BOSDescriptor
 
 	// Host requested settings
 	ConfigurationValue uint8
 	AlternateSetting   uint8
+	RemoteWakeup       bool
 
 	// Optional class-specific setup handler
 	Setup SetupFunction
 }
 
-func (d *Device) setStringDescriptor(s []byte, zero bool) (uint8, error) {
+func stringDescriptor(s []byte) ([]byte, error) {
 	var buf []byte
 
 	desc := &StringDescriptor{}
 	desc.SetDefaults()
-	desc.Length += uint8(len(s))
 
-	if desc.Length > 255 {
-		return 0, fmt.Errorf("string descriptor size (%d) cannot exceed 255", desc.Length)
+	if len(s)+int(desc.Length) > 255 {
+		return nil, fmt.Errorf("string descriptor size (%d) cannot exceed 255", len(s)+int(desc.Length))
 	}
 
+	desc.Length += uint8(len(s))
+
 	buf = append(buf, desc.Bytes()...)
 	buf = append(buf, s...)
 
+	return buf, nil
+}
+
+func (d *Device) setStringDescriptor(s []byte, zero bool) (uint8, error) {
+	buf, err := stringDescriptor(s)
+
+	if err != nil {
+		return 0, err
+	}
+
 	if zero && len(d.Strings) >= 1 {
 		d.Strings[0] = buf
 	} else {
@@ -392,6 +538,18 @@ func (d *Device) setStringDescriptor(s []byte, zero bool) (uint8, error) {
 	return uint8(len(d.Strings) - 1), nil
 }
 
+// utf16le converts a string to its UTF-16LE representation.
+func utf16le(s string) (buf []byte) {
+	u := utf16.Encode([]rune(s))
+
+	for i := 0; i < len(u); i++ {
+		buf = append(buf, byte(u[i]&0xff))
+		buf = append(buf, byte(u[i]>>8))
+	}
+
+	return
+}
+
 // SetLanguageCodes configures String Descriptor Zero language codes
 // (p273, Table 9-15. String Descriptor Zero, Specifying Languages Supported by the Device, USB2.0).
 func (d *Device) SetLanguageCodes(codes []uint16) (err error) {
@@ -416,25 +574,71 @@ func (d *Device) SetLanguageCodes(codes []uint16) (err error) {
 // be used to fill string descriptor index value in configuration descriptors
 // (p274, Table 9-16. UNICODE String Descriptor, USB2.0).
 func (d *Device) AddString(s string) (uint8, error) {
-	var buf []byte
+	return d.setStringDescriptor(utf16le(s), false)
+}
 
-	desc := &StringDescriptor{}
-	desc.SetDefaults()
+// SetString sets the string descriptor at the argument index, allowing
+// strings to be defined at runtime (e.g. a serial number derived from the SoC
+// unique ID). Index zero is reserved to language codes (see
+// SetLanguageCodes()), unset indexes below the argument one are filled with
+// empty strings.
+func (d *Device) SetString(index uint8, s string) (err error) {
+	if index == 0 {
+		return errors.New("string descriptor zero is reserved for language codes")
+	}
 
-	r := []rune(s)
-	u := utf16.Encode([]rune(r))
+	buf, err := stringDescriptor(utf16le(s))
 
-	for i := 0; i < len(u); i++ {
-		buf = append(buf, byte(u[i]&0xff))
-		buf = append(buf, byte(u[i]>>8))
+	if err != nil {
+		return
+	}
+
+	for int(index) >= len(d.Strings) {
+		empty := &StringDescriptor{}
+		empty.SetDefaults()
+
+		d.Strings = append(d.Strings, empty.Bytes())
+	}
+
+	d.Strings[index] = buf
+
+	return
+}
+
+// SetBOS sets the device Binary Device Object Store (BOS) descriptor, the
+// device descriptor USB release number is updated to 2.01 as required for
+// BOS support (USB 2.0 Link Power Management Addendum).
+func (d *Device) SetBOS(bos *BOSDescriptor) (err error) {
+	if d.Descriptor == nil {
+		return errors.New("invalid device descriptor")
 	}
 
-	return d.setStringDescriptor(buf, false)
+	d.Descriptor.bcdUSB = 0x0201
+	d.BOS = bos
+
+	return
+}
+
+// configuration returns the configuration matching the argument
+// configuration value.
+func (d *Device) configuration(value uint8) *ConfigurationDescriptor {
+	for _, conf := range d.Configurations {
+		if conf.ConfigurationValue == value {
+			return conf
+		}
+	}
+
+	return nil
 }
 
 // AddConfiguration adds a Configuration Descriptor to a device, updating its
-// Device Descriptor configuration count accordingly.
+// Device Descriptor configuration count accordingly. Each configuration must
+// have a unique, non-zero, configuration value.
 func (d *Device) AddConfiguration(conf *ConfigurationDescriptor) (err error) {
+	if conf.ConfigurationValue == 0 || d.configuration(conf.ConfigurationValue) != nil {
+		return fmt.Errorf("invalid configuration value %d", conf.ConfigurationValue)
+	}
+
 	d.Configurations = append(d.Configurations, conf)
 
 	if d.Descriptor == nil {
@@ -461,10 +665,9 @@ func (d *Device) Configuration(wIndex uint16) (buf []byte, err error) {
 		iface := conf.Interfaces[i]
 
 		// If an IAD is present set the first interface value, unless
-		// already set, depending on where we are in the interface
-		// list.
+		// already set, depending on the interface it is attached to.
 		if iface.IAD != nil && iface.IAD.FirstInterface == 0 {
-			iface.IAD.FirstInterface = uint8(i)
+			iface.IAD.FirstInterface = iface.InterfaceNumber
 		}
 
 		buf = append(buf, iface.Bytes()...)
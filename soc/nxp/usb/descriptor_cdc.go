@@ -34,8 +34,17 @@ const (
 
 	// p64, Table 46: Class-Specific Request Codes,
 	// USB Class Definitions for Communication Devices 1.1
+	SET_LINE_CODING            = 0x20
+	GET_LINE_CODING            = 0x21
+	SET_CONTROL_LINE_STATE     = 0x22
+	SEND_BREAK                 = 0x23
 	SET_ETHERNET_PACKET_FILTER = 0x43
 
+	// Class-Specific Notification Codes,
+	// USB Class Definitions for Communication Devices 1.1
+	NETWORK_CONNECTION = 0x00
+	SERIAL_STATE       = 0x20
+
 	// Maximum Segment Size
 	MSS = 1500 + 14
 )
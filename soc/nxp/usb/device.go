@@ -10,7 +10,7 @@
 package usb
 
 import (
-	"sync"
+	"errors"
 	"time"
 
 	"github.com/usbarmory/tamago/internal/reg"
@@ -54,7 +54,11 @@ func (hw *USB) DeviceMode() {
 }
 
 // Start waits and handles configured USB endpoints in device mode, it should
-// never return. Note that isochronous endpoints are not supported.
+// never return.
+//
+// Isochronous endpoints are serviced with one transfer per endpoint function
+// invocation, the host polls the endpoint at its descriptor Interval
+// therefore the function should return data for a single service interval.
 func (hw *USB) Start(dev *Device) {
 	if dev == nil {
 		return
@@ -93,6 +97,10 @@ func (hw *USB) Start(dev *Device) {
 }
 
 // ServiceInterrupts services pending endpoint transfer and bus reset events.
+//
+// When used, as an alternative to Start(), EP1-N transfers wait for
+// completion on a per-endpoint signal, sent by this function for each
+// endpoint flagged in ENDPTCOMPLETE, rather than polling the controller.
 func (hw *USB) ServiceInterrupts() {
 	defer reg.Or(hw.sts, (1<<USBSTS_URI | 1<<USBSTS_UI))
 
@@ -118,16 +126,117 @@ func (hw *USB) ServiceInterrupts() {
 				hw.wg.Wait()
 			}
 
-			// set interrupt event announcement
-			hw.event = sync.NewCond(hw)
+			// set transfer completion signals
+			hw.initCompletion()
 
 			// start configuration endpoints
 			hw.startEndpoints()
 		}
 	}
 
-	if hw.event != nil {
-		// announce interrupt event to endpoints waiting transfer
-		hw.event.Broadcast()
+	// signal completion to endpoints waiting transfer
+	hw.signalCompletion()
+}
+
+// initCompletion allocates the EP1-N transfer completion signals, used in
+// interrupt mode (see ServiceInterrupts()).
+func (hw *USB) initCompletion() {
+	for n := 1; n < MAX_ENDPOINTS; n++ {
+		hw.done[n][OUT] = make(chan struct{}, 1)
+		hw.done[n][IN] = make(chan struct{}, 1)
+	}
+}
+
+// signalCompletion notifies each EP1-N endpoint flagged in ENDPTCOMPLETE,
+// completion bits are left to be cleared by the waiting transfer.
+func (hw *USB) signalCompletion() {
+	complete := reg.Read(hw.complete)
+
+	for n := 1; n < MAX_ENDPOINTS; n++ {
+		for _, dir := range []int{OUT, IN} {
+			done := hw.done[n][dir]
+
+			if done == nil || (complete>>((dir*16)+n))&1 == 0 {
+				continue
+			}
+
+			select {
+			case done <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// State represents a USB device state.
+type State int
+
+// USB device states (9.1.1 Visible Device States, USB2.0)
+const (
+	STATE_DETACHED State = iota
+	STATE_DEFAULT
+	STATE_ADDRESS
+	STATE_CONFIGURED
+)
+
+// String returns the device state name.
+func (s State) String() string {
+	switch s {
+	case STATE_DETACHED:
+		return "detached"
+	case STATE_DEFAULT:
+		return "default"
+	case STATE_ADDRESS:
+		return "address"
+	case STATE_CONFIGURED:
+		return "configured"
+	default:
+		return "invalid"
+	}
+}
+
+// State returns the current device state, applications can poll it to wait
+// for the host to select a configuration before starting data transfers.
+// Note that a device in any attached state can also be suspended (see
+// Suspended()).
+func (hw *USB) State() State {
+	switch {
+	case !hw.Connected():
+		return STATE_DETACHED
+	case hw.Device != nil && hw.Device.ConfigurationValue != 0:
+		return STATE_CONFIGURED
+	case reg.Get(hw.addr, DEVICEADDR_USBADR, 0x7f) != 0:
+		return STATE_ADDRESS
+	default:
+		return STATE_DEFAULT
 	}
 }
+
+// Suspended returns whether the bus is in suspend state, in device mode this
+// happens when the host stops bus activity.
+func (hw *USB) Suspended() bool {
+	return reg.Get(hw.sc, PORTSC_SUSP, 1) == 1
+}
+
+// RemoteWakeup signals resume on a suspended bus to wake up the host
+// (7.1.7.7 Resume, USB2.0), the feature must have been enabled by
+// the host (see Device.RemoteWakeup), which requires the configuration
+// descriptor to advertise remote wakeup support (Attributes bit 5).
+func (hw *USB) RemoteWakeup() (err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.Device == nil || !hw.Device.RemoteWakeup {
+		return errors.New("remote wakeup not enabled by host")
+	}
+
+	if !hw.Suspended() {
+		return errors.New("bus not suspended")
+	}
+
+	// write-1-to-clear status bits must be preserved
+	sc := reg.Read(hw.sc) &^ (1<<PORTSC_CSC | 1<<PORTSC_PEC | 1<<PORTSC_OCC)
+	reg.Write(hw.sc, sc|1<<PORTSC_FPR)
+
+	return
+}
@@ -0,0 +1,229 @@
+// USB CDC-ECM function support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package usb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ECM receive and transmit queue sizes, in Ethernet frames
+const (
+	ecmRxQueueSize = 64
+	ecmTxQueueSize = 64
+)
+
+// ECM represents a CDC Ethernet Control Model (ECM) function, implementing a
+// virtual Ethernet interface through one bulk endpoint pair and one interrupt
+// notification endpoint.
+//
+// Ethernet frames are exchanged with the host through Rx() and Tx().
+type ECM struct {
+	// Control interface
+	Control *InterfaceDescriptor
+	// Data interface
+	Data *InterfaceDescriptor
+
+	// Ethernet address of the host side of the link, advertised through
+	// the iMACAddress string descriptor.
+	HostMAC net.HardwareAddr
+
+	rx     chan []byte
+	tx     chan []byte
+	notify chan []byte
+}
+
+// NewECM builds a CDC-ECM function and adds it to the argument configuration
+// and device, the argument endpoint numbers are used for the interrupt
+// notification endpoint and the bulk data endpoint pair.
+//
+// The host side Ethernet address is passed to the host through a string
+// descriptor, it should be unique and stable across power cycles and can
+// therefore be derived from the SoC unique ID (e.g. imx6ul.UniqueID()).
+//
+// The function is added with an Interface Association Descriptor, the device
+// must therefore use the Miscellaneous Device Class (see
+// ConfigurationDescriptor.AddFunction()).
+func NewECM(dev *Device, conf *ConfigurationDescriptor, notifyEP int, dataEP int, hostMAC net.HardwareAddr) (ecm *ECM, err error) {
+	if notifyEP < 1 || notifyEP >= MAX_ENDPOINTS || dataEP < 1 || dataEP >= MAX_ENDPOINTS {
+		return nil, errors.New("invalid endpoint number")
+	}
+
+	if notifyEP == dataEP {
+		return nil, errors.New("notification and data endpoints must differ")
+	}
+
+	if len(hostMAC) != 6 {
+		return nil, errors.New("invalid Ethernet address")
+	}
+
+	ecm = &ECM{
+		HostMAC: hostMAC,
+		rx:      make(chan []byte, ecmRxQueueSize),
+		tx:      make(chan []byte, ecmTxQueueSize),
+		notify:  make(chan []byte, 1),
+	}
+
+	// p56, Table 41: Ethernet Networking Functional Descriptor,
+	// iMACAddress string format (12 hexadecimal digits)
+	iMACAddress, err := dev.AddString(fmt.Sprintf("%X", []byte(hostMAC)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	iad := &InterfaceAssociationDescriptor{}
+	iad.SetDefaults()
+	iad.FunctionClass = COMMUNICATION_INTERFACE_CLASS
+	iad.FunctionSubClass = ETH_SUBCLASS
+
+	ecm.Control = &InterfaceDescriptor{}
+	ecm.Control.SetDefaults()
+	ecm.Control.NumEndpoints = 1
+	ecm.Control.InterfaceClass = COMMUNICATION_INTERFACE_CLASS
+	ecm.Control.InterfaceSubClass = ETH_SUBCLASS
+
+	ep := &EndpointDescriptor{}
+	ep.SetDefaults()
+	ep.EndpointAddress = 0x80 | uint8(notifyEP)
+	ep.Attributes = INTERRUPT
+	ep.MaxPacketSize = 16
+	ep.Interval = 9
+	ep.Function = ecm.notifyTx
+
+	ecm.Control.Endpoints = append(ecm.Control.Endpoints, ep)
+
+	ecm.Data = &InterfaceDescriptor{}
+	ecm.Data.SetDefaults()
+	ecm.Data.NumEndpoints = 2
+	ecm.Data.InterfaceClass = DATA_INTERFACE_CLASS
+
+	ep = &EndpointDescriptor{}
+	ep.SetDefaults()
+	ep.EndpointAddress = 0x80 | uint8(dataEP)
+	ep.Attributes = BULK
+	ep.Function = ecm.dataTx
+
+	ecm.Data.Endpoints = append(ecm.Data.Endpoints, ep)
+
+	ep = &EndpointDescriptor{}
+	ep.SetDefaults()
+	ep.EndpointAddress = uint8(dataEP)
+	ep.Attributes = BULK
+	ep.Function = ecm.dataRx
+
+	ecm.Data.Endpoints = append(ecm.Data.Endpoints, ep)
+
+	if err = conf.AddFunction(iad, ecm.Control, ecm.Data); err != nil {
+		return nil, err
+	}
+
+	header := &CDCHeaderDescriptor{}
+	header.SetDefaults()
+
+	union := &CDCUnionDescriptor{}
+	union.SetDefaults()
+	union.MasterInterface = ecm.Control.InterfaceNumber
+	union.SlaveInterface0 = ecm.Data.InterfaceNumber
+
+	eth := &CDCEthernetDescriptor{}
+	eth.SetDefaults()
+	eth.MacAddress = iMACAddress
+
+	ecm.Control.ClassDescriptors = append(ecm.Control.ClassDescriptors,
+		header.Bytes(),
+		union.Bytes(),
+		eth.Bytes(),
+	)
+
+	ecm.Connect(true)
+
+	return
+}
+
+// Connect queues a NETWORK_CONNECTION notification to the host, signaling
+// the link state, replacing any pending one. A connected state is notified
+// on function creation.
+func (ecm *ECM) Connect(connected bool) {
+	var state uint16
+
+	if connected {
+		state = 1
+	}
+
+	buf := new(bytes.Buffer)
+
+	// Notification format, USB Class Definitions for Communication
+	// Devices 1.1
+	buf.WriteByte(0b10100001)
+	buf.WriteByte(NETWORK_CONNECTION)
+	binary.Write(buf, binary.LittleEndian, state)
+	binary.Write(buf, binary.LittleEndian, uint16(ecm.Control.InterfaceNumber))
+	binary.Write(buf, binary.LittleEndian, uint16(0))
+
+	select {
+	case <-ecm.notify:
+	default:
+	}
+
+	ecm.notify <- buf.Bytes()
+}
+
+func (ecm *ECM) notifyTx(_ []byte, lastErr error) (in []byte, err error) {
+	select {
+	case in = <-ecm.notify:
+	default:
+	}
+
+	return
+}
+
+func (ecm *ECM) dataTx(_ []byte, lastErr error) (in []byte, err error) {
+	select {
+	case in = <-ecm.tx:
+	default:
+	}
+
+	return
+}
+
+func (ecm *ECM) dataRx(out []byte, lastErr error) (_ []byte, err error) {
+	// frames are discarded when the receive queue is full
+	select {
+	case ecm.rx <- append([]byte{}, out...):
+	default:
+	}
+
+	return
+}
+
+// Rx returns the next Ethernet frame received from the host, or nil if none
+// is available.
+func (ecm *ECM) Rx() (buf []byte) {
+	select {
+	case buf = <-ecm.rx:
+	default:
+	}
+
+	return
+}
+
+// Tx queues an Ethernet frame for transmission to the host, blocking only
+// when the transmit queue is full.
+func (ecm *ECM) Tx(buf []byte) {
+	if len(buf) == 0 {
+		return
+	}
+
+	ecm.tx <- append([]byte{}, buf...)
+}
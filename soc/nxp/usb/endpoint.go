@@ -63,6 +63,9 @@ const (
 	TOKEN_IOC    = 15
 	TOKEN_MULTO  = 10
 	TOKEN_ACTIVE = 7
+	TOKEN_HALTED = 6
+	TOKEN_DBE    = 5
+	TOKEN_TE     = 3
 	TOKEN_STATUS = 0
 )
 
@@ -187,6 +190,8 @@ func (hw *USB) enable(n int, dir int, transferType int) {
 		}
 	}
 
+	hw.transferType[n][dir] = transferType
+
 	reg.Write(ctrl, c)
 }
 
@@ -259,6 +264,8 @@ func buildDTD(n int, dir int, addr uint32, size int) (dtd *dTD) {
 // p3800, 56.4.6.4.1 Interrupt/Bulk Endpoint Operational Model, IMX6ULLRM
 // p3811, 56.4.6.6.4 Transfer Completion, IMX6ULLRM.
 func (hw *USB) checkDTD(n int, dir int, dtds []*dTD) (size int, err error) {
+	iso := hw.transferType[n][dir] == ISOCHRONOUS
+
 	for i, dtd := range dtds {
 		// treat dtd.token as a register within the dtd DMA buffer
 		token := dtd._dtd + DTD_TOKEN
@@ -268,6 +275,13 @@ func (hw *USB) checkDTD(n int, dir int, dtds []*dTD) (size int, err error) {
 
 		dtdToken := reg.Read(token)
 
+		if iso && dtdToken&(1<<TOKEN_HALTED) == 0 {
+			// Isochronous transfers are not retried, packets missed
+			// within their (micro)frame are reported as transaction
+			// or data buffer errors which must not halt the pipe.
+			dtdToken &^= (1<<TOKEN_TE | 1<<TOKEN_DBE)
+		}
+
 		if (dtdToken & 0xff) != 0 {
 			return 0, fmt.Errorf("dTD[%d] error status, token:%#x", i, dtdToken)
 		}
@@ -277,7 +291,7 @@ func (hw *USB) checkDTD(n int, dir int, dtds []*dTD) (size int, err error) {
 		rest := dtdToken >> TOKEN_TOTAL
 		n := int(dtd._size - rest)
 
-		if dir == IN && rest > 0 {
+		if dir == IN && rest > 0 && !iso {
 			return 0, fmt.Errorf("dTD[%d] partial transfer (%d/%d bytes)", i, n, dtd._size)
 		}
 
@@ -347,13 +361,9 @@ func (hw *USB) transfer(n int, dir int, buf []byte) (out []byte, err error) {
 	// wait for priming completion
 	reg.Wait(hw.prime, pos, 1, 0)
 
-	if hw.event != nil && n != 0 {
-		// wait for completion (event)
-		for reg.Get(hw.complete, pos, 1) != 1 {
-			hw.event.L.Lock()
-			hw.event.Wait()
-			hw.event.L.Unlock()
-		}
+	if done := hw.done[n][dir]; done != nil && n != 0 {
+		// wait for completion (interrupt)
+		hw.waitCompletion(done, pos)
 	} else {
 		// wait for completion (poll)
 		reg.WaitSignal(hw.exit, hw.complete, pos, 1, 1)
@@ -364,7 +374,7 @@ func (hw *USB) transfer(n int, dir int, buf []byte) (out []byte, err error) {
 
 	size, err := hw.checkDTD(n, dir, dtds)
 
-	if n != 0 && dir == OUT && buf != nil {
+	if dir == OUT && buf != nil {
 		out = buf[0:size]
 		dma.Read(pages, 0, out)
 	}
@@ -372,6 +382,18 @@ func (hw *USB) transfer(n int, dir int, buf []byte) (out []byte, err error) {
 	return
 }
 
+// waitCompletion waits for an endpoint completion signal, sent by
+// ServiceInterrupts(), without polling.
+func (hw *USB) waitCompletion(done chan struct{}, pos int) {
+	for reg.Get(hw.complete, pos, 1) != 1 {
+		select {
+		case <-done:
+		case <-hw.exit:
+			return
+		}
+	}
+}
+
 // ack transmits a zero length packet to the host through an IN endpoint
 func (hw *USB) ack(n int) (err error) {
 	_, err = hw.transfer(n, IN, nil)
@@ -406,6 +428,58 @@ func (hw *USB) stall(n int, dir int) {
 	}
 }
 
+// unstall clears the endpoint STALL condition and resets its data toggle
+func (hw *USB) unstall(n int, dir int) {
+	ctrl := hw.epctrl + uint32(4*n)
+
+	if dir == IN {
+		reg.Clear(ctrl, ENDPTCTRL_TXS)
+	} else {
+		reg.Clear(ctrl, ENDPTCTRL_RXS)
+	}
+
+	hw.reset(n, dir)
+}
+
+// stalled returns whether the endpoint is in STALL condition
+func (hw *USB) stalled(n int, dir int) bool {
+	ctrl := hw.epctrl + uint32(4*n)
+
+	if dir == IN {
+		return reg.Get(ctrl, ENDPTCTRL_TXS, 1) == 1
+	}
+
+	return reg.Get(ctrl, ENDPTCTRL_RXS, 1) == 1
+}
+
+// Stall forces an endpoint to return a STALL handshake to the host, the
+// condition persists until cleared with Unstall() or by a host
+// CLEAR_FEATURE(ENDPOINT_HALT) request.
+//
+// A control endpoint STALL is automatically cleared by the controller on the
+// next setup packet.
+//
+// Invalid endpoint numbers or directions are ignored.
+func (hw *USB) Stall(n int, dir int) {
+	if n < 0 || n >= MAX_ENDPOINTS || (dir != IN && dir != OUT) {
+		return
+	}
+
+	hw.stall(n, dir)
+}
+
+// Unstall clears an endpoint STALL condition, resetting its data toggle as
+// required after a halt (9.4.5 Get Status, USB2.0).
+//
+// Invalid endpoint numbers or directions are ignored.
+func (hw *USB) Unstall(n int, dir int) {
+	if n < 0 || n >= MAX_ENDPOINTS || (dir != IN && dir != OUT) {
+		return
+	}
+
+	hw.unstall(n, dir)
+}
+
 // reset forces data PID synchronization between host and device
 func (hw *USB) reset(n int, dir int) {
 	if n == 0 {
@@ -53,7 +53,19 @@ func (ep *endpoint) Init() {
 	ep.n = ep.desc.Number()
 	ep.dir = ep.desc.Direction()
 
-	ep.bus.set(ep.n, ep.dir, int(ep.desc.MaxPacketSize), ep.desc.Zero, 0)
+	max := ep.desc.MaxPacketLength()
+	zlt := ep.desc.Zero
+	mult := 0
+
+	if ep.desc.TransferType() == ISOCHRONOUS {
+		// p3784, 56.4.5.1 Endpoint Queue Head (dQH), IMX6ULLRM
+		// isochronous endpoints require the number of packets per
+		// (micro)frame, zero length termination does not apply.
+		mult = ep.desc.Transactions()
+		zlt = false
+	}
+
+	ep.bus.set(ep.n, ep.dir, max, zlt, mult)
 	ep.bus.enable(ep.n, ep.dir, ep.desc.TransferType())
 }
 
@@ -87,7 +99,9 @@ func (ep *endpoint) Start() {
 			ep.tx()
 		}
 
-		if ep.err != nil {
+		// isochronous endpoints cannot be halted, errors are
+		// reported to the next endpoint function invocation
+		if ep.err != nil && ep.desc.TransferType() != ISOCHRONOUS {
 			ep.bus.stall(ep.n, ep.dir)
 		}
 
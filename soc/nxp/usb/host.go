@@ -0,0 +1,688 @@
+// USB host mode support
+// https://github.com/usbarmory/tamago
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package usb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/usbarmory/tamago/bits"
+	"github.com/usbarmory/tamago/dma"
+	"github.com/usbarmory/tamago/internal/reg"
+)
+
+// Host mode constants
+const (
+	// Link pointer fields
+	LINK_TYP_QH = 0b01 << 1
+	LINK_T      = 1
+
+	// 3.6 Queue Head, EHCI
+	QH_ALIGN = 32
+
+	QH_LINK = 0
+
+	QH_INFO  = 4
+	QH_RL    = 28
+	QH_C     = 27
+	QH_MPL   = 16
+	QH_H     = 15
+	QH_DTC   = 14
+	QH_EPS   = 12
+	QH_ENDPT = 8
+	QH_ADDR  = 0
+
+	QH_CAPS = 8
+	QH_MULT = 30
+
+	QH_OVERLAY_TOKEN = 24
+
+	// 3.5 Queue Element Transfer Descriptor (qTD), EHCI
+	QTD_ALIGN     = 32
+	QTD_SIZE      = 32
+	QTD_PAGES     = 5
+	QTD_PAGE_SIZE = 4096
+
+	QTD_TOKEN     = 8
+	QTD_DT        = 31
+	QTD_TOTAL     = 16
+	QTD_IOC       = 15
+	QTD_CERR      = 10
+	QTD_PID       = 8
+	QTD_ACTIVE    = 7
+	QTD_HALTED    = 6
+	QTD_STATUS    = 0
+	QTD_ERR_MASK  = 0b01111100
+	QTD_TOTAL_MAX = 0x7fff
+
+	// PID codes
+	PID_OUT   = 0b00
+	PID_IN    = 0b01
+	PID_SETUP = 0b10
+)
+
+// Host mode timeouts
+const (
+	HOST_CONNECT_TIMEOUT  = 1 * time.Second
+	HOST_TRANSFER_TIMEOUT = 5 * time.Second
+
+	// 7.1.7.3 Connect and Disconnect Signaling, USB2.0
+	HOST_DEBOUNCE = 100 * time.Millisecond
+	// 7.1.7.5 Reset Signaling, USB2.0
+	HOST_RESET_TIMEOUT  = 100 * time.Millisecond
+	HOST_RESET_RECOVERY = 10 * time.Millisecond
+	// 9.2.6.3 Set Address Processing, USB2.0
+	HOST_SET_ADDRESS_RECOVERY = 2 * time.Millisecond
+)
+
+// qTD implements
+// 3.5 Queue Element Transfer Descriptor (qTD), EHCI.
+type qTD struct {
+	Next    uint32
+	AltNext uint32
+	Token   uint32
+	Buffer  [QTD_PAGES]uint32
+}
+
+// qH implements
+// 3.6 Queue Head, EHCI.
+type qH struct {
+	Link    uint32
+	Info    uint32
+	Caps    uint32
+	Current uint32
+	Overlay qTD
+}
+
+// HostDevice represents a USB device attached to a controller in host mode.
+type HostDevice struct {
+	// Device address
+	Address uint8
+	// Device descriptor
+	Descriptor *DeviceDescriptor
+
+	bus   *USB
+	speed uint32
+
+	// EP0 maximum packet size
+	maxPacketSize int
+	// maximum packet size for each endpoint address
+	endpoints map[uint8]int
+	// data toggle for each endpoint address
+	toggle map[uint8]uint32
+}
+
+// HostInit sets the USB controller in host mode, initializing the
+// asynchronous schedule used for control and bulk transfers.
+//
+// Note that the periodic schedule, required by interrupt and isochronous
+// transfers, is not supported.
+func (hw *USB) HostInit() {
+	hw.Lock()
+	defer hw.Unlock()
+
+	reg.Set(hw.cmd, USBCMD_RST)
+	reg.Wait(hw.cmd, USBCMD_RST, 1, 0)
+
+	// set host controller
+	reg.SetN(hw.mode, USBMODE_CM, 0b11, USBMODE_CM_HOST)
+	reg.Wait(hw.mode, USBMODE_CM, 0b11, USBMODE_CM_HOST)
+
+	// 4.8 Asynchronous Schedule, EHCI
+	if hw.asyncHead == 0 {
+		head := &qH{}
+
+		// head of reclamation list
+		bits.Set(&head.Info, QH_H)
+		// invalidate overlay pointers
+		head.Overlay.Next = LINK_T
+		head.Overlay.AltNext = LINK_T
+		bits.Set(&head.Overlay.Token, QTD_HALTED)
+
+		buf := new(bytes.Buffer)
+		binary.Write(buf, binary.LittleEndian, head)
+
+		hw.asyncHead = uint32(dma.Alloc(buf.Bytes(), QH_ALIGN))
+	}
+
+	// the head points to itself in an empty schedule
+	reg.Write(hw.asyncHead+QH_LINK, hw.asyncHead|LINK_TYP_QH)
+	reg.Write(hw.eplist, hw.asyncHead)
+
+	// enable port power, preserving write-1-to-clear change bits
+	sc := reg.Read(hw.sc) &^ (1<<PORTSC_CSC | 1<<PORTSC_PEC | 1<<PORTSC_OCC)
+	reg.Write(hw.sc, sc|1<<PORTSC_PP)
+
+	// clear pending interrupts
+	reg.WriteBack(hw.sts)
+
+	// run
+	reg.Set(hw.cmd, USBCMD_RS)
+	reg.Wait(hw.sts, USBSTS_HCH, 1, 0)
+}
+
+// Enumerate waits for a device connection on the root port, resets the port
+// and performs the device initial enumeration by fetching its descriptor and
+// assigning it an address, the device is not configured (see
+// HostDevice.SetConfiguration()).
+func (hw *USB) Enumerate() (dev *HostDevice, err error) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	if !reg.WaitFor(HOST_CONNECT_TIMEOUT, hw.sc, PORTSC_CCS, 1, 1) {
+		return nil, errors.New("no device connected")
+	}
+
+	time.Sleep(HOST_DEBOUNCE)
+
+	// write-1-to-clear status bits must be preserved
+	sc := reg.Read(hw.sc) &^ (1<<PORTSC_CSC | 1<<PORTSC_PEC | 1<<PORTSC_OCC)
+	reg.Write(hw.sc, sc|1<<PORTSC_PR)
+
+	// the port reset is cleared by hardware on completion
+	if !reg.WaitFor(HOST_RESET_TIMEOUT, hw.sc, PORTSC_PR, 1, 0) {
+		return nil, errors.New("port reset timeout")
+	}
+
+	if reg.Get(hw.sc, PORTSC_PE, 1) != 1 {
+		return nil, errors.New("port not enabled")
+	}
+
+	time.Sleep(HOST_RESET_RECOVERY)
+
+	dev = &HostDevice{
+		bus:       hw,
+		speed:     reg.Get(hw.sc, PORTSC_PSPD, 0b11),
+		endpoints: make(map[uint8]int),
+		toggle:    make(map[uint8]uint32),
+	}
+
+	switch dev.speed {
+	case 0b10:
+		dev.maxPacketSize = 64
+	default:
+		dev.maxPacketSize = 8
+	}
+
+	// fetch EP0 maximum packet size
+	desc, err := dev.getDescriptor(DEVICE, 0, 8)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(desc) < 8 {
+		return nil, errors.New("invalid device descriptor")
+	}
+
+	switch desc[7] {
+	case 8, 16, 32, 64:
+		dev.maxPacketSize = int(desc[7])
+	default:
+		return nil, fmt.Errorf("invalid EP0 maximum packet size %d", desc[7])
+	}
+
+	if err = dev.setAddress(1); err != nil {
+		return nil, err
+	}
+
+	if desc, err = dev.getDescriptor(DEVICE, 0, DEVICE_LENGTH); err != nil {
+		return nil, err
+	}
+
+	if len(desc) < DEVICE_LENGTH {
+		return nil, errors.New("invalid device descriptor")
+	}
+
+	dev.Descriptor = parseDeviceDescriptor(desc)
+
+	return
+}
+
+func parseDeviceDescriptor(buf []byte) (d *DeviceDescriptor) {
+	d = &DeviceDescriptor{
+		Length:            buf[0],
+		DescriptorType:    buf[1],
+		bcdUSB:            binary.LittleEndian.Uint16(buf[2:]),
+		DeviceClass:       buf[4],
+		DeviceSubClass:    buf[5],
+		DeviceProtocol:    buf[6],
+		MaxPacketSize:     buf[7],
+		VendorId:          binary.LittleEndian.Uint16(buf[8:]),
+		ProductId:         binary.LittleEndian.Uint16(buf[10:]),
+		Device:            binary.LittleEndian.Uint16(buf[12:]),
+		Manufacturer:      buf[14],
+		Product:           buf[15],
+		SerialNumber:      buf[16],
+		NumConfigurations: buf[17],
+	}
+
+	return
+}
+
+// buildQTD configures a queue element transfer descriptor as described in
+// 3.5 Queue Element Transfer Descriptor (qTD), EHCI.
+func buildQTD(pid uint32, toggle uint32, addr uint32, size int) (td *qTD) {
+	td = &qTD{
+		Next:    LINK_T,
+		AltNext: LINK_T,
+	}
+
+	bits.SetN(&td.Token, QTD_DT, 1, toggle)
+	bits.SetN(&td.Token, QTD_TOTAL, QTD_TOTAL_MAX, uint32(size))
+	bits.SetN(&td.Token, QTD_CERR, 0b11, 3)
+	bits.SetN(&td.Token, QTD_PID, 0b11, pid)
+	bits.Set(&td.Token, QTD_ACTIVE)
+
+	if size > 0 {
+		td.Buffer[0] = addr
+
+		for n := 1; n < QTD_PAGES; n++ {
+			td.Buffer[n] = (addr &^ (QTD_PAGE_SIZE - 1)) + QTD_PAGE_SIZE*uint32(n)
+		}
+	}
+
+	return
+}
+
+// packets returns the number of packets required to move the argument size.
+func packets(size int, max int) int {
+	if size == 0 {
+		return 1
+	}
+
+	return (size + max - 1) / max
+}
+
+// transfer executes a control (when setup is not nil) or bulk transfer on the
+// asynchronous schedule as described in
+// 4.10 Management of Control/Bulk/Interrupt Transfers via Queue Heads, EHCI.
+func (dev *HostDevice) transfer(epAddr uint8, setup *SetupData, buf []byte) (n int, err error) {
+	var tds []*qTD
+	var pages uint
+	var toggle uint32
+
+	hw := dev.bus
+	ep := uint32(epAddr & 0b1111)
+	dir := int(epAddr>>7) & 1
+
+	max := dev.maxPacketSize
+
+	if ep != 0 {
+		if max = dev.endpoints[epAddr]; max == 0 {
+			return 0, fmt.Errorf("unknown endpoint %#x", epAddr)
+		}
+
+		toggle = dev.toggle[epAddr]
+	}
+
+	pid := uint32(PID_OUT)
+
+	if dir == IN {
+		pid = PID_IN
+	}
+
+	if setup != nil {
+		var setupAddr uint
+
+		b := new(bytes.Buffer)
+		binary.Write(b, binary.LittleEndian, setup)
+
+		setupAddr = dma.Alloc(b.Bytes(), QTD_ALIGN)
+		defer dma.Free(setupAddr)
+
+		tds = append(tds, buildQTD(PID_SETUP, 0, uint32(setupAddr), len(b.Bytes())))
+		toggle = 1
+	}
+
+	if len(buf) > 0 {
+		pages = dma.Alloc(buf, QTD_PAGE_SIZE)
+		defer dma.Free(pages)
+	}
+
+	qtdLength := QTD_PAGES * QTD_PAGE_SIZE
+	first := len(tds)
+
+	for i := 0; i < len(buf); i += qtdLength {
+		size := qtdLength
+
+		if i+size > len(buf) {
+			size = len(buf) - i
+		}
+
+		tds = append(tds, buildQTD(pid, toggle, uint32(pages)+uint32(i), size))
+		toggle ^= uint32(packets(size, max) & 1)
+	}
+
+	last := len(tds)
+
+	if setup != nil {
+		// 8.5.3 Control Transfers, USB2.0
+		status := uint32(PID_IN)
+
+		if dir == IN && len(buf) > 0 {
+			status = PID_OUT
+		}
+
+		tds = append(tds, buildQTD(status, 1, 0, 0))
+	} else if len(buf) == 0 {
+		// zero length packet
+		tds = append(tds, buildQTD(pid, toggle, 0, 0))
+		last = len(tds)
+	}
+
+	// interrupt on completion
+	bits.Set(&tds[len(tds)-1].Token, QTD_IOC)
+
+	addrs := make([]uint32, len(tds))
+
+	// allocate in reverse order to link each qTD to the next one
+	for i := len(tds) - 1; i >= 0; i-- {
+		if i < len(tds)-1 {
+			tds[i].Next = addrs[i+1]
+		}
+
+		b := new(bytes.Buffer)
+		binary.Write(b, binary.LittleEndian, tds[i])
+
+		addrs[i] = uint32(dma.Alloc(b.Bytes(), QTD_ALIGN))
+		defer dma.Free(uint(addrs[i]))
+	}
+
+	qh := &qH{}
+
+	bits.SetN(&qh.Info, QH_MPL, 0x7ff, uint32(max))
+	bits.SetN(&qh.Info, QH_EPS, 0b11, dev.speed)
+	bits.SetN(&qh.Info, QH_ENDPT, 0b1111, ep)
+	bits.SetN(&qh.Info, QH_ADDR, 0x7f, uint32(dev.Address))
+	// data toggle from qTD
+	bits.Set(&qh.Info, QH_DTC)
+
+	if ep == 0 && dev.speed != 0b10 {
+		// full/low speed control endpoint
+		bits.Set(&qh.Info, QH_C)
+	}
+
+	bits.SetN(&qh.Caps, QH_MULT, 0b11, 1)
+
+	qh.Link = reg.Read(hw.asyncHead + QH_LINK)
+	qh.Overlay.Next = addrs[0]
+	qh.Overlay.AltNext = LINK_T
+
+	b := new(bytes.Buffer)
+	binary.Write(b, binary.LittleEndian, qh)
+
+	qhAddr := uint32(dma.Alloc(b.Bytes(), QH_ALIGN))
+	defer dma.Free(uint(qhAddr))
+
+	// link queue head and enable the asynchronous schedule
+	reg.Write(hw.asyncHead+QH_LINK, qhAddr|LINK_TYP_QH)
+	reg.Set(hw.cmd, USBCMD_ASE)
+	reg.Wait(hw.sts, USBSTS_AS, 1, 1)
+
+	start := time.Now()
+	lastToken := addrs[len(addrs)-1] + QTD_TOKEN
+
+	for reg.Get(lastToken, QTD_ACTIVE, 1) == 1 {
+		if reg.Get(qhAddr+QH_OVERLAY_TOKEN, QTD_HALTED, 1) == 1 {
+			break
+		}
+
+		if time.Since(start) >= HOST_TRANSFER_TIMEOUT {
+			err = errors.New("transfer timeout")
+			break
+		}
+
+		runtime.Gosched()
+	}
+
+	// disable the asynchronous schedule and unlink queue head
+	reg.Clear(hw.cmd, USBCMD_ASE)
+	reg.Wait(hw.sts, USBSTS_AS, 1, 0)
+	reg.Write(hw.asyncHead+QH_LINK, hw.asyncHead|LINK_TYP_QH)
+
+	if err != nil {
+		return
+	}
+
+	for i, addr := range addrs {
+		token := reg.Read(addr + QTD_TOKEN)
+
+		if token&(1<<QTD_HALTED) != 0 {
+			if token&QTD_ERR_MASK == 0 {
+				return 0, fmt.Errorf("endpoint %#x stalled", epAddr)
+			}
+
+			return 0, fmt.Errorf("qTD[%d] error status, token:%#x", i, token)
+		}
+
+		if i >= first && i < last {
+			n += int(tds[i].Token>>QTD_TOTAL&QTD_TOTAL_MAX) - int(token>>QTD_TOTAL&QTD_TOTAL_MAX)
+		}
+	}
+
+	if ep != 0 {
+		// the overlay holds the toggle for the next transaction
+		dev.toggle[epAddr] = reg.Get(qhAddr+QH_OVERLAY_TOKEN, QTD_DT, 1)
+	}
+
+	if dir == IN && n > 0 {
+		dma.Read(pages, 0, buf[0:n])
+	}
+
+	return
+}
+
+// control performs a control transfer on the default pipe.
+func (dev *HostDevice) control(setup *SetupData, data []byte) (in []byte, err error) {
+	var n int
+
+	dir := (setup.RequestType >> REQUEST_TYPE_DIR) & 1
+
+	if dir == IN {
+		data = make([]byte, setup.Length)
+	}
+
+	if n, err = dev.transfer(dir<<7, setup, data); err != nil {
+		return
+	}
+
+	if dir == IN {
+		in = data[0:n]
+	}
+
+	return
+}
+
+func (dev *HostDevice) getDescriptor(descriptorType uint8, index uint8, length uint16) ([]byte, error) {
+	setup := &SetupData{
+		RequestType: 1 << REQUEST_TYPE_DIR,
+		Request:     GET_DESCRIPTOR,
+		Value:       uint16(descriptorType)<<8 | uint16(index),
+		Length:      length,
+	}
+
+	return dev.control(setup, nil)
+}
+
+func (dev *HostDevice) setAddress(addr uint8) (err error) {
+	setup := &SetupData{
+		Request: SET_ADDRESS,
+		Value:   uint16(addr),
+	}
+
+	if _, err = dev.control(setup, nil); err != nil {
+		return
+	}
+
+	dev.Address = addr
+	time.Sleep(HOST_SET_ADDRESS_RECOVERY)
+
+	return
+}
+
+// Control performs a control transfer, with the argument setup packet, on the
+// device default pipe. On IN requests the received data is returned, on OUT
+// requests the data argument is transmitted.
+func (dev *HostDevice) Control(setup *SetupData, data []byte) (in []byte, err error) {
+	dev.bus.Lock()
+	defer dev.bus.Unlock()
+
+	return dev.control(setup, data)
+}
+
+// GetDescriptor requests a device descriptor of the argument type and index.
+func (dev *HostDevice) GetDescriptor(descriptorType uint8, index uint8, length uint16) ([]byte, error) {
+	dev.bus.Lock()
+	defer dev.bus.Unlock()
+
+	return dev.getDescriptor(descriptorType, index, length)
+}
+
+// SetAddress assigns the argument address to the device.
+func (dev *HostDevice) SetAddress(addr uint8) (err error) {
+	dev.bus.Lock()
+	defer dev.bus.Unlock()
+
+	if addr == 0 || addr > 127 {
+		return errors.New("invalid address")
+	}
+
+	return dev.setAddress(addr)
+}
+
+// Configuration returns the full configuration descriptor, including its
+// interface and endpoint descriptors, at the argument index.
+func (dev *HostDevice) Configuration(index uint8) (conf []byte, err error) {
+	dev.bus.Lock()
+	defer dev.bus.Unlock()
+
+	return dev.configuration(index)
+}
+
+func (dev *HostDevice) configuration(index uint8) (conf []byte, err error) {
+	if conf, err = dev.getDescriptor(CONFIGURATION, index, CONFIGURATION_LENGTH); err != nil {
+		return
+	}
+
+	if len(conf) < CONFIGURATION_LENGTH {
+		return nil, errors.New("invalid configuration descriptor")
+	}
+
+	if conf, err = dev.getDescriptor(CONFIGURATION, index, binary.LittleEndian.Uint16(conf[2:])); err != nil {
+		return
+	}
+
+	if len(conf) < CONFIGURATION_LENGTH {
+		return nil, errors.New("invalid configuration descriptor")
+	}
+
+	return
+}
+
+// SetConfiguration selects the device configuration matching the argument
+// value, endpoint descriptors of the selected configuration are parsed to
+// enable bulk transfers on its endpoints.
+func (dev *HostDevice) SetConfiguration(value uint8) (err error) {
+	var conf []byte
+
+	dev.bus.Lock()
+	defer dev.bus.Unlock()
+
+	if dev.Descriptor == nil {
+		return errors.New("device not enumerated")
+	}
+
+	for i := uint8(0); i < dev.Descriptor.NumConfigurations; i++ {
+		if conf, err = dev.configuration(i); err != nil {
+			return
+		}
+
+		if conf[5] == value {
+			break
+		}
+
+		conf = nil
+	}
+
+	if conf == nil {
+		return fmt.Errorf("invalid configuration value %d", value)
+	}
+
+	setup := &SetupData{
+		Request: SET_CONFIGURATION,
+		Value:   uint16(value),
+	}
+
+	if _, err = dev.control(setup, nil); err != nil {
+		return
+	}
+
+	dev.endpoints = make(map[uint8]int)
+	dev.toggle = make(map[uint8]uint32)
+
+	for i := 0; i+1 < len(conf) && conf[i] > 0; i += int(conf[i]) {
+		if conf[i+1] != ENDPOINT || i+ENDPOINT_LENGTH > len(conf) {
+			continue
+		}
+
+		addr := conf[i+2]
+		dev.endpoints[addr] = int(binary.LittleEndian.Uint16(conf[i+4:]) & 0x7ff)
+	}
+
+	return
+}
+
+// ClearHalt clears the halt condition of the argument endpoint address, also
+// resetting its data toggle.
+func (dev *HostDevice) ClearHalt(epAddr uint8) (err error) {
+	dev.bus.Lock()
+	defer dev.bus.Unlock()
+
+	setup := &SetupData{
+		RequestType: 0b10,
+		Request:     CLEAR_FEATURE,
+		Value:       ENDPOINT_HALT,
+		Index:       uint16(epAddr),
+	}
+
+	if _, err = dev.control(setup, nil); err != nil {
+		return
+	}
+
+	dev.toggle[epAddr] = 0
+
+	return
+}
+
+// BulkIn receives up to size bytes from the argument IN endpoint number.
+func (dev *HostDevice) BulkIn(n int, size int) (buf []byte, err error) {
+	dev.bus.Lock()
+	defer dev.bus.Unlock()
+
+	buf = make([]byte, size)
+	size, err = dev.transfer(uint8(n&0b1111)|0x80, nil, buf)
+
+	return buf[0:size], err
+}
+
+// BulkOut transmits a data buffer to the argument OUT endpoint number.
+func (dev *HostDevice) BulkOut(n int, buf []byte) (err error) {
+	dev.bus.Lock()
+	defer dev.bus.Unlock()
+
+	_, err = dev.transfer(uint8(n&0b1111), nil, buf)
+
+	return
+}
@@ -11,6 +11,7 @@ package usb
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 
 	"github.com/usbarmory/tamago/internal/reg"
@@ -18,7 +19,16 @@ import (
 
 // Format of Setup Data (p276, Table 9-2, USB2.0)
 const (
-	REQUEST_TYPE_DIR = 7
+	REQUEST_TYPE_DIR  = 7
+	REQUEST_TYPE_TYPE = 5
+
+	TYPE_STANDARD = 0
+	TYPE_CLASS    = 1
+	TYPE_VENDOR   = 2
+
+	RECIPIENT_DEVICE    = 0
+	RECIPIENT_INTERFACE = 1
+	RECIPIENT_ENDPOINT  = 2
 )
 
 // Standard request codes (p279, Table 9-4, USB2.0)
@@ -51,6 +61,8 @@ const (
 	OTG                   = 9
 	DEBUG                 = 10
 	INTERFACE_ASSOCIATION = 11
+	BOS                   = 15
+	DEVICE_CAPABILITY     = 16
 )
 
 // Standard feature selectors (p280, Table 9-6, USB2.0)
@@ -121,6 +133,13 @@ func (hw *USB) getDescriptor(setup *SetupData) (err error) {
 		}
 	case DEVICE_QUALIFIER:
 		err = hw.tx(0, hw.Device.Qualifier.Bytes())
+	case BOS:
+		if hw.Device.BOS == nil {
+			hw.stall(0, IN)
+			err = errors.New("BOS descriptor not available")
+		} else {
+			err = hw.tx(0, trim(hw.Device.BOS.Bytes(), setup.Length))
+		}
 	default:
 		hw.stall(0, IN)
 		err = fmt.Errorf("unsupported descriptor type: %#x", bDescriptorType)
@@ -136,8 +155,15 @@ func (hw *USB) handleSetup() (conf uint8, err error) {
 		return
 	}
 
-	if hw.Device.Setup != nil {
-		in, ack, done, err := hw.Device.Setup(setup)
+	setupFn := hw.Device.Setup
+
+	// route class/vendor interface requests to the interface handler
+	if fn := hw.interfaceSetup(setup); fn != nil {
+		setupFn = fn
+	}
+
+	if setupFn != nil {
+		in, ack, done, err := setupFn(setup)
 
 		if err != nil {
 			hw.stall(0, IN)
@@ -155,15 +181,63 @@ func (hw *USB) handleSetup() (conf uint8, err error) {
 
 	switch setup.Request {
 	case GET_STATUS:
-		// no meaningful status to report for now
-		err = hw.tx(0, []byte{0x00, 0x00})
+		status := []byte{0x00, 0x00}
+
+		// Figure 9-4. Information Returned by a GetStatus() Request to a Device, USB2.0
+		switch setup.RequestType & 0b11111 {
+		case RECIPIENT_DEVICE:
+			if hw.Device.RemoteWakeup {
+				status[0] |= 1 << DEVICE_REMOTE_WAKEUP
+			}
+		case RECIPIENT_ENDPOINT:
+			n := int(setup.Index & 0xf)
+			dir := int(setup.Index&0x80) / 0x80
+
+			if n >= MAX_ENDPOINTS {
+				hw.stall(0, IN)
+				return 0, fmt.Errorf("invalid endpoint %d", n)
+			}
+
+			if hw.stalled(n, dir) {
+				status[0] |= 1 << ENDPOINT_HALT
+			}
+		}
+
+		err = hw.tx(0, status)
 	case CLEAR_FEATURE:
-		switch setup.Value {
+		switch setup.Value >> 8 {
+		case ENDPOINT_HALT:
+			n := int(setup.Index & 0xf)
+			dir := int(setup.Index&0x80) / 0x80
+
+			if n >= MAX_ENDPOINTS {
+				hw.stall(0, IN)
+				return 0, fmt.Errorf("invalid endpoint %d", n)
+			}
+
+			hw.unstall(n, dir)
+			err = hw.ack(0)
+		case DEVICE_REMOTE_WAKEUP:
+			hw.Device.RemoteWakeup = false
+			err = hw.ack(0)
+		default:
+			hw.stall(0, IN)
+		}
+	case SET_FEATURE:
+		switch setup.Value >> 8 {
 		case ENDPOINT_HALT:
 			n := int(setup.Index & 0xf)
 			dir := int(setup.Index&0x80) / 0x80
 
-			hw.reset(n, dir)
+			if n >= MAX_ENDPOINTS {
+				hw.stall(0, IN)
+				return 0, fmt.Errorf("invalid endpoint %d", n)
+			}
+
+			err = hw.ack(0)
+			hw.stall(n, dir)
+		case DEVICE_REMOTE_WAKEUP:
+			hw.Device.RemoteWakeup = true
 			err = hw.ack(0)
 		default:
 			hw.stall(0, IN)
@@ -182,6 +256,11 @@ func (hw *USB) handleSetup() (conf uint8, err error) {
 	case SET_CONFIGURATION:
 		conf = uint8(setup.Value >> 8)
 
+		if conf != 0 && hw.Device.configuration(conf) == nil {
+			hw.stall(0, IN)
+			return 0, fmt.Errorf("invalid configuration value %d", conf)
+		}
+
 		if hw.Device.ConfigurationValue != conf {
 			hw.Device.ConfigurationValue = conf
 		} else {
@@ -210,6 +289,34 @@ func (hw *USB) handleSetup() (conf uint8, err error) {
 	return
 }
 
+// interfaceSetup returns the setup handler of the interface targeted by a
+// class or vendor request, if any.
+func (hw *USB) interfaceSetup(setup *SetupData) SetupFunction {
+	if setup.RequestType&0b11111 != RECIPIENT_INTERFACE {
+		return nil
+	}
+
+	if (setup.RequestType>>REQUEST_TYPE_TYPE)&0b11 == TYPE_STANDARD {
+		return nil
+	}
+
+	conf := hw.Device.configuration(hw.Device.ConfigurationValue)
+
+	if conf == nil {
+		return nil
+	}
+
+	n := uint8(setup.Index & 0xff)
+
+	for _, iface := range conf.Interfaces {
+		if iface.InterfaceNumber == n && iface.Setup != nil {
+			return iface.Setup
+		}
+	}
+
+	return nil
+}
+
 func trim(buf []byte, wLength uint16) []byte {
 	if int(wLength) < len(buf) {
 		buf = buf[0:wLength]
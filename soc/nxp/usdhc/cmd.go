@@ -12,6 +12,7 @@
 package usdhc
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -72,6 +73,8 @@ var cmds = map[uint32]cmdParams{
 	//  SD: CMD3 - SEND_RELATIVE_ADDR - get relative card address (RCA)
 	// MMC: CMD3 -  SET_RELATIVE_ADDR - set relative card address (RCA
 	3: {READ, RSP_48, true, true},
+	// SDIO: CMD5 - IO_SEND_OP_COND - send operating conditions
+	5: {READ, RSP_48, false, false},
 	// CMD6 - SWITCH - switch mode of operation
 	6: {READ, RSP_48_CHECK_BUSY, true, true},
 	// CMD7 - SELECT/DESELECT CARD - enter transfer state
@@ -93,16 +96,61 @@ var cmds = map[uint32]cmdParams{
 	18: {READ, RSP_48, true, true},
 	// CMD19 - send tuning block command, ignore responses
 	19: {READ, RSP_48, true, true},
+	// MMC: CMD21 - SEND_TUNING_BLOCK - send tuning block command, ignore responses
+	21: {READ, RSP_48, true, true},
 	// CMD23 - SET_BLOCK_COUNT - define read/write block count
 	23: {READ, RSP_48, true, true},
 	// CMD25 - WRITE_MULTIPLE_BLOCK - write consecutive blocks
 	25: {WRITE, RSP_48, true, true},
+	// SD: CMD32 - ERASE_WR_BLK_START - set first block to erase
+	32: {READ, RSP_48, true, true},
+	// SD: CMD33 - ERASE_WR_BLK_END - set last block to erase
+	33: {READ, RSP_48, true, true},
+	// MMC: CMD35 - ERASE_GROUP_START - set first erase group
+	35: {READ, RSP_48, true, true},
+	// MMC: CMD36 - ERASE_GROUP_END - set last erase group
+	36: {READ, RSP_48, true, true},
+	// CMD38 - ERASE - erase selected blocks
+	38: {READ, RSP_48_CHECK_BUSY, true, true},
 	// SD: ACMD41 - SD_SEND_OP_COND - read capacity information
 	41: {READ, RSP_48, false, false},
+	// SD: ACMD51 - SEND_SCR - read SD configuration register
+	51: {READ, RSP_48, true, true},
+	// SDIO: CMD52 - IO_RW_DIRECT - access a single register
+	52: {READ, RSP_48, true, true},
+	// SDIO: CMD53 - IO_RW_EXTENDED - access multiple registers
+	53: {READ, RSP_48, true, true},
 	// SD: CMD55 - APP_CMD - next command is application specific
 	55: {READ, RSP_48, true, true},
 }
 
+// Command and data transfer errors, reported through INT_STATUS, which can be
+// matched with errors.Is() on errors returned by transfer functions (e.g.
+// ReadBlocks(), WriteBlocks()).
+var (
+	ErrCommandTimeout = errors.New("command timeout")
+	ErrCommandCRC     = errors.New("command CRC error")
+	ErrDataTimeout    = errors.New("data timeout")
+	ErrDataCRC        = errors.New("data CRC error")
+)
+
+// statusError returns the error matching the INT_STATUS error bits, if any
+// among the ones with a corresponding error value.
+func statusError(status uint32) error {
+	switch {
+	case bits.Get(&status, INT_STATUS_CTOE, 1) == 1:
+		return ErrCommandTimeout
+	case bits.Get(&status, INT_STATUS_CCE, 1) == 1:
+		return ErrCommandCRC
+	case bits.Get(&status, INT_STATUS_DTOE, 1) == 1:
+		return ErrDataTimeout
+	case bits.Get(&status, INT_STATUS_DCE, 1) == 1:
+		return ErrDataCRC
+	}
+
+	return nil
+}
+
 // cmd sends an SD / MMC command as described in
 // p349, 35.4.3 Send command to card flow chart, IMX6FG
 func (hw *USDHC) cmd(index uint32, arg uint32, blocks uint32, timeout time.Duration) (err error) {
@@ -112,6 +160,11 @@ func (hw *USDHC) cmd(index uint32, arg uint32, blocks uint32, timeout time.Durat
 		return fmt.Errorf("CMD%d unsupported", index)
 	}
 
+	// SDIO: CMD53 transfer direction is set by its R/W flag
+	if index == 53 && (arg>>IO_RW_FLAG)&1 == 1 {
+		params.dtd = WRITE
+	}
+
 	if timeout == 0 {
 		timeout = DEFAULT_CMD_TIMEOUT
 	}
@@ -135,7 +188,7 @@ func (hw *USDHC) cmd(index uint32, arg uint32, blocks uint32, timeout time.Durat
 	// clear interrupts status
 	reg.Write(hw.int_status, 0xffffffff)
 
-	if params.dtd == WRITE && reg.Get(hw.pres_state, PRES_STATE_WPSPL, 1) == 0 {
+	if params.dtd == WRITE && !hw.card.SDIO && hw.WriteProtected() {
 		// The uSDHC merely reports on WP, it doesn't really act on it
 		// despite IMX6ULLRM suggesting otherwise (e.g. p4017).
 		return fmt.Errorf("card is write protected")
@@ -145,7 +198,16 @@ func (hw *USDHC) cmd(index uint32, arg uint32, blocks uint32, timeout time.Durat
 		if err != nil {
 			reg.Clear(hw.pres_state, PRES_STATE_CIHB)
 			reg.Clear(hw.pres_state, PRES_STATE_CDIHB)
+
+			// reset the command line
 			reg.Set(hw.sys_ctrl, SYS_CTRL_RSTC)
+			reg.Wait(hw.sys_ctrl, SYS_CTRL_RSTC, 1, 0)
+
+			if blocks > 0 {
+				// reset the data line
+				reg.Set(hw.sys_ctrl, SYS_CTRL_RSTD)
+				reg.Wait(hw.sys_ctrl, SYS_CTRL_RSTD, 1, 0)
+			}
 		}
 	}()
 
@@ -186,8 +248,9 @@ func (hw *USDHC) cmd(index uint32, arg uint32, blocks uint32, timeout time.Durat
 		bits.Set(&xfr, CMD_XFR_TYP_DPSEL)
 		// enable DMA
 		bits.Set(&mix, MIX_CTRL_DMAEN)
-		// enable automatic CMD12 to stop transactions
-		bits.Set(&mix, MIX_CTRL_AC12EN)
+		// enable automatic CMD12 to stop transactions (not for SDIO
+		// or predefined block count transfers)
+		bits.SetTo(&mix, MIX_CTRL_AC12EN, !(hw.card.SDIO || hw.predefined))
 		// multiple blocks
 		bits.SetTo(&mix, MIX_CTRL_MSBSEL, blocks > 1)
 		// block count
@@ -210,9 +273,16 @@ func (hw *USDHC) cmd(index uint32, arg uint32, blocks uint32, timeout time.Durat
 
 	// wait for completion
 	if !reg.WaitFor(timeout, hw.int_status, int_status, 1, 1) {
-		err = fmt.Errorf("CMD%d:timeout pres_state:%#x int_status:%#x", index,
+		e := ErrCommandTimeout
+
+		if blocks > 0 {
+			e = ErrDataTimeout
+		}
+
+		err = fmt.Errorf("CMD%d:timeout pres_state:%#x int_status:%#x, %w", index,
 			reg.Read(hw.pres_state),
-			reg.Read(hw.int_status))
+			reg.Read(hw.int_status),
+			e)
 		// According to the IMX6FG flow chart we shouldn't return in
 		// case of error, but still go ahead and check status.
 	}
@@ -238,7 +308,11 @@ func (hw *USDHC) cmd(index uint32, arg uint32, blocks uint32, timeout time.Durat
 			msg += fmt.Sprintf(" AC12:%#x", reg.Read(hw.ac12_err_status))
 		}
 
-		err = fmt.Errorf("CMD%d:error %s", index, msg)
+		if e := statusError(status); e != nil {
+			err = fmt.Errorf("CMD%d:error %s, %w", index, msg, e)
+		} else {
+			err = fmt.Errorf("CMD%d:error %s", index, msg)
+		}
 	}
 
 	return
@@ -265,7 +339,7 @@ func (hw *USDHC) waitState(state int, timeout time.Duration) (err error) {
 		// CMD13 - SEND_STATUS - poll card status
 		if err = hw.cmd(13, hw.rca, 0, hw.writeTimeout); err != nil {
 			if time.Since(start) >= timeout {
-				return fmt.Errorf("error polling card status, %v", err)
+				return fmt.Errorf("error polling card status, %w", err)
 			}
 
 			continue
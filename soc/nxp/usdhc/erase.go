@@ -0,0 +1,111 @@
+// NXP Ultra Secured Digital Host Controller (uSDHC) driver
+// https://github.com/usbarmory/tamago
+//
+// IP: https://www.mobiveil.com/esdhc/
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package usdhc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CMD38 arguments
+const (
+	// 4.3.5 Erase, SD-PL-7.10
+	// 6.6.9 Erase, JESD84-B51
+	ERASE_ARG_ERASE = 0x00000000
+	ERASE_ARG_TRIM  = 0x00000001
+)
+
+// ERASE_TIMEOUT represents the erase timeout for each erase group.
+const ERASE_TIMEOUT = 300 * time.Millisecond
+
+// Discard marks the blocks within the argument range, inclusive of the end
+// block, as no longer in use.
+//
+// On eMMC cards supporting it the TRIM operation is used, which has write
+// block granularity, otherwise start and end+1 must be aligned to the card
+// erase group size (see CardInfo.EraseGroup).
+//
+// Discarded blocks content is undefined (eMMC) or erased (SD), it must not be
+// relied upon.
+func (hw *USDHC) Discard(start uint32, end uint32) (err error) {
+	var startCmd uint32
+	var endCmd uint32
+
+	arg := uint32(ERASE_ARG_ERASE)
+
+	switch {
+	case hw.card.MMC:
+		// CMD35 - ERASE_GROUP_START - set first erase group
+		// CMD36 - ERASE_GROUP_END - set last erase group
+		startCmd = 35
+		endCmd = 36
+
+		if hw.card.TRIM {
+			arg = ERASE_ARG_TRIM
+		}
+	case hw.card.SD:
+		// CMD32 - ERASE_WR_BLK_START - set first block to erase
+		// CMD33 - ERASE_WR_BLK_END - set last block to erase
+		startCmd = 32
+		endCmd = 33
+	default:
+		return fmt.Errorf("no SD/MMC card detected on uSDHC%d", hw.Index)
+	}
+
+	if end < start || int(end) >= hw.card.Blocks {
+		return errors.New("invalid block range")
+	}
+
+	group := uint32(hw.card.EraseGroup)
+
+	if arg == ERASE_ARG_TRIM {
+		group = 1
+	}
+
+	if group == 0 || start%group != 0 || (end+1)%group != 0 {
+		return fmt.Errorf("block range must be %d blocks aligned", group)
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	startAddr := start
+	endAddr := end
+
+	if !hw.card.HC {
+		// byte addressing
+		startAddr *= uint32(hw.card.BlockSize)
+		endAddr *= uint32(hw.card.BlockSize)
+	}
+
+	if err = hw.waitState(CURRENT_STATE_TRAN, 1*time.Millisecond); err != nil {
+		return
+	}
+
+	if err = hw.cmd(startCmd, startAddr, 0, 0); err != nil {
+		return
+	}
+
+	if err = hw.cmd(endCmd, endAddr, 0, 0); err != nil {
+		return
+	}
+
+	timeout := ERASE_TIMEOUT * time.Duration((end-start+1)/group)
+
+	// CMD38 - ERASE - erase selected blocks
+	if err = hw.cmd(38, arg, 0, timeout); err != nil {
+		return
+	}
+
+	return hw.waitState(CURRENT_STATE_TRAN, timeout)
+}
@@ -0,0 +1,181 @@
+// NXP Ultra Secured Digital Host Controller (uSDHC) driver
+// https://github.com/usbarmory/tamago
+//
+// IP: https://www.mobiveil.com/esdhc/
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package usdhc
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// CID registers
+const (
+	// ALL_SEND_CID response contains CID[127:8]
+	CID_RSP_OFF = -8
+
+	// 5.2 CID register, SD-PL-7.10
+	SD_CID_MID       = 120 + CID_RSP_OFF
+	SD_CID_OID       = 104 + CID_RSP_OFF
+	SD_CID_PNM       = 64 + CID_RSP_OFF
+	SD_CID_PNM_CHARS = 5
+	SD_CID_PRV       = 56 + CID_RSP_OFF
+	SD_CID_PSN       = 24 + CID_RSP_OFF
+	SD_CID_MDT_YEAR  = 12 + CID_RSP_OFF
+	SD_CID_MDT_MONTH = 8 + CID_RSP_OFF
+
+	// 7.2 CID register, JESD84-B51
+	MMC_CID_MID       = 120 + CID_RSP_OFF
+	MMC_CID_OID       = 104 + CID_RSP_OFF
+	MMC_CID_PNM       = 56 + CID_RSP_OFF
+	MMC_CID_PNM_CHARS = 6
+	MMC_CID_PRV       = 48 + CID_RSP_OFF
+	MMC_CID_PSN       = 16 + CID_RSP_OFF
+	MMC_CID_MDT_MONTH = 12 + CID_RSP_OFF
+	MMC_CID_MDT_YEAR  = 8 + CID_RSP_OFF
+
+	// 4.10.2 SD Status, SD-PL-7.10
+	SD_STATUS_LENGTH = 64
+	// SPEED_CLASS [447:440]
+	SD_STATUS_SPEED_CLASS = 8
+
+	// 5.6 SCR register, SD-PL-7.10
+	SD_SCR_LENGTH = 8
+	// CMD_SUPPORT [35:32]
+	SD_SCR_CMD_SUPPORT = 3
+	// SET_BLOCK_COUNT (CMD23) support [33]
+	SCR_CMD23 = 1
+)
+
+// field returns a bit field from a little endian representation of a
+// card register.
+func field(buf []byte, pos int, size int) (val uint32) {
+	for i := 0; i < size; i++ {
+		p := pos + i
+		val |= uint32((buf[p/8]>>(p%8))&1) << i
+	}
+
+	return
+}
+
+// str returns an ASCII string field from a little endian representation of a
+// card register.
+func str(buf []byte, pos int, chars int) string {
+	s := make([]byte, chars)
+
+	for i := 0; i < chars; i++ {
+		s[i] = byte(field(buf, pos+(chars-1-i)*8, 8))
+	}
+
+	return string(s)
+}
+
+// Capacity returns the card user area size in bytes.
+func (info CardInfo) Capacity() int64 {
+	return int64(info.Blocks) * int64(info.BlockSize)
+}
+
+func (hw *USDHC) readCID() {
+	for i := 0; i < len(hw.card.CID); i += 4 {
+		binary.LittleEndian.PutUint32(hw.card.CID[i:], hw.rsp(i/4))
+	}
+}
+
+func (hw *USDHC) readCSD() {
+	for i := 0; i < len(hw.card.CSD); i += 4 {
+		binary.LittleEndian.PutUint32(hw.card.CSD[i:], hw.rsp(i/4))
+	}
+}
+
+// decodeCID parses manufacturer and product information from the card
+// identification register, the EXT_CSD revision is required to decode the
+// manufacturing date of MMC cards.
+func (hw *USDHC) decodeCID(extCSDRev int) {
+	cid := hw.card.CID[:]
+	card := &hw.card
+
+	switch {
+	case card.SD:
+		prv := field(cid, SD_CID_PRV, 8)
+
+		card.MID = int(field(cid, SD_CID_MID, 8))
+		card.OID = str(cid, SD_CID_OID, 2)
+		card.Name = str(cid, SD_CID_PNM, SD_CID_PNM_CHARS)
+		card.Revision = fmt.Sprintf("%d.%d", prv>>4, prv&0xf)
+		card.Serial = field(cid, SD_CID_PSN, 32)
+		card.Year = 2000 + int(field(cid, SD_CID_MDT_YEAR, 8))
+		card.Month = int(field(cid, SD_CID_MDT_MONTH, 4))
+	case card.MMC:
+		prv := field(cid, MMC_CID_PRV, 8)
+
+		card.MID = int(field(cid, MMC_CID_MID, 8))
+		card.OID = fmt.Sprintf("%#02x", field(cid, MMC_CID_OID, 8))
+		card.Name = str(cid, MMC_CID_PNM, MMC_CID_PNM_CHARS)
+		card.Revision = fmt.Sprintf("%d.%d", prv>>4, prv&0xf)
+		card.Serial = field(cid, MMC_CID_PSN, 32)
+		card.Year = 1997 + int(field(cid, MMC_CID_MDT_YEAR, 4))
+
+		// MDT year offset for EXT_CSD_REV > 4 devices, JESD84-B51
+		if extCSDRev > 4 && card.Year < 2010 {
+			card.Year += 16
+		}
+		card.Month = int(field(cid, MMC_CID_MDT_MONTH, 4))
+	}
+}
+
+// 4.10.2 SD Status, SD-PL-7.10
+func (hw *USDHC) speedClassSD() (class int, err error) {
+	status := make([]byte, SD_STATUS_LENGTH)
+
+	// CMD55 - APP_CMD - next command is application specific
+	if err = hw.cmd(55, hw.rca, 0, 0); err != nil {
+		return
+	}
+
+	// ACMD13 - SD_STATUS - read SD status
+	if err = hw.transfer(13, READ, 0, 1, SD_STATUS_LENGTH, status); err != nil {
+		return
+	}
+
+	// SPEED_CLASS, 4.10.2 SD Status, SD-PL-7.10
+	switch status[SD_STATUS_SPEED_CLASS] {
+	case 0x00:
+		class = 0
+	case 0x01:
+		class = 2
+	case 0x02:
+		class = 4
+	case 0x03:
+		class = 6
+	case 0x04:
+		class = 10
+	default:
+		err = fmt.Errorf("invalid speed class %#x", status[SD_STATUS_SPEED_CLASS])
+	}
+
+	return
+}
+
+// 5.6 SCR register, SD-PL-7.10
+func (hw *USDHC) cmd23SD() (cmd23 bool, err error) {
+	scr := make([]byte, SD_SCR_LENGTH)
+
+	// CMD55 - APP_CMD - next command is application specific
+	if err = hw.cmd(55, hw.rca, 0, 0); err != nil {
+		return
+	}
+
+	// ACMD51 - SEND_SCR - read SD configuration register
+	if err = hw.transfer(51, READ, 0, 1, SD_SCR_LENGTH, scr); err != nil {
+		return
+	}
+
+	return (scr[SD_SCR_CMD_SUPPORT]>>SCR_CMD23)&1 == 1, nil
+}
@@ -39,28 +39,48 @@ const (
 	MMC_SWITCH_VALUE   = 8
 	MMC_SWITCH_CMD_SET = 0
 
+	ACCESS_SET_BITS   = 0b01
+	ACCESS_CLEAR_BITS = 0b10
 	ACCESS_WRITE_BYTE = 0b11
 
 	// p184 7.3 CSD register, JESD84-B51
-	MMC_CSD_SPEC_VERS   = 122 + CSD_RSP_OFF
-	MMC_CSD_TRAN_SPEED  = 96 + CSD_RSP_OFF
-	MMC_CSD_READ_BL_LEN = 80 + CSD_RSP_OFF
-	MMC_CSD_C_SIZE      = 62 + CSD_RSP_OFF
-	MMC_CSD_C_SIZE_MULT = 47 + CSD_RSP_OFF
+	MMC_CSD_SPEC_VERS      = 122 + CSD_RSP_OFF
+	MMC_CSD_TRAN_SPEED     = 96 + CSD_RSP_OFF
+	MMC_CSD_READ_BL_LEN    = 80 + CSD_RSP_OFF
+	MMC_CSD_C_SIZE         = 62 + CSD_RSP_OFF
+	MMC_CSD_C_SIZE_MULT    = 47 + CSD_RSP_OFF
+	MMC_CSD_ERASE_GRP_SIZE = 42 + CSD_RSP_OFF
+	MMC_CSD_ERASE_GRP_MULT = 37 + CSD_RSP_OFF
 
 	// p186 TRAN_SPEED [103:96], JESD84-B51
 	TRAN_SPEED_26MHZ = 0x32
 
 	// p193, 7.4 Extended CSD register, JESD84-B51
-	EXT_CSD_SEC_COUNT        = 212
-	EXT_CSD_DEVICE_TYPE      = 196
-	EXT_CSD_HS_TIMING        = 185
-	EXT_CSD_BUS_WIDTH        = 183
-	EXT_CSD_PARTITION_CONFIG = 179
+	EXT_CSD_SEC_FEATURE_SUPPORT         = 231
+	EXT_CSD_BOOT_SIZE_MULT              = 226
+	EXT_CSD_HC_ERASE_GRP_SIZE           = 224
+	EXT_CSD_HC_WP_GRP_SIZE              = 221
+	EXT_CSD_SEC_COUNT                   = 212
+	EXT_CSD_DEVICE_TYPE                 = 196
+	EXT_CSD_REV                         = 192
+	EXT_CSD_HS_TIMING                   = 185
+	EXT_CSD_BUS_WIDTH                   = 183
+	EXT_CSD_PARTITION_CONFIG            = 179
+	EXT_CSD_RPMB_SIZE_MULT              = 168
+	EXT_CSD_PARTITION_SETTING_COMPLETED = 155
+	EXT_CSD_GP_SIZE_MULT                = 143
+
+	// SEC_FEATURE_SUPPORT [231], JESD84-B51
+	SEC_GB_CL_EN = 4
 
 	// p224, PARTITION_CONFIG, JESD84-B51
-	PARTITION_ACCESS_NONE = 0x0
-	PARTITION_ACCESS_RPMB = 0x3
+	PARTITION_ACCESS       = 0
+	PARTITION_ACCESS_NONE  = 0x0
+	PARTITION_ACCESS_BOOT1 = 0x1
+	PARTITION_ACCESS_BOOT2 = 0x2
+	PARTITION_ACCESS_RPMB  = 0x3
+	PARTITION_ACCESS_GP1   = 0x4
+	PARTITION_ACCESS_GP4   = 0x7
 
 	// p222, 7.4.65 HS_TIMING [185], JESD84-B51
 	HS_TIMING_HS    = 0x1
@@ -89,6 +109,11 @@ const (
 const (
 	MMC_DETECT_TIMEOUT     = 1 * time.Second
 	MMC_DEFAULT_BLOCK_SIZE = 512
+
+	// boot and RPMB partition size multiplier
+	MMC_BOOT_SIZE_UNIT = 128 * 1024
+	// general purpose partition size multiplier
+	MMC_GP_SIZE_UNIT = 512 * 1024
 )
 
 // p352, 35.4.6 MMC voltage validation flow chart, IMX6FG
@@ -142,6 +167,10 @@ func (hw *USDHC) writeCardRegisterMMC(reg uint32, val uint32) (err error) {
 	// set register value
 	bits.SetN(&arg, MMC_SWITCH_VALUE, 0xff, val)
 
+	return hw.switchMMC(arg)
+}
+
+func (hw *USDHC) switchMMC(arg uint32) (err error) {
 	// CMD6 - SWITCH - switch mode of operation
 	err = hw.cmd(6, arg, 0, 0)
 
@@ -185,6 +214,14 @@ func (hw *USDHC) detectCapabilitiesMMC(c_size_mult uint32, c_size uint32, read_b
 		hw.card.Blocks = int((c_size + 1) * (2 << (c_size_mult + 2)))
 	}
 
+	hw.partitionsMMC(extCSD)
+
+	// EXT_CSD_REV [192], JESD84-B51
+	hw.extCSDRev = int(extCSD[EXT_CSD_REV])
+
+	// TRIM support
+	hw.card.TRIM = (extCSD[EXT_CSD_SEC_FEATURE_SUPPORT]>>SEC_GB_CL_EN)&1 == 1
+
 	// p220, Table 137 — Device types, JESD84-B51
 	deviceType := extCSD[EXT_CSD_DEVICE_TYPE]
 
@@ -199,9 +236,36 @@ func (hw *USDHC) detectCapabilitiesMMC(c_size_mult uint32, c_size uint32, read_b
 	return
 }
 
+// 6.6.5.1 Sampling Tuning Sequence for HS200, JESD84-B51
 func (hw *USDHC) executeTuningMMC() error {
-	reg.SetN(hw.vend_spec2, VEND_SPEC2_TUNING_8bit_EN, 1, 1)
-	return hw.executeTuning(21, 128)
+	// the tuning block pattern size depends on the bus width
+	if hw.width == 8 {
+		reg.Set(hw.vend_spec2, VEND_SPEC2_TUNING_8bit_EN)
+		return hw.executeTuning(21, 128)
+	}
+
+	reg.Clear(hw.vend_spec2, VEND_SPEC2_TUNING_8bit_EN)
+
+	return hw.executeTuning(21, 64)
+}
+
+// checkTimingMMC validates the data path by reading back the extended device
+// data and comparing the bus timing with the expected one.
+//
+// BUS_WIDTH [183] is not checked as it is write-only (W/E_P), JESD84-B51.
+func (hw *USDHC) checkTimingMMC(timing uint32) (err error) {
+	extCSD := make([]byte, MMC_DEFAULT_BLOCK_SIZE)
+
+	// CMD8 - SEND_EXT_CSD - read extended device data
+	if err = hw.transfer(8, READ, 0, 1, MMC_DEFAULT_BLOCK_SIZE, extCSD); err != nil {
+		return
+	}
+
+	if t := uint32(extCSD[EXT_CSD_HS_TIMING] & 0xf); t != timing {
+		return fmt.Errorf("unexpected HS_TIMING %#x", t)
+	}
+
+	return
 }
 
 // p352, 35.4.7 MMC card initialization flow chart, IMX6FG
@@ -220,9 +284,7 @@ func (hw *USDHC) initMMC() (err error) {
 		return
 	}
 
-	for i := 0; i < len(hw.card.CID); i += 4 {
-		binary.LittleEndian.PutUint32(hw.card.CID[i:], hw.rsp(i/4))
-	}
+	hw.readCID()
 
 	// Send CMD3 with a chosen RCA, with value greater than 1,
 	// p301, A.6.1 Bus initialization , JESD84-B51.
@@ -242,6 +304,8 @@ func (hw *USDHC) initMMC() (err error) {
 		return
 	}
 
+	hw.readCSD()
+
 	// block count multiplier
 	c_size_mult := hw.rspVal(MMC_CSD_C_SIZE_MULT, 0b111)
 	// block count
@@ -252,6 +316,12 @@ func (hw *USDHC) initMMC() (err error) {
 	mhz := hw.rspVal(MMC_CSD_TRAN_SPEED, 0xff)
 	// e•MMC specification version
 	ver := hw.rspVal(MMC_CSD_SPEC_VERS, 0xf)
+	// erase group size
+	erase_grp_size := hw.rspVal(MMC_CSD_ERASE_GRP_SIZE, 0x1f)
+	erase_grp_mult := hw.rspVal(MMC_CSD_ERASE_GRP_MULT, 0x1f)
+
+	// CSD ERASE_GRP_SIZE [46:42] and ERASE_GRP_MULT [41:37], JESD84-B51
+	hw.card.EraseGroup = int((erase_grp_size + 1) * (erase_grp_mult + 1))
 
 	if mhz == TRAN_SPEED_26MHZ {
 		// clear clock
@@ -295,6 +365,10 @@ func (hw *USDHC) initMMC() (err error) {
 		return
 	}
 
+	// CMD23 is supported by block oriented read/write command classes,
+	// JESD84-B51
+	hw.card.CMD23 = true
+
 	// Enable High Speed DDR mode only on Version 4.1 or above eMMC cards
 	// with supported rate.
 	if ver < 4 || hw.card.Rate <= HSSDR_MBPS {
@@ -342,8 +416,15 @@ func (hw *USDHC) initMMC() (err error) {
 	hw.setFreq(DVS_HS, clk)
 
 	if tune {
-		// FIXME: Use fixed sampling clock as eMMC tuning fails for unknown reasons.
-		// err = hw.executeTuningMMC()
+		if err = hw.executeTuningMMC(); err == nil {
+			err = hw.checkTimingMMC(timing)
+		}
+
+		if err != nil {
+			// fall back to fixed sampling clock
+			hw.resetTuning()
+			err = nil
+		}
 	}
 
 	hw.card.DDR = ddr
@@ -352,9 +433,96 @@ func (hw *USDHC) initMMC() (err error) {
 	return
 }
 
+// partitionsMMC records the size, in blocks, of each card partition.
+func (hw *USDHC) partitionsMMC(extCSD []byte) {
+	blockSize := hw.card.BlockSize
+
+	hw.partitions = [8]int{}
+	hw.partitions[PARTITION_ACCESS_NONE] = hw.card.Blocks
+
+	// BOOT_SIZE_MULT [226], JESD84-B51
+	boot := int(extCSD[EXT_CSD_BOOT_SIZE_MULT]) * MMC_BOOT_SIZE_UNIT / blockSize
+	hw.partitions[PARTITION_ACCESS_BOOT1] = boot
+	hw.partitions[PARTITION_ACCESS_BOOT2] = boot
+
+	// RPMB_SIZE_MULT [168], JESD84-B51
+	hw.partitions[PARTITION_ACCESS_RPMB] = int(extCSD[EXT_CSD_RPMB_SIZE_MULT]) * MMC_BOOT_SIZE_UNIT / blockSize
+
+	// general purpose partitions are only available once their
+	// configuration is completed
+	if extCSD[EXT_CSD_PARTITION_SETTING_COMPLETED]&1 == 0 {
+		return
+	}
+
+	// GP_SIZE_MULT_GP0 - GP_SIZE_MULT_GP3 [154:143], JESD84-B51
+	grp := int(extCSD[EXT_CSD_HC_WP_GRP_SIZE]) * int(extCSD[EXT_CSD_HC_ERASE_GRP_SIZE]) * MMC_GP_SIZE_UNIT
+
+	for i := 0; i <= PARTITION_ACCESS_GP4-PARTITION_ACCESS_GP1; i++ {
+		off := EXT_CSD_GP_SIZE_MULT + i*3
+		mult := int(extCSD[off]) | int(extCSD[off+1])<<8 | int(extCSD[off+2])<<16
+
+		hw.partitions[PARTITION_ACCESS_GP1+i] = mult * grp / blockSize
+	}
+}
+
 // p224, 7.4.69 PARTITION_CONFIG [179], JESD84-B51
 func (hw *USDHC) partitionAccessMMC(access uint32) (err error) {
-	return hw.writeCardRegisterMMC(EXT_CSD_PARTITION_CONFIG, access)
+	var arg uint32
+
+	// only modify PARTITION_ACCESS to preserve boot configuration
+	bits.SetN(&arg, MMC_SWITCH_ACCESS, 0b11, ACCESS_CLEAR_BITS)
+	bits.SetN(&arg, MMC_SWITCH_INDEX, 0xff, EXT_CSD_PARTITION_CONFIG)
+	bits.SetN(&arg, MMC_SWITCH_VALUE, 0xff, 0b111<<PARTITION_ACCESS)
+
+	if err = hw.switchMMC(arg); err != nil || access == PARTITION_ACCESS_NONE {
+		return
+	}
+
+	bits.SetN(&arg, MMC_SWITCH_ACCESS, 0b11, ACCESS_SET_BITS)
+	bits.SetN(&arg, MMC_SWITCH_VALUE, 0xff, access<<PARTITION_ACCESS)
+
+	return hw.switchMMC(arg)
+}
+
+// PartitionSwitch selects the eMMC partition targeted by subsequent block
+// transfers (e.g. ReadBlocks(), WriteBlocks()), the argument partition index
+// follows the PARTITION_ACCESS definition (see PARTITION_ACCESS_* constants):
+//
+//	0:   user data area
+//	1-2: boot partitions
+//	3:   Replay Protected Memory Block (RPMB), see ReadRPMB()/WriteRPMB()
+//	4-7: general purpose partitions
+//
+// The card capacity, as reported by Info(), is updated to reflect the size
+// of the selected partition.
+func (hw *USDHC) PartitionSwitch(part int) (err error) {
+	if !hw.card.MMC {
+		return fmt.Errorf("no MMC card detected on uSDHC%d", hw.Index)
+	}
+
+	if part < PARTITION_ACCESS_NONE || part > PARTITION_ACCESS_GP4 {
+		return fmt.Errorf("invalid partition %d", part)
+	}
+
+	if part == PARTITION_ACCESS_RPMB {
+		return errors.New("RPMB partition is only accessible with ReadRPMB()/WriteRPMB()")
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	if hw.partitions[part] == 0 {
+		return fmt.Errorf("partition %d is not available", part)
+	}
+
+	if err = hw.partitionAccessMMC(uint32(part)); err != nil {
+		return
+	}
+
+	hw.partition = uint32(part)
+	hw.card.Blocks = hw.partitions[part]
+
+	return
 }
 
 // p106, 6.6.22.4.3 Authenticated Data Write, JESD84-B51
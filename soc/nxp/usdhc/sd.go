@@ -12,7 +12,6 @@
 package usdhc
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"time"
@@ -252,8 +251,13 @@ func (hw *USDHC) detectCapabilitiesSD() (err error) {
 		return
 	}
 
+	hw.readCSD()
+
 	ver := hw.rspVal(SD_CSD_STRUCTURE, 0b11)
 
+	// erase is performed in write block units
+	hw.card.EraseGroup = 1
+
 	switch ver {
 	case 0:
 		// CSD Version 1.0
@@ -289,17 +293,43 @@ func (hw *USDHC) detectCapabilitiesSD() (err error) {
 
 // p60, 4.2.4 Bus Signal Voltage Switch Sequence, SD-PL-7.10
 func (hw *USDHC) voltageSwitchSD() (err error) {
+	// DAT[3:0] line signal levels
+	dat := 0b1
+
+	if hw.width == 4 {
+		dat = 0b1111
+	}
+
 	// CMD11 - VOLTAGE_SWITCH - switch to 1.8V signaling
 	if err = hw.cmd(11, 0, 0, 0); err != nil {
 		return
 	}
 
-	if !reg.WaitFor(1*time.Millisecond, hw.pres_state, PRES_STATE_DLSL, 1, 0) {
+	// the card drives DAT[3:0] low to acknowledge the switch
+	if !reg.WaitFor(1*time.Millisecond, hw.pres_state, PRES_STATE_DLSL, dat, 0) {
 		return fmt.Errorf("voltage switch failed, invalid data line")
 	}
 
 	hw.setFreq(-1, -1)
 
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		// revert to 3.3V signaling, the card requires a power cycle
+		// to recover.
+		reg.Clear(hw.vend_spec, VEND_SPEC_VSELECT)
+
+		if hw.LowVoltage != nil {
+			hw.LowVoltage(false)
+		}
+
+		// restore identification frequency
+		hw.setFreq(-1, -1)
+		hw.setFreq(DVS_ID, SDCLKFS_ID)
+	}()
+
 	// SoC uSDHC IO power voltage selection signal (might be unused)
 	reg.Set(hw.vend_spec, VEND_SPEC_VSELECT)
 
@@ -308,11 +338,13 @@ func (hw *USDHC) voltageSwitchSD() (err error) {
 		return errors.New("voltage switch failed, not at LV")
 	}
 
+	// wait at least 5ms for the regulator output to stabilize
 	time.Sleep(10 * time.Millisecond)
 
 	hw.setFreq(DVS_OP, SDCLKFS_OP)
 
-	if !reg.WaitFor(1*time.Millisecond, hw.pres_state, PRES_STATE_DLSL, 1, 1) {
+	// the card drives DAT[3:0] high within 1ms from clock restart
+	if !reg.WaitFor(1*time.Millisecond, hw.pres_state, PRES_STATE_DLSL, dat, uint32(dat)) {
 		return fmt.Errorf("voltage switch failed, invalid data line")
 	}
 
@@ -342,9 +374,7 @@ func (hw *USDHC) initSD() (err error) {
 		return
 	}
 
-	for i := 0; i < len(hw.card.CID); i += 4 {
-		binary.LittleEndian.PutUint32(hw.card.CID[i:], hw.rsp(i/4))
-	}
+	hw.readCID()
 
 	// CMD3 - SEND_RELATIVE_ADDR - get relative card address (RCA)
 	if err = hw.cmd(3, arg, 0, 0); err != nil {
@@ -400,6 +430,13 @@ func (hw *USDHC) initSD() (err error) {
 		return
 	}
 
+	// speed class is informative, ignore errors
+	hw.card.SpeedClass, _ = hw.speedClassSD()
+
+	if hw.card.CMD23, err = hw.cmd23SD(); err != nil {
+		return fmt.Errorf("could not read SCR, %w", err)
+	}
+
 	if hw.card.Rate >= SDR50_MBPS {
 		// Check support bits 415:400 for SDR104 mode,
 		// p96, 4.3.10.4 Switch Function Status, SD-PL-7.10.
@@ -423,6 +460,8 @@ func (hw *USDHC) initSD() (err error) {
 		root_clk = ROOTCLK_UHS_SDR104
 		clk = SDCLKFS_UHS_SDR104
 		tune = true
+		// CMD23 support is mandatory for UHS104 cards, SD-PL-7.10
+		hw.card.CMD23 = true
 	default:
 		return
 	}
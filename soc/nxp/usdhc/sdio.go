@@ -0,0 +1,296 @@
+// NXP Ultra Secured Digital Host Controller (uSDHC) driver
+// https://github.com/usbarmory/tamago
+//
+// IP: https://www.mobiveil.com/esdhc/
+//
+// Copyright (c) WithSecure Corporation
+// https://foundry.withsecure.com
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package usdhc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/usbarmory/tamago/bits"
+)
+
+// SDIO registers
+const (
+	// 5.2 IO_SEND_OP_COND Response (R4), SDIO-3.00
+	SDIO_OCR_READY       = 31
+	SDIO_OCR_IO_NUM      = 28
+	SDIO_OCR_MEM_PRESENT = 27
+	SDIO_OCR_S18A        = 24
+	SDIO_OCR_VDD_3V3     = 20
+
+	// 5.3 IO_RW_DIRECT Command (CMD52), SDIO-3.00
+	// 5.5 IO_RW_EXTENDED Command (CMD53), SDIO-3.00
+	IO_RW_FLAG       = 31
+	IO_RW_FN         = 28
+	IO_RW_RAW        = 27
+	IO_RW_BLOCK_MODE = 27
+	IO_RW_OP_CODE    = 26
+	IO_RW_ADDR       = 9
+	IO_RW_DATA       = 0
+	IO_RW_COUNT      = 0
+
+	// 5.4 IO_RW_DIRECT Response (R5), SDIO-3.00
+	R5_FLAGS         = 8
+	R5_COM_CRC_ERROR = 7
+	R5_ILLEGAL_CMD   = 6
+	R5_ERROR         = 3
+	R5_FUNCTION_NUM  = 1
+	R5_OUT_OF_RANGE  = 0
+	R5_ERROR_MASK    = 1<<R5_COM_CRC_ERROR | 1<<R5_ILLEGAL_CMD | 1<<R5_ERROR | 1<<R5_FUNCTION_NUM | 1<<R5_OUT_OF_RANGE
+	R5_DATA          = 0
+
+	// 6.9 Card Common Control Registers (CCCR), SDIO-3.00
+	CCCR_BUS_IF_CTRL = 0x07
+	BUS_IF_WIDTH     = 0
+	BUS_IF_WIDTH_1   = 0b00
+	BUS_IF_WIDTH_4   = 0b10
+)
+
+// SDIO constants
+const (
+	SDIO_DETECT_TIMEOUT = 1 * time.Second
+
+	// maximum number of bytes per CMD53 byte mode transfer
+	SDIO_MAX_BYTE_COUNT = 512
+	// maximum number of blocks per CMD53 block mode transfer
+	SDIO_MAX_BLOCK_COUNT = 511
+)
+
+// 3.2 SDIO Card Initialization, SDIO-3.00
+func (hw *USDHC) voltageValidationSDIO() bool {
+	// CMD5 - IO_SEND_OP_COND - read supported voltage window
+	if hw.cmd(5, 0, 0, 0) != nil {
+		return false
+	}
+
+	ocr := hw.rsp(0)
+
+	if bits.Get(&ocr, SDIO_OCR_IO_NUM, 0b111) == 0 {
+		return false
+	}
+
+	var arg uint32
+
+	// select 3.2-3.4V
+	bits.SetN(&arg, SDIO_OCR_VDD_3V3, 0b11, bits.Get(&ocr, SDIO_OCR_VDD_3V3, 0b11))
+
+	if arg == 0 {
+		return false
+	}
+
+	start := time.Now()
+
+	for time.Since(start) <= SDIO_DETECT_TIMEOUT {
+		// CMD5 - IO_SEND_OP_COND - send operating conditions
+		if err := hw.cmd(5, arg, 0, 0); err != nil {
+			break
+		}
+
+		rsp := hw.rsp(0)
+
+		if bits.Get(&rsp, SDIO_OCR_READY, 1) == 0 {
+			continue
+		}
+
+		hw.card.SDIO = true
+		hw.card.Functions = int(bits.Get(&rsp, SDIO_OCR_IO_NUM, 0b111))
+		hw.card.Rate = HS_MBPS
+
+		break
+	}
+
+	return hw.card.SDIO
+}
+
+// 3.2 SDIO Card Initialization, SDIO-3.00
+func (hw *USDHC) initSDIO() (err error) {
+	// CMD3 - SEND_RELATIVE_ADDR - get relative card address (RCA)
+	if err = hw.cmd(3, 0, 0, 0); err != nil {
+		return
+	}
+
+	// set relative card address
+	hw.rca = hw.rsp(0) & (0xffff << RCA_ADDR)
+
+	hw.setFreq(-1, -1)
+	hw.setFreq(DVS_OP, SDCLKFS_OP)
+
+	// CMD7 - SELECT/DESELECT CARD - enter command state
+	if err = hw.cmd(7, hw.rca, 0, 0); err != nil {
+		return
+	}
+
+	var width uint32
+
+	switch hw.width {
+	case 1:
+		width = BUS_IF_WIDTH_1
+	case 4:
+		width = BUS_IF_WIDTH_4
+	default:
+		return errors.New("unsupported SDIO bus width")
+	}
+
+	ctrl, err := hw.rwDirect(false, 0, CCCR_BUS_IF_CTRL, 0)
+
+	if err != nil {
+		return
+	}
+
+	bits.SetN(&ctrl, BUS_IF_WIDTH, 0b11, width)
+
+	_, err = hw.rwDirect(true, 0, CCCR_BUS_IF_CTRL, ctrl)
+
+	return
+}
+
+func (hw *USDHC) checkR5(index uint32) (err error) {
+	flags := (hw.rsp(0) >> R5_FLAGS) & 0xff
+
+	if flags&R5_ERROR_MASK != 0 {
+		return fmt.Errorf("CMD%d:error R5 flags:%#x", index, flags)
+	}
+
+	return
+}
+
+func (hw *USDHC) rwDirect(write bool, fn int, addr uint32, data uint32) (val uint32, err error) {
+	var arg uint32
+
+	if fn < 0 || fn > 7 {
+		return 0, errors.New("invalid SDIO function")
+	}
+
+	if addr > 0x1ffff {
+		return 0, errors.New("invalid SDIO register address")
+	}
+
+	bits.SetTo(&arg, IO_RW_FLAG, write)
+	bits.SetN(&arg, IO_RW_FN, 0b111, uint32(fn))
+	bits.SetN(&arg, IO_RW_ADDR, 0x1ffff, addr)
+	bits.SetN(&arg, IO_RW_DATA, 0xff, data)
+
+	if write {
+		// read after write
+		bits.Set(&arg, IO_RW_RAW)
+	}
+
+	// CMD52 - IO_RW_DIRECT - access a single register
+	if err = hw.cmd(52, arg, 0, 0); err != nil {
+		return
+	}
+
+	if err = hw.checkR5(52); err != nil {
+		return
+	}
+
+	return (hw.rsp(0) >> R5_DATA) & 0xff, nil
+}
+
+// CMD52 issues an IO_RW_DIRECT command to read or write a single register of
+// an SDIO card function, the register value (the read back value when
+// writing) is returned.
+func (hw *USDHC) CMD52(write bool, fn int, addr uint32, data byte) (val byte, err error) {
+	if !hw.card.SDIO {
+		return 0, fmt.Errorf("no SDIO card detected on uSDHC%d", hw.Index)
+	}
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	v, err := hw.rwDirect(write, fn, addr, uint32(data))
+
+	return byte(v), err
+}
+
+// CMD53 issues an IO_RW_EXTENDED command to read or write multiple registers
+// of an SDIO card function.
+//
+// A block size greater than 0 selects block mode transfers, in which case it
+// must match the function block size (configured through its FBR) and the
+// buffer length must be a multiple of it, a block size of 0 selects byte mode
+// transfers of up to 512 bytes.
+//
+// The incr argument selects whether the register address is incremented
+// during the transfer (e.g. for memory access) or fixed (e.g. for FIFO
+// access).
+func (hw *USDHC) CMD53(write bool, fn int, addr uint32, buf []byte, blockSize int, incr bool) (err error) {
+	var arg uint32
+	var dtd uint32
+	var blocks int
+	var count int
+
+	if !hw.card.SDIO {
+		return fmt.Errorf("no SDIO card detected on uSDHC%d", hw.Index)
+	}
+
+	if fn < 0 || fn > 7 {
+		return errors.New("invalid SDIO function")
+	}
+
+	if addr > 0x1ffff {
+		return errors.New("invalid SDIO register address")
+	}
+
+	size := len(buf)
+
+	if size == 0 {
+		return
+	}
+
+	if blockSize > 0 {
+		if size%blockSize != 0 {
+			return fmt.Errorf("transfer size must be %d bytes aligned", blockSize)
+		}
+
+		blocks = size / blockSize
+		count = blocks
+
+		if blocks > SDIO_MAX_BLOCK_COUNT {
+			return fmt.Errorf("transfer size cannot exceed %d blocks", SDIO_MAX_BLOCK_COUNT)
+		}
+
+		bits.Set(&arg, IO_RW_BLOCK_MODE)
+	} else {
+		if size > SDIO_MAX_BYTE_COUNT {
+			return fmt.Errorf("transfer size cannot exceed %d bytes", SDIO_MAX_BYTE_COUNT)
+		}
+
+		blocks = 1
+		blockSize = size
+		// a count of 0 indicates 512 bytes
+		count = size % SDIO_MAX_BYTE_COUNT
+	}
+
+	if write {
+		dtd = WRITE
+	} else {
+		dtd = READ
+	}
+
+	bits.SetTo(&arg, IO_RW_FLAG, write)
+	bits.SetN(&arg, IO_RW_FN, 0b111, uint32(fn))
+	bits.SetTo(&arg, IO_RW_OP_CODE, incr)
+	bits.SetN(&arg, IO_RW_ADDR, 0x1ffff, addr)
+	bits.SetN(&arg, IO_RW_COUNT, 0x1ff, uint32(count))
+
+	hw.Lock()
+	defer hw.Unlock()
+
+	// CMD53 - IO_RW_EXTENDED - access multiple registers
+	if err = hw.transfer(53, dtd, uint64(arg), uint32(blocks), uint32(blockSize), buf); err != nil {
+		return
+	}
+
+	return hw.checkR5(53)
+}
@@ -18,6 +18,7 @@
 //   - IMX6FG     - i.MX 6 Series Firmware Guide                                     - Rev 0      2012/11
 //   - SD-PL-7.10 - SD Specifications Part 1 Physical Layer Simplified Specification - 7.10       2020/03/25
 //   - JESD84-B51 - Embedded Multi-Media Card (e•MMC) Electrical Standard (5.1)      - JESD84-B51 2015/02
+//   - SDIO-3.00  - SD Specifications Part E1 SDIO Simplified Specification           - 3.00       2011/02/25
 //
 // The driver currently supports interfacing with SD/MMC cards up to High Speed
 // mode and Dual Data Rate.
@@ -63,6 +64,7 @@ import (
 	"github.com/usbarmory/tamago/bits"
 	"github.com/usbarmory/tamago/dma"
 	"github.com/usbarmory/tamago/internal/reg"
+	"github.com/usbarmory/tamago/soc/nxp/gpio"
 )
 
 // USDHC registers (p4012, 58.8 uSDHC Memory Map/Register Definition, IMX6ULLRM).
@@ -89,6 +91,8 @@ const (
 	USDHCx_PRES_STATE = 0x24
 	PRES_STATE_DLSL   = 24
 	PRES_STATE_WPSPL  = 19
+	PRES_STATE_CDPL   = 18
+	PRES_STATE_CINST  = 16
 	PRES_STATE_BREN   = 11
 	PRES_STATE_SDSTB  = 3
 	PRES_STATE_CDIHB  = 1
@@ -112,6 +116,9 @@ const (
 	INT_STATUS_DMAE   = 28
 	INT_STATUS_TNE    = 26
 	INT_STATUS_AC12E  = 24
+	INT_STATUS_DEBE   = 22
+	INT_STATUS_DCE    = 21
+	INT_STATUS_DTOE   = 20
 	INT_STATUS_CIE    = 19
 	INT_STATUS_CEBE   = 18
 	INT_STATUS_CCE    = 17
@@ -210,6 +217,10 @@ type CardInfo struct {
 	MMC bool
 	// SD card
 	SD bool
+	// SDIO card
+	SDIO bool
+	// SDIO functions (excluding function 0)
+	Functions int
 	// High Capacity
 	HC bool
 	// High Speed
@@ -218,14 +229,38 @@ type CardInfo struct {
 	DDR bool
 	// Maximum throughput (on this controller)
 	Rate int
+	// Predefined block count (CMD23) support
+	CMD23 bool
+	// TRIM support
+	TRIM bool
 
 	// Block Size
 	BlockSize int
 	// Capacity
 	Blocks int
+	// Erase group size (in blocks)
+	EraseGroup int
 
 	// device identification number
 	CID [16]byte
+	// device specific data
+	CSD [16]byte
+
+	// Manufacturer ID
+	MID int
+	// OEM/Application ID
+	OID string
+	// Product name
+	Name string
+	// Product revision
+	Revision string
+	// Product serial number
+	Serial uint32
+	// Manufacturing date
+	Year  int
+	Month int
+	// Speed class (SD only)
+	SpeedClass int
 }
 
 // USDHC represents an SD/MMC controller instance.
@@ -250,6 +285,18 @@ type USDHC struct {
 	// low voltage indication (MMC) is successful.
 	LowVoltage func(enable bool) bool
 
+	// BlockCount selects, when supported by the card, predefined
+	// multiple block transfers (CMD23 - SET_BLOCK_COUNT) rather than
+	// open-ended ones terminated with CMD12 - STOP_TRANSMISSION.
+	BlockCount bool
+
+	// CD is the optional card detect GPIO (active low), when not defined
+	// the controller card detect signal (USDHCx_CD_B) is used.
+	CD *gpio.Pin
+	// WP is the optional write protect GPIO (active high), when not
+	// defined the controller write protect signal (USDHCx_WP) is used.
+	WP *gpio.Pin
+
 	// bus width
 	width int
 	// Relative Card Address
@@ -277,9 +324,21 @@ type USDHC struct {
 
 	// detected card properties
 	card CardInfo
+	// last polled card detect state
+	inserted bool
 
 	// eMMC Replay Protected Memory Block (RPMB) operation
 	rpmb bool
+	// predefined block count transfer
+	predefined bool
+	// eMMC reliable write request
+	reliable bool
+	// eMMC selected partition
+	partition uint32
+	// eMMC partition sizes
+	partitions [8]int
+	// eMMC EXT_CSD revision
+	extCSDRev int
 
 	readTimeout  time.Duration
 	writeTimeout time.Duration
@@ -356,11 +415,70 @@ func (hw *USDHC) executeTuning(index uint32, blocks uint32) error {
 	return errors.New("tuning failed")
 }
 
+// resetTuning reverts to the fixed sampling clock.
+func (hw *USDHC) resetTuning() {
+	reg.Clear(hw.mix_ctrl, MIX_CTRL_AUTO_TUNE_EN)
+	reg.Clear(hw.mix_ctrl, MIX_CTRL_FBCLK_SEL)
+	reg.Clear(hw.ac12_err_status, AUTOCMD12_ERR_STATUS_EXE_TUNE)
+	reg.Clear(hw.ac12_err_status, AUTOCMD12_ERR_STATUS_SMP_CLK_SEL)
+	reg.Clear(hw.tuning_ctrl, TUNING_CTRL_STD_TUNING_EN)
+}
+
 // Info returns detected card information.
 func (hw *USDHC) Info() CardInfo {
 	return hw.card
 }
 
+// Inserted returns whether a card is present, as reported by the card detect
+// GPIO (see CD) or controller signal.
+func (hw *USDHC) Inserted() bool {
+	if hw.CD != nil {
+		return !hw.CD.Value()
+	}
+
+	if hw.pres_state == 0 {
+		return false
+	}
+
+	return reg.Get(hw.pres_state, PRES_STATE_CINST, 1) == 1
+}
+
+// WriteProtected returns whether the card is write protected, as reported by
+// the write protect GPIO (see WP) or controller signal.
+func (hw *USDHC) WriteProtected() bool {
+	if hw.WP != nil {
+		return hw.WP.Value()
+	}
+
+	if hw.pres_state == 0 {
+		return false
+	}
+
+	return reg.Get(hw.pres_state, PRES_STATE_WPSPL, 1) == 0
+}
+
+// CardChanged polls the card detect state and returns whether a card is
+// present and whether this changed since the previous poll or Detect()
+// invocation. It is meant to be periodically invoked to handle card
+// insertion, with Detect(), and removal.
+//
+// On card removal the detected card information is cleared, so that the next
+// Detect() invocation initializes any newly inserted card.
+func (hw *USDHC) CardChanged() (inserted bool, changed bool) {
+	hw.Lock()
+	defer hw.Unlock()
+
+	inserted = hw.Inserted()
+	changed = inserted != hw.inserted
+	hw.inserted = inserted
+
+	if changed && !inserted {
+		hw.card = CardInfo{}
+	}
+
+	return
+}
+
 // Init initializes the uSDHC controller instance.
 func (hw *USDHC) Init(width int) {
 	hw.Lock()
@@ -402,10 +520,13 @@ func (hw *USDHC) Init(width int) {
 	reg.SetN(hw.CCGR, hw.CG, 0b11, 0b11)
 }
 
-// Detect initializes an SD/MMC card. The highest speed supported by the
+// Detect initializes an SD/MMC/SDIO card. The highest speed supported by the
 // driver, card and controller is automatically selected. Speed modes that
 // require voltage switching require definition of function VoltageSelect on
 // the USDHC instance, which is up to board packages.
+//
+// SDIO cards are only initialized for I/O function access through CMD52()
+// and CMD53(), combo cards are treated as I/O only.
 func (hw *USDHC) Detect() (err error) {
 	hw.Lock()
 	defer hw.Unlock()
@@ -414,13 +535,22 @@ func (hw *USDHC) Detect() (err error) {
 		return errors.New("controller is not initialized")
 	}
 
+	hw.inserted = hw.Inserted()
+
+	if hw.CD != nil && !hw.inserted {
+		hw.card = CardInfo{}
+		return fmt.Errorf("no card inserted on uSDHC%d", hw.Index)
+	}
+
 	// check if a card has already been detected and not removed since
-	if reg.Get(hw.int_status, INT_STATUS_CRM, 1) == 0 && (hw.card.MMC || hw.card.SD) {
+	if reg.Get(hw.int_status, INT_STATUS_CRM, 1) == 0 && (hw.card.MMC || hw.card.SD || hw.card.SDIO) {
 		return
 	}
 
 	// clear card information
 	hw.card = CardInfo{}
+	hw.partition = PARTITION_ACCESS_NONE
+	hw.extCSDRev = 0
 
 	// soft reset uSDHC
 	reg.Set(hw.sys_ctrl, SYS_CTRL_RSTA)
@@ -473,7 +603,9 @@ func (hw *USDHC) Detect() (err error) {
 		return
 	}
 
-	if hw.voltageValidationSD() {
+	if hw.voltageValidationSDIO() {
+		err = hw.initSDIO()
+	} else if hw.voltageValidationSD() {
 		err = hw.initSD()
 	} else if hw.voltageValidationMMC() {
 		err = hw.initMMC()
@@ -485,7 +617,9 @@ func (hw *USDHC) Detect() (err error) {
 		return
 	}
 
-	if !hw.card.DDR {
+	hw.decodeCID(hw.extCSDRev)
+
+	if !hw.card.DDR && !hw.card.SDIO {
 		// CMD16 - SET_BLOCKLEN - define the block length,
 		// only legal In single data rate mode.
 		err = hw.cmd(16, uint32(hw.card.BlockSize), 0, 0)
@@ -513,8 +647,10 @@ func (hw *USDHC) transfer(index uint32, dtd uint32, arg uint64, blocks uint32, b
 		return errors.New("transfer size cannot exceed 65535 blocks")
 	}
 
-	// State polling cannot be issued while tuning (CMD19 and CMD21).
-	if !(index == 19 || index == 21) {
+	// State polling cannot be issued while tuning (CMD19 and CMD21),
+	// between CMD55 and an application command (ACMD13, ACMD51) or on SDIO
+	// cards.
+	if !(index == 19 || index == 21 || index == 13 || index == 51 || hw.card.SDIO) {
 		if err = hw.waitState(CURRENT_STATE_TRAN, 1*time.Millisecond); err != nil {
 			return
 		}
@@ -554,7 +690,23 @@ func (hw *USDHC) transfer(index uint32, dtd uint32, arg uint64, blocks uint32, b
 			return
 		}
 
-		defer hw.partitionAccessMMC(PARTITION_ACCESS_NONE)
+		defer hw.partitionAccessMMC(hw.partition)
+	} else if (hw.BlockCount || hw.reliable) && hw.card.CMD23 && (index == 18 || index == 25) {
+		count := blocks
+
+		if hw.reliable && index == 25 {
+			// reliable write request
+			bits.Set(&count, 31)
+		}
+
+		// CMD23 - SET_BLOCK_COUNT - define read/write block count
+		if err = hw.cmd(23, count, 0, 0); err != nil {
+			return
+		}
+
+		// no CMD12 - STOP_TRANSMISSION is required
+		hw.predefined = true
+		defer func() { hw.predefined = false }()
 	}
 
 	switch dtd {
@@ -572,7 +724,7 @@ func (hw *USDHC) transfer(index uint32, dtd uint32, arg uint64, blocks uint32, b
 	adma_err := reg.Read(hw.adma_err_status)
 
 	if err != nil {
-		return fmt.Errorf("len:%d arg:%#x timeout:%v ADMA:%#x, %v", len(buf), arg, timeout, adma_err, err)
+		return fmt.Errorf("len:%d arg:%#x timeout:%v ADMA:%#x, %w", len(buf), arg, timeout, adma_err, err)
 	}
 
 	if adma_err > 0 {
@@ -586,7 +738,7 @@ func (hw *USDHC) transfer(index uint32, dtd uint32, arg uint64, blocks uint32, b
 	return
 }
 
-func (hw *USDHC) transferBlocks(index uint32, dtd uint32, lba int, buf []byte) (err error) {
+func (hw *USDHC) transferBlocks(index uint32, dtd uint32, lba int, buf []byte, rel bool) (err error) {
 	blockSize := hw.card.BlockSize
 	offset := uint64(lba) * uint64(blockSize)
 	size := len(buf)
@@ -604,19 +756,34 @@ func (hw *USDHC) transferBlocks(index uint32, dtd uint32, lba int, buf []byte) (
 	hw.Lock()
 	defer hw.Unlock()
 
+	hw.reliable = rel
+	defer func() { hw.reliable = false }()
+
 	return hw.transfer(index, dtd, offset, uint32(blocks), uint32(blockSize), buf)
 }
 
 // WriteBlocks transfers full blocks of data to the card.
 func (hw *USDHC) WriteBlocks(lba int, buf []byte) (err error) {
 	// CMD25 - WRITE_MULTIPLE_BLOCK - write consecutive blocks
-	return hw.transferBlocks(25, WRITE, lba, buf)
+	return hw.transferBlocks(25, WRITE, lba, buf, false)
+}
+
+// WriteBlocksReliable transfers full blocks of data to an eMMC card, with a
+// reliable write request (CMD23 - SET_BLOCK_COUNT with Reliable Write bit),
+// to ensure that old data is preserved on sudden power loss.
+func (hw *USDHC) WriteBlocksReliable(lba int, buf []byte) (err error) {
+	if !hw.card.MMC || !hw.card.CMD23 {
+		return errors.New("reliable write is not supported")
+	}
+
+	// CMD25 - WRITE_MULTIPLE_BLOCK - write consecutive blocks
+	return hw.transferBlocks(25, WRITE, lba, buf, true)
 }
 
 // ReadBlocks transfers full blocks of data from the card.
 func (hw *USDHC) ReadBlocks(lba int, buf []byte) (err error) {
 	// CMD18 - READ_MULTIPLE_BLOCK - read consecutive blocks
-	return hw.transferBlocks(18, READ, lba, buf)
+	return hw.transferBlocks(18, READ, lba, buf, false)
 }
 
 // Read transfers data from the card.
@@ -7,7 +7,7 @@
 // Use of this source code is governed by the license
 // that can be found in the LICENSE file.
 
-// Package bee implements a driver for the NXP Watchdog Timer (WDOG)
+// Package wdog implements a driver for the NXP Watchdog Timer (WDOG)
 // adopting the following reference specifications:
 //   - IMX6ULLRM - i.MX 6ULL Applications Processor Reference Manual - Rev 1 2017/11
 //
@@ -155,7 +155,7 @@ func (hw *WDOG) Reset() {
 	reg.Clear16(hw.wcr, WCR_WDA)
 }
 
-// SoftwareReset asserts the watchdog softwarw reset signal.
+// SoftwareReset asserts the watchdog software reset signal.
 func (hw *WDOG) SoftwareReset() {
 	reg.Set16(hw.wcr, WCR_SRE)
 	reg.Clear16(hw.wcr, WCR_SRS)